@@ -7,8 +7,13 @@ import (
 	"github.com/wailsapp/wails/v2/pkg/runtime"
 
 	"cliq/config"
+	"cliq/deps"
 	"cliq/handlers"
+	"cliq/history"
+	"cliq/jobs"
+	"cliq/recent"
 	"repo/shared-go-lib/models"
+	"repo/shared-go-lib/safety"
 	templ "repo/shared-go-lib/template"
 )
 
@@ -38,6 +43,10 @@ func (a *App) startup(ctx context.Context) {
 	ss, err := config.NewSettingsService()
 	if err == nil {
 		a.settingsService = ss
+		if cfg, err := ss.Load(); err == nil {
+			a.fileHandler.SetMaxConcurrentJobs(cfg.MaxConcurrentJobs)
+			a.fileHandler.SetRememberValues(cfg.RememberValues)
+		}
 	}
 }
 
@@ -51,20 +60,258 @@ func (a *App) OpenFileDialogWithFilters(filters []runtime.FileFilter) (string, e
 	return a.fileHandler.OpenFileDialogWithFilters(filters)
 }
 
+// OpenMultipleFilesDialog opens a file dialog for selecting several files,
+// used by file_list variables.
+func (a *App) OpenMultipleFilesDialog(filters []runtime.FileFilter) ([]string, error) {
+	return a.fileHandler.OpenMultipleFilesDialog(filters)
+}
+
+// OpenDirectoryDialog opens a dialog for selecting a directory, used by
+// directory variables.
+func (a *App) OpenDirectoryDialog() (string, error) {
+	return a.fileHandler.OpenDirectoryDialog()
+}
+
 // SaveFileDialog opens a save file dialog and returns the selected file path
 func (a *App) SaveFileDialog() (string, error) {
 	return a.fileHandler.SaveFileDialog()
 }
 
-// ExecuteCommand executes a shell command with the given input and output file paths
-func (a *App) ExecuteCommand(commandID string, variables map[string]interface{}) (string, error) {
+// ExecuteCommand queues the command and returns its job ID. Status changes,
+// output and the final exit status are delivered as "job:status",
+// "command:output" and "command:exit" events. A command with risky
+// operations (see AnalyzeCommand) only runs when confirmed is true.
+func (a *App) ExecuteCommand(commandID string, variables map[string]interface{}, confirmed bool) (string, error) {
+	if a.fileHandler == nil {
+		return "", fmt.Errorf("fileHandler 未初始化")
+	}
+	if a.template == nil {
+		return "", fmt.Errorf("模板未加载")
+	}
+	return a.fileHandler.ExecuteCommand(a.template, commandID, variables, confirmed)
+}
+
+// AnalyzeCommand renders a command of the current template and reports
+// risky operations such as recursive deletes or piping a download into a
+// shell. The frontend shows the findings and asks for confirmation.
+func (a *App) AnalyzeCommand(commandID string, variables map[string]interface{}) (*safety.Report, error) {
+	if a.fileHandler == nil {
+		return nil, fmt.Errorf("fileHandler 未初始化")
+	}
+	report, err := a.fileHandler.AnalyzeCommand(a.template, commandID, variables)
+	if err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// ExecuteBatch runs the command once for every file matched by req.Source
+// and returns the batch ID. Per-item progress and the final summary are
+// delivered as "batch:status" events.
+func (a *App) ExecuteBatch(commandID string, variables map[string]interface{}, req handlers.BatchRequest) (string, error) {
 	if a.fileHandler == nil {
 		return "", fmt.Errorf("fileHandler 未初始化")
 	}
 	if a.template == nil {
 		return "", fmt.Errorf("模板未加载")
 	}
-	return a.fileHandler.ExecuteCommand(a.template, commandID, variables)
+	return a.fileHandler.ExecuteBatch(a.template, commandID, variables, req)
+}
+
+// CancelBatch stops a batch: no further items are started and running ones
+// are cancelled.
+func (a *App) CancelBatch(batchID string) error {
+	if a.fileHandler == nil {
+		return fmt.Errorf("fileHandler 未初始化")
+	}
+	return a.fileHandler.CancelBatch(batchID)
+}
+
+// ListBatches returns all batches, newest first.
+func (a *App) ListBatches() ([]jobs.Batch, error) {
+	if a.fileHandler == nil {
+		return nil, fmt.Errorf("fileHandler 未初始化")
+	}
+	return a.fileHandler.ListBatches(), nil
+}
+
+// GetBatch returns the per-item state and summary of a batch.
+func (a *App) GetBatch(batchID string) (*jobs.Batch, error) {
+	if a.fileHandler == nil {
+		return nil, fmt.Errorf("fileHandler 未初始化")
+	}
+	batch, err := a.fileHandler.GetBatch(batchID)
+	if err != nil {
+		return nil, err
+	}
+	return &batch, nil
+}
+
+// ExecutePipeline runs the steps of a pipeline in order and returns the run
+// ID. Each step is a regular job; the overall progress is delivered as
+// "pipeline:status" events. Steps with risky operations fail unless
+// confirmed is true.
+func (a *App) ExecutePipeline(pipelineID string, variables map[string]interface{}, confirmed bool) (string, error) {
+	if a.fileHandler == nil {
+		return "", fmt.Errorf("fileHandler 未初始化")
+	}
+	if a.template == nil {
+		return "", fmt.Errorf("模板未加载")
+	}
+	return a.fileHandler.ExecutePipeline(a.template, pipelineID, variables, confirmed)
+}
+
+// CancelPipeline cancels the running step of a pipeline and skips the rest.
+func (a *App) CancelPipeline(runID string) error {
+	if a.fileHandler == nil {
+		return fmt.Errorf("fileHandler 未初始化")
+	}
+	return a.fileHandler.CancelPipeline(runID)
+}
+
+// ListPipelineRuns returns all pipeline runs, newest first.
+func (a *App) ListPipelineRuns() ([]jobs.PipelineRun, error) {
+	if a.fileHandler == nil {
+		return nil, fmt.Errorf("fileHandler 未初始化")
+	}
+	return a.fileHandler.ListPipelineRuns(), nil
+}
+
+// GetPipelineRun returns the per-step state of a pipeline run.
+func (a *App) GetPipelineRun(runID string) (*jobs.PipelineRun, error) {
+	if a.fileHandler == nil {
+		return nil, fmt.Errorf("fileHandler 未初始化")
+	}
+	run, err := a.fileHandler.GetPipelineRun(runID)
+	if err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+// CheckTemplateRequirements reports which CLI tools declared by a template
+// and its commands are missing or outdated. The frontend calls it right
+// after importing a template.
+func (a *App) CheckTemplateRequirements(template *models.TemplateFile) (*deps.Report, error) {
+	if a.fileHandler == nil {
+		return nil, fmt.Errorf("fileHandler 未初始化")
+	}
+	report, err := a.fileHandler.CheckTemplateRequirements(template)
+	if err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// CheckCommandRequirements reports which CLI tools a command of the current
+// template needs but cannot find. Execution is refused while any is missing.
+func (a *App) CheckCommandRequirements(commandID string) (*deps.Report, error) {
+	if a.fileHandler == nil {
+		return nil, fmt.Errorf("fileHandler 未初始化")
+	}
+	report, err := a.fileHandler.CheckCommandRequirements(a.template, commandID)
+	if err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// GetInstallPlan describes how a requirement of the current template would
+// be installed on this platform, for the user to confirm.
+func (a *App) GetInstallPlan(name string) (*deps.Plan, error) {
+	if a.fileHandler == nil {
+		return nil, fmt.Errorf("fileHandler 未初始化")
+	}
+	plan, err := a.fileHandler.GetInstallPlan(a.template, name)
+	if err != nil {
+		return nil, err
+	}
+	return &plan, nil
+}
+
+// InstallRequirement runs the installer of a requirement and returns the
+// job ID. It only runs when confirmed is true, i.e. the user has accepted
+// the plan from GetInstallPlan. Output is streamed like a command; when it
+// finishes the requirements are checked again and a "deps:checked" event is
+// emitted.
+func (a *App) InstallRequirement(name string, confirmed bool) (string, error) {
+	if a.fileHandler == nil {
+		return "", fmt.Errorf("fileHandler 未初始化")
+	}
+	return a.fileHandler.InstallRequirement(a.template, name, confirmed)
+}
+
+// CancelCommand cancels a queued job or stops a running one. The process
+// group is asked to terminate first and killed after a grace period.
+func (a *App) CancelCommand(jobID string) error {
+	if a.fileHandler == nil {
+		return fmt.Errorf("fileHandler 未初始化")
+	}
+	return a.fileHandler.CancelCommand(jobID)
+}
+
+// ListJobs returns all queued, running and recently finished jobs, newest
+// first, without their output.
+func (a *App) ListJobs() ([]jobs.Job, error) {
+	if a.fileHandler == nil {
+		return nil, fmt.Errorf("fileHandler 未初始化")
+	}
+	return a.fileHandler.ListJobs(), nil
+}
+
+// GetJob returns the status of a job together with its recent output.
+func (a *App) GetJob(jobID string) (*jobs.Job, error) {
+	if a.fileHandler == nil {
+		return nil, fmt.Errorf("fileHandler 未初始化")
+	}
+	job, err := a.fileHandler.GetJob(jobID)
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// ListHistory returns recorded executions matching filter, newest first.
+func (a *App) ListHistory(filter history.Filter) ([]history.Entry, error) {
+	if a.fileHandler == nil {
+		return nil, fmt.Errorf("fileHandler 未初始化")
+	}
+	return a.fileHandler.ListHistory(filter)
+}
+
+// GetHistoryEntry returns a single recorded execution.
+func (a *App) GetHistoryEntry(id string) (*history.Entry, error) {
+	if a.fileHandler == nil {
+		return nil, fmt.Errorf("fileHandler 未初始化")
+	}
+	return a.fileHandler.GetHistoryEntry(id)
+}
+
+// DeleteHistoryEntry removes a single recorded execution.
+func (a *App) DeleteHistoryEntry(id string) error {
+	if a.fileHandler == nil {
+		return fmt.Errorf("fileHandler 未初始化")
+	}
+	return a.fileHandler.DeleteHistoryEntry(id)
+}
+
+// ClearHistory removes all recorded executions.
+func (a *App) ClearHistory() error {
+	if a.fileHandler == nil {
+		return fmt.Errorf("fileHandler 未初始化")
+	}
+	return a.fileHandler.ClearHistory()
+}
+
+// RerunHistoryEntry runs a recorded execution again and returns the new job
+// ID. Pass nil variables to reuse the recorded values, or an edited map to
+// run with different ones. Risky commands need confirmed, as for
+// ExecuteCommand.
+func (a *App) RerunHistoryEntry(id string, variables map[string]interface{}, confirmed bool) (string, error) {
+	if a.fileHandler == nil {
+		return "", fmt.Errorf("fileHandler 未初始化")
+	}
+	return a.fileHandler.RerunHistoryEntry(a.template, id, variables, confirmed)
 }
 
 func (a *App) GetCommandText(commandID string, variables map[string]interface{}) (string, error) {
@@ -77,6 +324,130 @@ func (a *App) GetCommandText(commandID string, variables map[string]interface{})
 	return a.fileHandler.GetCommandText(a.template, commandID, variables)
 }
 
+// ValidateVariables checks the values entered for a command of the current
+// template and returns an error message per field, empty when all are
+// valid. ExecuteCommand runs the same checks.
+func (a *App) ValidateVariables(commandID string, variables map[string]interface{}) (map[string]string, error) {
+	if a.fileHandler == nil {
+		return nil, fmt.Errorf("fileHandler 未初始化")
+	}
+	return a.fileHandler.ValidateVariables(a.template, commandID, variables)
+}
+
+// FieldStates evaluates the show_if and required_if conditions of
+// command's variables against the values entered so far, so the form can
+// hide fields and mark them as required.
+func (a *App) FieldStates(command models.Command, variables map[string]interface{}) (*handlers.FieldStates, error) {
+	if a.fileHandler == nil {
+		return nil, fmt.Errorf("fileHandler 未初始化")
+	}
+	states, err := a.fileHandler.FieldStates(command, variables)
+	if err != nil {
+		return nil, err
+	}
+	return &states, nil
+}
+
+// LoadVariableOptions runs the `options_from` command of a select or
+// multi_select variable with the values entered so far and returns its
+// choices. Results are cached as the source's `cache` setting says; refresh
+// runs the command again.
+func (a *App) LoadVariableOptions(commandID string, variable string, variables map[string]interface{}, refresh bool) (*handlers.VariableOptions, error) {
+	if a.fileHandler == nil {
+		return nil, fmt.Errorf("fileHandler 未初始化")
+	}
+	options, err := a.fileHandler.LoadVariableOptions(a.template, commandID, variable, variables, refresh)
+	if err != nil {
+		return nil, err
+	}
+	return &options, nil
+}
+
+// ListPresets returns the presets of a command of the current template:
+// the ones shipped with the template followed by the ones the user saved,
+// and the preset to fill in when the command is opened.
+func (a *App) ListPresets(commandID string) (*handlers.CommandPresets, error) {
+	if a.fileHandler == nil {
+		return nil, fmt.Errorf("fileHandler 未初始化")
+	}
+	list, err := a.fileHandler.ListPresets(a.template, commandID)
+	if err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// ApplyPreset returns variables with the values of the named preset filled
+// in. Variables the preset does not set keep their value.
+func (a *App) ApplyPreset(commandID string, name string, variables map[string]interface{}) (map[string]interface{}, error) {
+	if a.fileHandler == nil {
+		return nil, fmt.Errorf("fileHandler 未初始化")
+	}
+	return a.fileHandler.ApplyPreset(a.template, commandID, name, variables)
+}
+
+// SavePreset saves the values entered for a command as a user preset,
+// replacing a user preset of the same name. Secrets are not saved.
+func (a *App) SavePreset(commandID string, preset models.Preset) error {
+	if a.fileHandler == nil {
+		return fmt.Errorf("fileHandler 未初始化")
+	}
+	return a.fileHandler.SavePreset(a.template, commandID, preset)
+}
+
+// RenamePreset renames a user preset.
+func (a *App) RenamePreset(commandID string, oldName string, newName string) error {
+	if a.fileHandler == nil {
+		return fmt.Errorf("fileHandler 未初始化")
+	}
+	return a.fileHandler.RenamePreset(a.template, commandID, oldName, newName)
+}
+
+// DeletePreset deletes a user preset.
+func (a *App) DeletePreset(commandID string, name string) error {
+	if a.fileHandler == nil {
+		return fmt.Errorf("fileHandler 未初始化")
+	}
+	return a.fileHandler.DeletePreset(a.template, commandID, name)
+}
+
+// SetDefaultPreset makes the named preset the one filled in when the
+// command is opened; an empty name turns this off.
+func (a *App) SetDefaultPreset(commandID string, name string) error {
+	if a.fileHandler == nil {
+		return fmt.Errorf("fileHandler 未初始化")
+	}
+	return a.fileHandler.SetDefaultPreset(a.template, commandID, name)
+}
+
+// SuggestValues returns the values entered before for a variable of the
+// current template, ranked by how often and how recently they were used.
+// Only values containing query are returned; limit <= 0 returns all.
+func (a *App) SuggestValues(commandID string, variable string, query string, limit int) ([]recent.Entry, error) {
+	if a.fileHandler == nil {
+		return nil, fmt.Errorf("fileHandler 未初始化")
+	}
+	return a.fileHandler.SuggestValues(a.template, commandID, variable, query, limit)
+}
+
+// ClearValueHistory forgets all values remembered for suggestions.
+func (a *App) ClearValueHistory() error {
+	if a.fileHandler == nil {
+		return fmt.Errorf("fileHandler 未初始化")
+	}
+	return a.fileHandler.ClearValueHistory()
+}
+
+// ComputeVariables evaluates the derived variables of command (`computed`
+// or a default with placeholders) against the values entered so far. The
+// form uses it to pre-fill fields such as output paths.
+func (a *App) ComputeVariables(command models.Command, variables map[string]interface{}) (map[string]interface{}, error) {
+	if a.fileHandler == nil {
+		return nil, fmt.Errorf("fileHandler 未初始化")
+	}
+	return a.fileHandler.ComputeVariables(command, variables)
+}
+
 // ParseCommandToTemplate 将命令字符串解析为模板
 func (a *App) ParseCommandToTemplate(commandStr string) (*models.TemplateFile, error) {
 	return a.templateService.ParseCommandToTemplate(commandStr)
@@ -97,6 +468,11 @@ func (a *App) ParseYAMLToTemplate(yamlStr string) (*models.TemplateFile, error)
 	return a.templateService.ParseYAMLToTemplate(yamlStr)
 }
 
+// ResolveTemplate 展开命令的 extends 和 include, 表单使用展开后的变量列表
+func (a *App) ResolveTemplate(template *models.TemplateFile) (*models.TemplateFile, error) {
+	return a.templateService.ResolveTemplate(template)
+}
+
 // ExportTemplateToFile 将模板导出为文件
 func (a *App) ExportTemplateToFile(template *models.TemplateFile, filePath string) error {
 	return a.fileHandler.ExportTemplateToFile(template, filePath)
@@ -131,7 +507,9 @@ func (a *App) GetFavTemplate(templateName string) (*models.TemplateFile, error)
 
 	// Set the loaded template to the app's template field so that
 	// commands like GetCommandText can access it.
-	a.template = template
+	if err := a.setTemplate(template); err != nil {
+		return nil, err
+	}
 
 	return template, nil
 }
@@ -163,5 +541,15 @@ func (a *App) UpdateAppSettings(partial map[string]any) error {
 	if err := a.settingsService.Update(partial); err != nil {
 		return err
 	}
+	if _, ok := partial["max_concurrent_jobs"]; ok {
+		cfg, err := a.settingsService.Load()
+		if err != nil {
+			return err
+		}
+		a.fileHandler.SetMaxConcurrentJobs(cfg.MaxConcurrentJobs)
+	}
+	if v, ok := partial["remember_values"].(bool); ok {
+		a.fileHandler.SetRememberValues(v)
+	}
 	return nil
 }
@@ -11,9 +11,15 @@ import (
 )
 
 type AppSettings struct {
-    CliqHubBaseURL string `mapstructure:"cliq_hub_base_url"`
+    CliqHubBaseURL    string `mapstructure:"cliq_hub_base_url" json:"cliq_hub_base_url"`
+    MaxConcurrentJobs int    `mapstructure:"max_concurrent_jobs" json:"max_concurrent_jobs"`
+    // RememberValues records the values entered in forms and suggests them again.
+    RememberValues bool `mapstructure:"remember_values" json:"remember_values"`
 }
 
+// maxConcurrentJobsLimit caps max_concurrent_jobs to keep the machine usable.
+const maxConcurrentJobsLimit = 32
+
 type SettingsService struct {
 	vp         *viper.Viper
 	configFile string
@@ -25,6 +31,8 @@ func NewSettingsService() (*SettingsService, error) {
 	vp.AutomaticEnv()
 
     vp.SetDefault("cliq_hub_base_url", "http://localhost:8080")
+    vp.SetDefault("max_concurrent_jobs", 2)
+    vp.SetDefault("remember_values", true)
 
 	cfgDir, err := os.UserConfigDir()
 	if err != nil {
@@ -66,7 +74,12 @@ func (s *SettingsService) Save(in *AppSettings) error {
     if err := validateURL(in.CliqHubBaseURL); err != nil {
         return err
     }
+    if err := validateConcurrency(in.MaxConcurrentJobs); err != nil {
+        return err
+    }
     s.vp.Set("cliq_hub_base_url", in.CliqHubBaseURL)
+    s.vp.Set("max_concurrent_jobs", in.MaxConcurrentJobs)
+    s.vp.Set("remember_values", in.RememberValues)
     return s.vp.WriteConfigAs(s.configFile)
 }
 
@@ -83,6 +96,23 @@ func (s *SettingsService) Update(partial map[string]any) error {
             s.vp.Set("cliq_hub_base_url", str)
         }
     }
+    if v, ok := partial["max_concurrent_jobs"]; ok {
+        n, err := toInt(v)
+        if err != nil {
+            return fmt.Errorf("max_concurrent_jobs: %w", err)
+        }
+        if err := validateConcurrency(n); err != nil {
+            return err
+        }
+        s.vp.Set("max_concurrent_jobs", n)
+    }
+    if v, ok := partial["remember_values"]; ok {
+        b, ok := v.(bool)
+        if !ok {
+            return fmt.Errorf("remember_values: unsupported value %v", v)
+        }
+        s.vp.Set("remember_values", b)
+    }
     return s.vp.WriteConfigAs(s.configFile)
 }
 
@@ -99,3 +129,27 @@ func validateURL(u string) error {
 	}
 	return nil
 }
+
+func validateConcurrency(n int) error {
+	if n < 1 || n > maxConcurrentJobsLimit {
+		return fmt.Errorf("max_concurrent_jobs must be between 1 and %d", maxConcurrentJobsLimit)
+	}
+	return nil
+}
+
+// toInt accepts the numeric types a JSON or YAML decoder may produce.
+func toInt(v any) (int, error) {
+	switch n := v.(type) {
+	case int:
+		return n, nil
+	case int64:
+		return int(n), nil
+	case float64:
+		if n != float64(int(n)) {
+			return 0, fmt.Errorf("%v is not an integer", n)
+		}
+		return int(n), nil
+	default:
+		return 0, fmt.Errorf("unsupported value %v", v)
+	}
+}
@@ -0,0 +1,236 @@
+// Package deps checks that the CLI tools a template declares in `requires`
+// are installed and recent enough.
+package deps
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"regexp"
+	goruntime "runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"repo/shared-go-lib/models"
+	"repo/shared-go-lib/render"
+	"repo/shared-go-lib/safety"
+)
+
+// Status is the outcome of checking one requirement.
+type Status string
+
+const (
+	StatusOK       Status = "ok"
+	StatusMissing  Status = "missing"
+	StatusOutdated Status = "outdated"
+	// StatusUnknownVersion means the tool exists but its version could not
+	// be determined, so the minimum version could not be verified.
+	StatusUnknownVersion Status = "unknown_version"
+)
+
+// probeTimeout bounds the version command of a single requirement.
+const probeTimeout = 10 * time.Second
+
+// defaultVersionPattern extracts the first dotted number from the output of
+// the version command.
+var defaultVersionPattern = regexp.MustCompile(`(\d+(?:\.\d+)+)`)
+
+// Result reports the state of one requirement.
+type Result struct {
+	Name       string `json:"name"`
+	Status     Status `json:"status"`
+	Path       string `json:"path,omitempty"`
+	Version    string `json:"version,omitempty"`
+	MinVersion string `json:"min_version,omitempty"`
+	// InstallHint is the install hint for the current OS.
+	InstallHint string `json:"install_hint,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+// Report is the result of a pre-flight check. OK is false when any tool is
+// missing or outdated; an undeterminable version does not block execution.
+type Report struct {
+	OK      bool     `json:"ok"`
+	Results []Result `json:"results"`
+	Message string   `json:"message,omitempty"`
+}
+
+// ForCommand merges the template-level requirements with the command's own;
+// a command requirement replaces a template requirement with the same name.
+func ForCommand(template *models.TemplateFile, command models.Command) []models.Requirement {
+	return merge(template.Requires, command.Requires)
+}
+
+// ForTemplate returns the requirements of the template and all its commands.
+func ForTemplate(template *models.TemplateFile) []models.Requirement {
+	reqs := template.Requires
+	for _, c := range template.Cmds {
+		reqs = merge(reqs, c.Requires)
+	}
+	return reqs
+}
+
+func merge(base, extra []models.Requirement) []models.Requirement {
+	out := append([]models.Requirement(nil), base...)
+	for _, r := range extra {
+		replaced := false
+		for i := range out {
+			if out[i].Name == r.Name {
+				out[i] = r
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// Check runs the pre-flight check for reqs.
+func Check(reqs []models.Requirement) Report {
+	report := Report{OK: true, Results: make([]Result, 0, len(reqs))}
+	var problems []string
+	for _, r := range reqs {
+		res := checkOne(r)
+		report.Results = append(report.Results, res)
+		switch res.Status {
+		case StatusMissing:
+			report.OK = false
+			problems = append(problems, withHint(fmt.Sprintf("未找到 %s", res.Name), res.InstallHint))
+		case StatusOutdated:
+			report.OK = false
+			problems = append(problems, withHint(fmt.Sprintf("%s 版本 %s 低于要求的 %s", res.Name, res.Version, res.MinVersion), res.InstallHint))
+		}
+	}
+	if len(problems) > 0 {
+		report.Message = "缺少依赖: " + strings.Join(problems, "; ")
+	}
+	return report
+}
+
+func withHint(msg, hint string) string {
+	if hint == "" {
+		return msg
+	}
+	return fmt.Sprintf("%s (安装: %s)", msg, hint)
+}
+
+// checkOne looks up the binary and, when a minimum version is declared,
+// runs the version command and compares the result.
+func checkOne(r models.Requirement) Result {
+	res := Result{Name: r.Name, MinVersion: r.MinVersion, InstallHint: r.Install[goruntime.GOOS]}
+
+	path, err := exec.LookPath(render.ExpandHome(r.Name))
+	if err != nil {
+		res.Status = StatusMissing
+		return res
+	}
+	res.Path = path
+	res.Status = StatusOK
+	if r.MinVersion == "" {
+		return res
+	}
+
+	version, err := probeVersion(r, path)
+	if err != nil {
+		res.Status = StatusUnknownVersion
+		res.Error = err.Error()
+		return res
+	}
+	res.Version = version
+	if CompareVersions(version, r.MinVersion) < 0 {
+		res.Status = StatusOutdated
+	}
+	return res
+}
+
+// probeVersion runs the version command and extracts the version number.
+// Both stdout and stderr are searched, as some tools print their version on
+// stderr.
+//
+// The check runs without asking the user, e.g. right after a template is
+// imported, so the command must start with the required binary itself,
+// found at path, and must not contain anything the safety analyzer flags.
+func probeVersion(r models.Requirement, path string) (string, error) {
+	command := r.VersionCommand
+	if command == "" {
+		command = render.Quote(r.Name) + " --version"
+	}
+	argv, err := render.Argv(command, nil)
+	if err != nil || len(argv) == 0 {
+		return "", fmt.Errorf("无效的版本命令: %s", command)
+	}
+	if bin, err := exec.LookPath(render.ExpandHome(argv[0])); err != nil || bin != path {
+		return "", fmt.Errorf("版本命令必须运行 %s 本身, 已跳过: %s", r.Name, command)
+	}
+	if report := safety.Analyze(safety.Input{Command: command, Argv: argv}); report.RequiresConfirmation {
+		return "", fmt.Errorf("版本命令包含高风险操作, 已跳过: %s", command)
+	}
+
+	pattern := defaultVersionPattern
+	if r.VersionPattern != "" {
+		if pattern, err = regexp.Compile(r.VersionPattern); err != nil {
+			return "", fmt.Errorf("无效的版本正则: %w", err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), probeTimeout)
+	defer cancel()
+	// 部分工具 (如 java -version) 以非零状态退出, 只要输出中有版本号就接受
+	out, _ := exec.CommandContext(ctx, path, argv[1:]...).CombinedOutput()
+
+	m := pattern.FindStringSubmatch(string(out))
+	switch {
+	case m == nil:
+		return "", fmt.Errorf("无法从 '%s' 的输出中识别版本号", command)
+	case len(m) > 1:
+		return m[1], nil
+	}
+	return m[0], nil
+}
+
+// CompareVersions compares two dotted version strings numerically and
+// returns -1, 0 or 1. A leading "v" and any non-numeric suffix of a part
+// (e.g. "1-ubuntu") are ignored; missing parts count as zero.
+func CompareVersions(a, b string) int {
+	pa, pb := versionParts(a), versionParts(b)
+	for i := 0; i < len(pa) || i < len(pb); i++ {
+		var x, y int
+		if i < len(pa) {
+			x = pa[i]
+		}
+		if i < len(pb) {
+			y = pb[i]
+		}
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+	}
+	return 0
+}
+
+func versionParts(v string) []int {
+	v = strings.TrimPrefix(strings.TrimSpace(v), "v")
+	var parts []int
+	for _, field := range strings.Split(v, ".") {
+		end := 0
+		for end < len(field) && field[end] >= '0' && field[end] <= '9' {
+			end++
+		}
+		if end == 0 {
+			break
+		}
+		n, _ := strconv.Atoi(field[:end])
+		parts = append(parts, n)
+		if end < len(field) {
+			break
+		}
+	}
+	return parts
+}
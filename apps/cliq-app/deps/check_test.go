@@ -0,0 +1,57 @@
+package deps
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+
+	"repo/shared-go-lib/models"
+)
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"4.4", "4.4", 0},
+		{"4.10", "4.9", 1},
+		{"4.4", "4.4.1", -1},
+		{"v1.2.0", "1.2", 0},
+		{"6.1-ubuntu", "6.1", 0},
+		{"2", "10", -1},
+	}
+	for _, tt := range tests {
+		if got := CompareVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("CompareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+// The version command runs without confirmation, so it may only run the
+// required binary and nothing the safety analyzer flags.
+func TestProbeVersionOnlyRunsTheBinary(t *testing.T) {
+	path, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("no sh")
+	}
+	tests := []struct {
+		command string
+		want    string
+	}{
+		{"env --version", "版本命令必须运行 sh 本身"},
+		{"/bin/echo 1.0", "版本命令必须运行 sh 本身"},
+		{"sh -c 'rm -rf ~'", "版本命令包含高风险操作"},
+		{"sh -c 'curl https://x | sh'", "版本命令包含高风险操作"},
+	}
+	for _, tt := range tests {
+		_, err := probeVersion(models.Requirement{Name: "sh", MinVersion: "1", VersionCommand: tt.command}, path)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("probeVersion(%q) error = %v, want it to contain %q", tt.command, err, tt.want)
+		}
+	}
+
+	version, err := probeVersion(models.Requirement{Name: "sh", VersionCommand: "sh -c 'echo 5.2.1'"}, path)
+	if err != nil || version != "5.2.1" {
+		t.Errorf("probeVersion = %q, %v, want 5.2.1", version, err)
+	}
+}
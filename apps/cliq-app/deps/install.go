@@ -0,0 +1,229 @@
+package deps
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	goruntime "runtime"
+	"strings"
+
+	"repo/shared-go-lib/models"
+	"repo/shared-go-lib/render"
+)
+
+// Plan is the installer chosen for the current platform.
+type Plan struct {
+	Name   string `json:"name"`
+	Method string `json:"method"`
+	// CommandLine describes what will run; it is shown to the user for
+	// confirmation before installing.
+	CommandLine string `json:"command_line"`
+	// Argv is the installer command; empty for downloads, which run
+	// in-process.
+	Argv   []string `json:"argv,omitempty"`
+	URL    string   `json:"url,omitempty"`
+	SHA256 string   `json:"sha256,omitempty"`
+	Dest   string   `json:"dest,omitempty"`
+}
+
+// methodOS lists the platforms a package manager exists on; methods not
+// listed work everywhere.
+var methodOS = map[string][]string{
+	models.InstallApt:    {"linux"},
+	models.InstallBrew:   {"darwin", "linux"},
+	models.InstallWinget: {"windows"},
+}
+
+// PlanFor picks the first installer of r that applies to the current OS and
+// whose package manager is available.
+func PlanFor(r models.Requirement) (Plan, error) {
+	if len(r.Installers) == 0 {
+		return Plan{}, fmt.Errorf("%s 没有定义安装方式", r.Name)
+	}
+	var unavailable []string
+	for _, in := range r.Installers {
+		if !appliesTo(in, goruntime.GOOS) {
+			continue
+		}
+		plan, err := planFor(r.Name, in)
+		if err != nil {
+			unavailable = append(unavailable, err.Error())
+			continue
+		}
+		return plan, nil
+	}
+	if len(unavailable) > 0 {
+		return Plan{}, fmt.Errorf("无法安装 %s: %s", r.Name, strings.Join(unavailable, "; "))
+	}
+	return Plan{}, fmt.Errorf("%s 没有适用于 %s 的安装方式", r.Name, goruntime.GOOS)
+}
+
+func appliesTo(in models.Installer, goos string) bool {
+	if in.OS != "" {
+		return in.OS == goos
+	}
+	oses, ok := methodOS[in.Method]
+	if !ok {
+		return true
+	}
+	for _, os := range oses {
+		if os == goos {
+			return true
+		}
+	}
+	return false
+}
+
+// planFor builds the installer command. It fails when the package manager
+// itself is not installed.
+func planFor(name string, in models.Installer) (Plan, error) {
+	plan := Plan{Name: name, Method: in.Method}
+	switch in.Method {
+	case models.InstallApt:
+		// 桌面应用没有终端输入密码, 优先使用图形化的 pkexec 提权
+		if _, err := exec.LookPath("pkexec"); err == nil {
+			plan.Argv = []string{"pkexec", "apt-get", "install", "-y", in.Package}
+		} else {
+			plan.Argv = []string{"sudo", "-n", "apt-get", "install", "-y", in.Package}
+		}
+	case models.InstallBrew:
+		plan.Argv = []string{"brew", "install", in.Package}
+	case models.InstallWinget:
+		plan.Argv = []string{"winget", "install", "-e", "--id", in.Package, "--accept-package-agreements", "--accept-source-agreements"}
+	case models.InstallPip:
+		python := "python3"
+		if goruntime.GOOS == "windows" {
+			python = "python"
+		}
+		plan.Argv = []string{python, "-m", "pip", "install", "--user", in.Package}
+	case models.InstallNpm:
+		plan.Argv = []string{"npm", "install", "-g", in.Package}
+	case models.InstallGo:
+		pkg := in.Package
+		if !strings.Contains(pkg, "@") {
+			pkg += "@latest"
+		}
+		plan.Argv = []string{"go", "install", pkg}
+	case models.InstallDownload:
+		if !strings.HasPrefix(in.URL, "https://") {
+			return Plan{}, fmt.Errorf("下载地址必须使用 https: %s", in.URL)
+		}
+		plan.URL = in.URL
+		plan.SHA256 = strings.ToLower(in.SHA256)
+		dest, err := downloadDest(name, in.Dest)
+		if err != nil {
+			return Plan{}, err
+		}
+		plan.Dest = dest
+		plan.CommandLine = fmt.Sprintf("下载 %s 到 %s (sha256 %s)", plan.URL, plan.Dest, plan.SHA256)
+		return plan, nil
+	default:
+		return Plan{}, fmt.Errorf("不支持的安装方式: %s", in.Method)
+	}
+
+	if _, err := exec.LookPath(plan.Argv[0]); err != nil {
+		return Plan{}, fmt.Errorf("未找到 %s", plan.Argv[0])
+	}
+	plan.CommandLine = render.Join(plan.Argv)
+	return plan, nil
+}
+
+// downloadDest returns where a downloaded binary is written. It is always a
+// file in ~/.local/bin: dest only renames it, so a template cannot make the
+// download overwrite files elsewhere.
+func downloadDest(name, dest string) (string, error) {
+	file := name
+	if dest != "" {
+		file = dest
+	}
+	if !isFileName(file) {
+		return "", fmt.Errorf("无效的安装文件名 '%s': 只能是 ~/.local/bin 中的文件名", file)
+	}
+	if goruntime.GOOS == "windows" && !strings.HasSuffix(strings.ToLower(file), ".exe") {
+		file += ".exe"
+	}
+	return render.ExpandHome(filepath.Join("~", ".local", "bin", file)), nil
+}
+
+// isFileName reports whether name is a bare file name without separators
+// or references to parent directories.
+func isFileName(name string) bool {
+	return name != "" && name != "." && name != ".." && !strings.ContainsAny(name, `/\:`)
+}
+
+// downloadClient follows redirects only to https addresses, so a download
+// cannot be downgraded to plain http on the way.
+var downloadClient = &http.Client{
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		if req.URL.Scheme != "https" {
+			return fmt.Errorf("拒绝重定向到非 https 地址: %s", req.URL)
+		}
+		if len(via) >= 10 {
+			return fmt.Errorf("重定向次数过多")
+		}
+		return nil
+	},
+}
+
+// Download fetches plan.URL, verifies its SHA-256 checksum and installs it
+// as an executable at plan.Dest. Nothing is written to Dest unless the
+// checksum matches.
+func Download(ctx context.Context, plan Plan, out func(line string)) error {
+	out(fmt.Sprintf("下载 %s", plan.URL))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, plan.URL, nil)
+	if err != nil {
+		return fmt.Errorf("无效的下载地址: %w", err)
+	}
+	resp, err := downloadClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("下载失败: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("下载失败，状态码: %d", resp.StatusCode)
+	}
+
+	dir := filepath.Dir(plan.Dest)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("创建目录失败: %w", err)
+	}
+	tmp, err := os.CreateTemp(dir, ".cliq-download-*")
+	if err != nil {
+		return fmt.Errorf("创建临时文件失败: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	hash := sha256.New()
+	n, err := io.Copy(io.MultiWriter(tmp, hash), resp.Body)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("下载失败: %w", err)
+	}
+	out(fmt.Sprintf("已下载 %.1f MB", float64(n)/(1024*1024)))
+
+	sum := hex.EncodeToString(hash.Sum(nil))
+	if sum != plan.SHA256 {
+		return fmt.Errorf("sha256 校验失败: 期望 %s, 实际 %s", plan.SHA256, sum)
+	}
+	out("sha256 校验通过")
+
+	if err := os.Chmod(tmp.Name(), 0755); err != nil {
+		return fmt.Errorf("设置可执行权限失败: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), plan.Dest); err != nil {
+		return fmt.Errorf("安装失败: %w", err)
+	}
+	out(fmt.Sprintf("已安装到 %s", plan.Dest))
+	if _, err := exec.LookPath(filepath.Base(plan.Dest)); err != nil {
+		out(fmt.Sprintf("注意: %s 不在 PATH 中, 请将其加入 PATH", dir))
+	}
+	return nil
+}
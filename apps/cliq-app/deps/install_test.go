@@ -0,0 +1,106 @@
+package deps
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"repo/shared-go-lib/models"
+)
+
+func TestPlanForDownload(t *testing.T) {
+	sum := strings.Repeat("A", 64)
+	plan, err := planFor("jq", models.Installer{Method: models.InstallDownload, URL: "https://x/jq", SHA256: sum})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.URL != "https://x/jq" || plan.SHA256 != strings.ToLower(sum) || len(plan.Argv) != 0 {
+		t.Errorf("plan = %+v", plan)
+	}
+	if dir := filepath.Base(filepath.Dir(plan.Dest)); dir != "bin" {
+		t.Errorf("dest = %s, want a file in ~/.local/bin", plan.Dest)
+	}
+
+	tests := []struct {
+		in   models.Installer
+		want string
+	}{
+		{models.Installer{Method: models.InstallDownload, URL: "http://x/jq", SHA256: sum}, "下载地址必须使用 https"},
+		{models.Installer{Method: models.InstallDownload, URL: "https://x/jq", SHA256: sum, Dest: "../jq"}, "无效的安装文件名"},
+		{models.Installer{Method: "snap", Package: "jq"}, "不支持的安装方式"},
+	}
+	for _, tt := range tests {
+		if _, err := planFor("jq", tt.in); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("planFor(%+v) error = %v, want it to contain %q", tt.in, err, tt.want)
+		}
+	}
+}
+
+func TestDownload(t *testing.T) {
+	body := []byte("#!/bin/sh\necho jq-1.7\n")
+	hash := sha256.Sum256(body)
+	sum := hex.EncodeToString(hash[:])
+
+	plain := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(body)
+	}))
+	defer plain.Close()
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/jq":
+			w.Write(body)
+		case "/moved":
+			http.Redirect(w, r, "/jq", http.StatusFound)
+		case "/downgrade":
+			http.Redirect(w, r, plain.URL+"/jq", http.StatusFound)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	transport := downloadClient.Transport
+	downloadClient.Transport = srv.Client().Transport
+	defer func() { downloadClient.Transport = transport }()
+
+	tests := []struct {
+		name string
+		path string
+		sum  string
+		want string
+	}{
+		{"ok", "/jq", sum, ""},
+		{"redirect", "/moved", sum, ""},
+		{"redirect to http", "/downgrade", sum, "拒绝重定向到非 https 地址"},
+		{"checksum mismatch", "/jq", strings.Repeat("0", 64), "sha256 校验失败"},
+		{"not found", "/missing", sum, "状态码: 404"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dest := filepath.Join(t.TempDir(), "bin", "jq")
+			plan := Plan{Name: "jq", Method: models.InstallDownload, URL: srv.URL + tt.path, SHA256: tt.sum, Dest: dest}
+			err := Download(context.Background(), plan, func(string) {})
+			if tt.want != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.want) {
+					t.Errorf("Download error = %v, want it to contain %q", err, tt.want)
+				}
+				if _, err := os.Stat(dest); err == nil {
+					t.Errorf("failed download left %s", dest)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Download error: %v", err)
+			}
+			got, err := os.ReadFile(dest)
+			if err != nil || string(got) != string(body) {
+				t.Errorf("installed file = %q, %v", got, err)
+			}
+		})
+	}
+}
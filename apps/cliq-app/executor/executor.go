@@ -0,0 +1,250 @@
+// Package executor runs a rendered command and streams its output line by
+// line while it is running.
+package executor
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Stream identifies which output stream a line was read from.
+type Stream string
+
+const (
+	StreamStdout Stream = "stdout"
+	StreamStderr Stream = "stderr"
+)
+
+// maxLineSize bounds a single output line; longer lines are split.
+const maxLineSize = 1024 * 1024
+
+// OutputLine is one line of process output.
+type OutputLine struct {
+	JobID  string    `json:"job_id"`
+	Stream Stream    `json:"stream"`
+	Line   string    `json:"line"`
+	Time   time.Time `json:"time"`
+}
+
+// Result describes a finished process.
+type Result struct {
+	JobID      string    `json:"job_id"`
+	ExitCode   int       `json:"exit_code"`
+	StartedAt  time.Time `json:"started_at"`
+	FinishedAt time.Time `json:"finished_at"`
+	DurationMs int64     `json:"duration_ms"`
+	Canceled   bool      `json:"canceled"`
+	TimedOut   bool      `json:"timed_out"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// Command is the process Run starts.
+type Command struct {
+	Argv []string
+	// Dir is the working directory; empty means the app's.
+	Dir string
+	// Env is added to the app's environment, overriding inherited values.
+	Env map[string]string
+	// StdinFile is streamed to the process's stdin. It takes precedence
+	// over Stdin.
+	StdinFile string
+	// Stdin is written to the process's stdin when StdinFile is empty.
+	Stdin string
+	// Stdout, when set, also receives the process's stdout exactly as it
+	// was written, before it is split into lines.
+	Stdout io.Writer
+}
+
+// NewJobID returns a random identifier for a job.
+func NewJobID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
+
+// terminateGrace is how long a cancelled process group gets to exit after
+// the polite termination request before it is killed.
+const terminateGrace = 5 * time.Second
+
+// Run executes c and calls onLine for every line written to stdout or
+// stderr. onLine is never called concurrently. Run blocks until the process
+// has exited and all output has been delivered. ExitCode is -1 when the
+// process could not be started or was killed by a signal.
+//
+// The process runs in its own process group (a job object on Windows). When
+// ctx is done the whole group is asked to terminate and, if it is still
+// alive after a grace period, killed. Whatever is left of the group when the
+// process exits is killed as well, so children spawned by the tool do not
+// outlive the job.
+func Run(ctx context.Context, jobID string, c Command, onLine func(OutputLine)) Result {
+	res := Result{JobID: jobID, ExitCode: -1, StartedAt: time.Now()}
+	finish := func(err error) Result {
+		res.FinishedAt = time.Now()
+		res.DurationMs = res.FinishedAt.Sub(res.StartedAt).Milliseconds()
+		switch {
+		case errors.Is(ctx.Err(), context.DeadlineExceeded):
+			res.TimedOut = true
+			res.Error = "命令执行超时"
+		case ctx.Err() != nil:
+			res.Canceled = true
+			res.Error = "命令已取消"
+		case err != nil:
+			res.Error = err.Error()
+		}
+		return res
+	}
+
+	argv := c.Argv
+	if len(argv) == 0 {
+		return finish(errors.New("命令为空"))
+	}
+	if ctx.Err() != nil {
+		return finish(nil)
+	}
+
+	var mu sync.Mutex
+	emit := func(stream Stream, line string) {
+		mu.Lock()
+		defer mu.Unlock()
+		if onLine != nil {
+			onLine(OutputLine{JobID: jobID, Stream: stream, Line: line, Time: time.Now()})
+		}
+	}
+	stdout := &lineWriter{stream: StreamStdout, emit: emit}
+	stderr := &lineWriter{stream: StreamStderr, emit: emit}
+
+	cmd := exec.Command(argv[0], argv[1:]...)
+	cmd.Dir = c.Dir
+	if len(c.Env) > 0 {
+		cmd.Env = os.Environ()
+		for name, value := range c.Env {
+			cmd.Env = append(cmd.Env, name+"="+value)
+		}
+	}
+	switch {
+	case c.StdinFile != "":
+		// 直接把文件交给子进程, 不经过内存
+		f, err := os.Open(c.StdinFile)
+		if err != nil {
+			return finish(fmt.Errorf("打开标准输入文件失败: %w", err))
+		}
+		defer f.Close()
+		cmd.Stdin = f
+	case c.Stdin != "":
+		cmd.Stdin = strings.NewReader(c.Stdin)
+	}
+	cmd.Stdout = stdout
+	if c.Stdout != nil {
+		cmd.Stdout = io.MultiWriter(stdout, c.Stdout)
+	}
+	cmd.Stderr = stderr
+	// Children that inherited our pipes must not keep Wait blocked forever.
+	cmd.WaitDelay = terminateGrace
+	group := setProcessGroup(cmd)
+
+	if err := cmd.Start(); err != nil {
+		return finish(fmt.Errorf("启动命令失败: %w", err))
+	}
+	if err := group.start(cmd); err != nil {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+		return finish(fmt.Errorf("启动命令失败: %w", err))
+	}
+
+	exited := make(chan struct{})
+	watched := make(chan struct{})
+	go func() {
+		defer close(watched)
+		select {
+		case <-exited:
+			return
+		case <-ctx.Done():
+		}
+		_ = group.terminate()
+		select {
+		case <-exited:
+		case <-time.After(terminateGrace):
+			_ = group.kill()
+		}
+	}()
+
+	// The job is over once the leader exits; children it left running in
+	// the background are killed with the rest of the group. The group is
+	// only signalled before the leader is reaped, while its ID is still
+	// reserved.
+	exitErr := group.waitExited()
+	close(exited)
+	<-watched
+	if exitErr == nil {
+		group.release()
+	}
+	err := cmd.Wait()
+	stdout.flush()
+	stderr.flush()
+
+	var exitStatus *exec.ExitError
+	switch {
+	case err == nil:
+		res.ExitCode = 0
+	case errors.As(err, &exitStatus):
+		res.ExitCode = exitStatus.ExitCode()
+	}
+	return finish(err)
+}
+
+// lineWriter splits everything written to it into lines. Both "\n" and a
+// lone "\r" end a line, so progress output that redraws itself with carriage
+// returns is delivered as it is produced.
+type lineWriter struct {
+	stream Stream
+	emit   func(Stream, string)
+	buf    []byte
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexAny(w.buf, "\r\n")
+		if i < 0 {
+			if len(w.buf) >= maxLineSize {
+				w.emit(w.stream, string(w.buf))
+				w.buf = w.buf[:0]
+			}
+			break
+		}
+		next := i + 1
+		if w.buf[i] == '\r' {
+			if next == len(w.buf) {
+				// Wait for more data to tell "\r\n" from a lone "\r".
+				break
+			}
+			if w.buf[next] == '\n' {
+				next++
+			}
+		}
+		w.emit(w.stream, string(w.buf[:i]))
+		w.buf = w.buf[next:]
+	}
+	return len(p), nil
+}
+
+// flush delivers a trailing line that was not terminated by a newline.
+func (w *lineWriter) flush() {
+	line := bytes.TrimSuffix(w.buf, []byte("\r"))
+	if len(line) > 0 {
+		w.emit(w.stream, string(line))
+	}
+	w.buf = nil
+}
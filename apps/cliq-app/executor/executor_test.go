@@ -0,0 +1,283 @@
+package executor
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func skipWithoutSh(t *testing.T) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("the commands use sh")
+	}
+}
+
+// run executes a shell script and collects its output lines per stream.
+func run(ctx context.Context, c Command) (Result, map[Stream][]string) {
+	lines := map[Stream][]string{}
+	res := Run(ctx, "job", c, func(l OutputLine) {
+		lines[l.Stream] = append(lines[l.Stream], l.Line)
+	})
+	return res, lines
+}
+
+func sh(script string) Command {
+	return Command{Argv: []string{"sh", "-c", script}}
+}
+
+func TestRunOutputAndExitCode(t *testing.T) {
+	skipWithoutSh(t)
+	tests := []struct {
+		name     string
+		script   string
+		exitCode int
+		stdout   []string
+		stderr   []string
+	}{
+		{"lines", "echo a; echo b", 0, []string{"a", "b"}, nil},
+		{"both streams", "echo out; echo err >&2", 0, []string{"out"}, []string{"err"}},
+		{"exit code", "echo x; exit 3", 3, []string{"x"}, nil},
+		{"unterminated last line", "printf 'a\\nb'", 0, []string{"a", "b"}, nil},
+		{"crlf", "printf 'a\\r\\nb\\r\\n'", 0, []string{"a", "b"}, nil},
+		{"carriage return redraws", "printf '10%%\\r50%%\\r100%%\\n'", 0, []string{"10%", "50%", "100%"}, nil},
+		{"empty lines kept", "printf 'a\\n\\nb\\n'", 0, []string{"a", "", "b"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, lines := run(context.Background(), sh(tt.script))
+			if res.ExitCode != tt.exitCode || res.Error != "" && tt.exitCode == 0 {
+				t.Errorf("result = %+v, want exit code %d", res, tt.exitCode)
+			}
+			if !reflect.DeepEqual(lines[StreamStdout], tt.stdout) || !reflect.DeepEqual(lines[StreamStderr], tt.stderr) {
+				t.Errorf("stdout = %q, stderr = %q, want %q, %q", lines[StreamStdout], lines[StreamStderr], tt.stdout, tt.stderr)
+			}
+		})
+	}
+}
+
+func TestRunStartErrors(t *testing.T) {
+	res, _ := run(context.Background(), Command{})
+	if res.ExitCode != -1 || res.Error != "命令为空" {
+		t.Errorf("empty argv result = %+v", res)
+	}
+	res, _ = run(context.Background(), Command{Argv: []string{"cliq-no-such-binary"}})
+	if res.ExitCode != -1 || !strings.Contains(res.Error, "启动命令失败") {
+		t.Errorf("missing binary result = %+v", res)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	res, _ = run(ctx, Command{Argv: []string{"cliq-no-such-binary"}})
+	if !res.Canceled {
+		t.Errorf("cancelled before start result = %+v", res)
+	}
+}
+
+func TestLineWriterSplitsLongLines(t *testing.T) {
+	var got []string
+	w := &lineWriter{stream: StreamStdout, emit: func(_ Stream, line string) { got = append(got, line) }}
+	w.Write(bytes.Repeat([]byte("x"), maxLineSize+10))
+	w.Write([]byte("\n"))
+	w.flush()
+	if len(got) != 2 || len(got[0]) != maxLineSize+10 || got[1] != "" {
+		t.Errorf("got %d lines", len(got))
+	}
+
+	got = nil
+	w.Write([]byte("a\r"))
+	w.Write([]byte("\nb\r"))
+	w.flush()
+	if !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Errorf("split \\r\\n across writes = %q", got)
+	}
+}
+
+// waitForFile waits until a script has written a pid to path.
+func waitForFile(t *testing.T, path string) int {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if b, err := os.ReadFile(path); err == nil {
+			if pid, err := strconv.Atoi(strings.TrimSpace(string(b))); err == nil {
+				return pid
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("%s was not written", path)
+	return 0
+}
+
+// alive reports whether pid still runs, treating zombies as gone.
+func alive(pid int) bool {
+	if b, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "stat")); err == nil {
+		fields := strings.Fields(string(b))
+		return len(fields) > 2 && fields[2] != "Z"
+	}
+	p, err := os.FindProcess(pid)
+	return err == nil && p.Signal(syscall.Signal(0)) == nil
+}
+
+func waitGone(pid int) bool {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if !alive(pid) {
+			return true
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	return false
+}
+
+func TestRunCancelAndTimeout(t *testing.T) {
+	skipWithoutSh(t)
+	tests := []struct {
+		name     string
+		script   string
+		timeout  time.Duration
+		cancel   bool
+		canceled bool
+		timedOut bool
+		maxTime  time.Duration
+	}{
+		// sh exits on SIGTERM, and so does its sleeping child
+		{"cancel", "sleep 30 & echo $! > PID; wait", 0, true, true, false, terminateGrace},
+		{"timeout", "sleep 30 & echo $! > PID; wait", 200 * time.Millisecond, false, false, true, terminateGrace},
+		// a group that ignores SIGTERM is killed after the grace period
+		{"ignores sigterm", "trap '' TERM; sleep 30 & echo $! > PID; wait; wait", 0, true, true, false, terminateGrace + 3*time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.name == "ignores sigterm" && testing.Short() {
+				t.Skip("waits for the grace period")
+			}
+			dir := t.TempDir()
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tt.timeout > 0 {
+				ctx, cancel = context.WithTimeout(ctx, tt.timeout)
+				defer cancel()
+			}
+			c := sh(tt.script)
+			c.Dir = dir
+			done := make(chan Result, 1)
+			start := time.Now()
+			go func() {
+				res, _ := run(ctx, c)
+				done <- res
+			}()
+			child := waitForFile(t, filepath.Join(dir, "PID"))
+			if tt.cancel {
+				cancel()
+			}
+			var res Result
+			select {
+			case res = <-done:
+			case <-time.After(tt.maxTime + 5*time.Second):
+				t.Fatal("Run did not return")
+			}
+			if elapsed := time.Since(start); elapsed > tt.maxTime+2*time.Second {
+				t.Errorf("Run took %v", elapsed)
+			}
+			if res.Canceled != tt.canceled || res.TimedOut != tt.timedOut || res.ExitCode == 0 {
+				t.Errorf("result = %+v", res)
+			}
+			if !waitGone(child) {
+				t.Errorf("child %d survived", child)
+			}
+		})
+	}
+}
+
+// Children left running in the background are killed once the command
+// exits, and Run does not wait for them.
+func TestRunKillsBackgroundChildren(t *testing.T) {
+	skipWithoutSh(t)
+	dir := t.TempDir()
+	c := sh("sleep 30 >/dev/null 2>&1 & echo $! > PID; echo started")
+	c.Dir = dir
+	start := time.Now()
+	res, lines := run(context.Background(), c)
+	if res.ExitCode != 0 || res.Canceled || !reflect.DeepEqual(lines[StreamStdout], []string{"started"}) {
+		t.Errorf("result = %+v, output %q", res, lines)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Run waited %v for the background child", elapsed)
+	}
+	child := waitForFile(t, filepath.Join(dir, "PID"))
+	if !waitGone(child) {
+		t.Errorf("background child %d survived", child)
+	}
+}
+
+func TestRunDirEnvAndStdin(t *testing.T) {
+	skipWithoutSh(t)
+	dir := t.TempDir()
+	input := filepath.Join(dir, "in.txt")
+	if err := os.WriteFile(input, []byte("from file\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CLIQ_TEST_INHERITED", "inherited")
+	t.Setenv("CLIQ_TEST_OVERRIDDEN", "old")
+	real, _ := filepath.EvalSymlinks(dir)
+
+	tests := []struct {
+		name string
+		c    Command
+		want []string
+	}{
+		{"workdir", Command{Argv: []string{"sh", "-c", "pwd -P"}, Dir: dir}, []string{real}},
+		{"env merges with the inherited environment", Command{
+			Argv: []string{"sh", "-c", `echo "$CLIQ_TEST_INHERITED $CLIQ_TEST_OVERRIDDEN $CLIQ_TEST_NEW"`},
+			Env:  map[string]string{"CLIQ_TEST_OVERRIDDEN": "new", "CLIQ_TEST_NEW": "a b"},
+		}, []string{"inherited new a b"}},
+		{"no env keeps the inherited environment", Command{
+			Argv: []string{"sh", "-c", `echo "$CLIQ_TEST_INHERITED"`},
+		}, []string{"inherited"}},
+		{"stdin text", Command{Argv: []string{"cat"}, Stdin: "line 1\nline 2"}, []string{"line 1", "line 2"}},
+		{"stdin file", Command{Argv: []string{"cat"}, StdinFile: input}, []string{"from file"}},
+		{"stdin file wins over text", Command{Argv: []string{"cat"}, StdinFile: input, Stdin: "text"}, []string{"from file"}},
+		{"no stdin", Command{Argv: []string{"sh", "-c", "cat; echo done"}}, []string{"done"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, lines := run(context.Background(), tt.c)
+			if res.ExitCode != 0 {
+				t.Fatalf("result = %+v", res)
+			}
+			if !reflect.DeepEqual(lines[StreamStdout], tt.want) {
+				t.Errorf("stdout = %q, want %q", lines[StreamStdout], tt.want)
+			}
+		})
+	}
+
+	res, _ := run(context.Background(), Command{Argv: []string{"cat"}, StdinFile: filepath.Join(dir, "missing")})
+	if !strings.Contains(res.Error, "打开标准输入文件失败") {
+		t.Errorf("missing stdin file result = %+v", res)
+	}
+}
+
+func TestRunRawStdout(t *testing.T) {
+	skipWithoutSh(t)
+	var raw bytes.Buffer
+	c := sh("printf 'a\\r\\nb\\rc'; echo err >&2")
+	c.Stdout = &raw
+	res, lines := run(context.Background(), c)
+	if res.ExitCode != 0 {
+		t.Fatalf("result = %+v", res)
+	}
+	if raw.String() != "a\r\nb\rc" {
+		t.Errorf("raw stdout = %q", raw.String())
+	}
+	if !reflect.DeepEqual(lines[StreamStdout], []string{"a", "b", "c"}) {
+		t.Errorf("stdout lines = %q", lines[StreamStdout])
+	}
+}
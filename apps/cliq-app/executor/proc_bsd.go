@@ -0,0 +1,33 @@
+//go:build darwin || dragonfly || freebsd || netbsd || openbsd
+
+package executor
+
+import "golang.org/x/sys/unix"
+
+// waitExited blocks until the group leader has exited, without reaping it.
+// These systems have no waitid(WNOWAIT) in x/sys, so a kqueue exit event is
+// used instead.
+func (g *procGroup) waitExited() error {
+	kq, err := unix.Kqueue()
+	if err != nil {
+		return err
+	}
+	defer unix.Close(kq)
+
+	var ev unix.Kevent_t
+	unix.SetKevent(&ev, g.pid, unix.EVFILT_PROC, unix.EV_ADD|unix.EV_ONESHOT)
+	ev.Fflags = unix.NOTE_EXIT
+	if _, err := unix.Kevent(kq, []unix.Kevent_t{ev}, nil, nil); err != nil {
+		if err == unix.ESRCH {
+			return nil // 已经退出, 尚未回收
+		}
+		return err
+	}
+	events := make([]unix.Kevent_t, 1)
+	for {
+		_, err := unix.Kevent(kq, nil, events, nil)
+		if err != unix.EINTR {
+			return err
+		}
+	}
+}
@@ -0,0 +1,16 @@
+//go:build linux
+
+package executor
+
+import "golang.org/x/sys/unix"
+
+// waitExited blocks until the group leader has exited, without reaping it.
+func (g *procGroup) waitExited() error {
+	for {
+		var info unix.Siginfo
+		err := unix.Waitid(unix.P_PID, g.pid, &info, unix.WEXITED|unix.WNOWAIT, nil)
+		if err != unix.EINTR {
+			return err
+		}
+	}
+}
@@ -0,0 +1,11 @@
+//go:build !windows && !linux && !darwin && !dragonfly && !freebsd && !netbsd && !openbsd
+
+package executor
+
+import "errors"
+
+// waitExited is not supported here; Run then skips release, as the group
+// could no longer be signalled safely once the leader is reaped.
+func (g *procGroup) waitExited() error {
+	return errors.ErrUnsupported
+}
@@ -0,0 +1,43 @@
+//go:build !windows
+
+package executor
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// procGroup is the process group the command leads.
+type procGroup struct {
+	pid int
+}
+
+// setProcessGroup makes the command the leader of a new process group.
+func setProcessGroup(cmd *exec.Cmd) *procGroup {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+	return &procGroup{}
+}
+
+// start records the group of the started command.
+func (g *procGroup) start(cmd *exec.Cmd) error {
+	g.pid = cmd.Process.Pid
+	return nil
+}
+
+// terminate asks every process in the group to exit.
+func (g *procGroup) terminate() error {
+	return syscall.Kill(-g.pid, syscall.SIGTERM)
+}
+
+// kill forcibly kills every process in the group.
+func (g *procGroup) kill() error {
+	return syscall.Kill(-g.pid, syscall.SIGKILL)
+}
+
+// release kills whatever is left of the group after the leader exited, such
+// as background children. It must be called after waitExited and before the
+// leader is reaped: until then the leader is a zombie member of the group,
+// so the group ID cannot have been reused by an unrelated process.
+func (g *procGroup) release() {
+	_ = g.kill()
+}
@@ -0,0 +1,126 @@
+//go:build windows
+
+package executor
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// procGroup is a job object holding the command and every process it
+// starts, so the whole tree can be killed without looking up PIDs.
+type procGroup struct {
+	pid int
+	job windows.Handle
+	// process stays open until release, so the command's PID is not reused
+	// while terminate may still address it.
+	process windows.Handle
+}
+
+// setProcessGroup starts the command suspended in a new process group, so
+// that start can put it into the job before it runs any code.
+func setProcessGroup(cmd *exec.Cmd) *procGroup {
+	cmd.SysProcAttr = &syscall.SysProcAttr{CreationFlags: syscall.CREATE_NEW_PROCESS_GROUP | windows.CREATE_SUSPENDED}
+	return &procGroup{}
+}
+
+// start puts the suspended command into a new job object and then resumes
+// it, so every process it starts belongs to the job as well. Closing the job
+// kills them all.
+func (g *procGroup) start(cmd *exec.Cmd) error {
+	g.pid = cmd.Process.Pid
+	job, err := windows.CreateJobObject(nil, nil)
+	if err != nil {
+		return err
+	}
+	info := windows.JOBOBJECT_EXTENDED_LIMIT_INFORMATION{
+		BasicLimitInformation: windows.JOBOBJECT_BASIC_LIMIT_INFORMATION{
+			LimitFlags: windows.JOB_OBJECT_LIMIT_KILL_ON_JOB_CLOSE,
+		},
+	}
+	if _, err := windows.SetInformationJobObject(job, windows.JobObjectExtendedLimitInformation,
+		uintptr(unsafe.Pointer(&info)), uint32(unsafe.Sizeof(info))); err != nil {
+		windows.CloseHandle(job)
+		return err
+	}
+	process, err := windows.OpenProcess(windows.PROCESS_SET_QUOTA|windows.PROCESS_TERMINATE|windows.SYNCHRONIZE, false, uint32(g.pid))
+	if err != nil {
+		windows.CloseHandle(job)
+		return err
+	}
+	if err := windows.AssignProcessToJobObject(job, process); err != nil {
+		windows.CloseHandle(process)
+		windows.CloseHandle(job)
+		return err
+	}
+	if err := resumeThreads(uint32(g.pid)); err != nil {
+		windows.CloseHandle(process)
+		windows.CloseHandle(job)
+		return err
+	}
+	g.job, g.process = job, process
+	return nil
+}
+
+// resumeThreads resumes the threads of a process created suspended. The
+// thread handle CreateProcess returned is not exposed by os/exec, so the
+// threads are looked up in a snapshot.
+func resumeThreads(pid uint32) error {
+	snapshot, err := windows.CreateToolhelp32Snapshot(windows.TH32CS_SNAPTHREAD, 0)
+	if err != nil {
+		return err
+	}
+	defer windows.CloseHandle(snapshot)
+
+	entry := windows.ThreadEntry32{Size: uint32(unsafe.Sizeof(windows.ThreadEntry32{}))}
+	resumed := false
+	for err = windows.Thread32First(snapshot, &entry); err == nil; err = windows.Thread32Next(snapshot, &entry) {
+		if entry.OwnerProcessID != pid {
+			continue
+		}
+		thread, err := windows.OpenThread(windows.THREAD_SUSPEND_RESUME, false, entry.ThreadID)
+		if err != nil {
+			return err
+		}
+		_, err = windows.ResumeThread(thread)
+		windows.CloseHandle(thread)
+		if err != nil {
+			return err
+		}
+		resumed = true
+	}
+	if !resumed {
+		return fmt.Errorf("进程 %d 没有可恢复的线程", pid)
+	}
+	return nil
+}
+
+// waitExited blocks until the command has exited.
+func (g *procGroup) waitExited() error {
+	_, err := windows.WaitForSingleObject(g.process, windows.INFINITE)
+	return err
+}
+
+// terminate asks the process tree to exit. Console programs cannot be
+// signalled individually on Windows, so taskkill without /F is used.
+func (g *procGroup) terminate() error {
+	return exec.Command("taskkill", "/T", "/PID", strconv.Itoa(g.pid)).Run()
+}
+
+// kill forcibly kills every process in the job.
+func (g *procGroup) kill() error {
+	return windows.TerminateJobObject(g.job, 1)
+}
+
+// release kills whatever is left in the job after the command exited and
+// closes the handles.
+func (g *procGroup) release() {
+	_ = g.kill()
+	windows.CloseHandle(g.job)
+	windows.CloseHandle(g.process)
+}
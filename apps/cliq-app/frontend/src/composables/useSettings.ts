@@ -2,9 +2,12 @@ import { ref } from 'vue'
 
 export type AppSettings = {
   cliq_hub_base_url: string
+  max_concurrent_jobs?: number
+  remember_values?: boolean
 }
 
 export const DEFAULT_BASE_URL = 'http://localhost:8080'
+export const DEFAULT_MAX_CONCURRENT_JOBS = 2
 
 const settingsRef = ref<AppSettings | null>(null)
 
@@ -1,45 +1,123 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
+import {safety} from '../models';
+import {deps} from '../models';
 import {models} from '../models';
+import {handlers} from '../models';
 import {config} from '../models';
+import {jobs} from '../models';
+import {history} from '../models';
 import {frontend} from '../models';
+import {recent} from '../models';
+
+export function AnalyzeCommand(arg1:string,arg2:Record<string, any>):Promise<safety.Report>;
+
+export function ApplyPreset(arg1:string,arg2:string,arg3:Record<string, any>):Promise<Record<string, any>>;
+
+export function CancelBatch(arg1:string):Promise<void>;
+
+export function CancelCommand(arg1:string):Promise<void>;
+
+export function CancelPipeline(arg1:string):Promise<void>;
+
+export function CheckCommandRequirements(arg1:string):Promise<deps.Report>;
+
+export function CheckTemplateRequirements(arg1:models.TemplateFile):Promise<deps.Report>;
+
+export function ClearHistory():Promise<void>;
+
+export function ClearValueHistory():Promise<void>;
+
+export function ComputeVariables(arg1:models.Command,arg2:Record<string, any>):Promise<Record<string, any>>;
 
 export function DeleteFavTemplate(arg1:string):Promise<void>;
 
-export function ExecuteCommand(arg1:string,arg2:Record<string, any>):Promise<string>;
+export function DeleteHistoryEntry(arg1:string):Promise<void>;
+
+export function DeletePreset(arg1:string,arg2:string):Promise<void>;
+
+export function ExecuteBatch(arg1:string,arg2:Record<string, any>,arg3:handlers.BatchRequest):Promise<string>;
+
+export function ExecuteCommand(arg1:string,arg2:Record<string, any>,arg3:boolean):Promise<string>;
+
+export function ExecutePipeline(arg1:string,arg2:Record<string, any>,arg3:boolean):Promise<string>;
 
 export function ExportTemplateToFile(arg1:models.TemplateFile,arg2:string):Promise<void>;
 
+export function FieldStates(arg1:models.Command,arg2:Record<string, any>):Promise<handlers.FieldStates>;
+
 export function GenerateYAMLFromTemplate(arg1:models.TemplateFile):Promise<string>;
 
 export function GetAppSettings():Promise<config.AppSettings>;
 
+export function GetBatch(arg1:string):Promise<jobs.Batch>;
+
 export function GetCommandText(arg1:string,arg2:Record<string, any>):Promise<string>;
 
 export function GetFavTemplate(arg1:string):Promise<models.TemplateFile>;
 
+export function GetHistoryEntry(arg1:string):Promise<history.Entry>;
+
+export function GetInstallPlan(arg1:string):Promise<deps.Plan>;
+
+export function GetJob(arg1:string):Promise<jobs.Job>;
+
+export function GetPipelineRun(arg1:string):Promise<jobs.PipelineRun>;
+
 export function ImportTemplate():Promise<models.TemplateFile>;
 
 export function ImportTemplateFromURL(arg1:string):Promise<models.TemplateFile>;
 
+export function InstallRequirement(arg1:string,arg2:boolean):Promise<string>;
+
+export function ListBatches():Promise<Array<jobs.Batch>>;
+
 export function ListFavTemplates():Promise<Array<models.TemplateFile>>;
 
+export function ListHistory(arg1:history.Filter):Promise<Array<history.Entry>>;
+
+export function ListJobs():Promise<Array<jobs.Job>>;
+
+export function ListPipelineRuns():Promise<Array<jobs.PipelineRun>>;
+
+export function ListPresets(arg1:string):Promise<handlers.CommandPresets>;
+
+export function LoadVariableOptions(arg1:string,arg2:string,arg3:Record<string, any>,arg4:boolean):Promise<handlers.VariableOptions>;
+
+export function OpenDirectoryDialog():Promise<string>;
+
 export function OpenFileDialog():Promise<string>;
 
 export function OpenFileDialogWithFilters(arg1:Array<frontend.FileFilter>):Promise<string>;
 
+export function OpenMultipleFilesDialog(arg1:Array<frontend.FileFilter>):Promise<Array<string>>;
+
 export function ParseCommandToTemplate(arg1:string):Promise<models.TemplateFile>;
 
 export function ParseYAMLToTemplate(arg1:string):Promise<models.TemplateFile>;
 
+export function RenamePreset(arg1:string,arg2:string,arg3:string):Promise<void>;
+
+export function RerunHistoryEntry(arg1:string,arg2:Record<string, any>,arg3:boolean):Promise<string>;
+
+export function ResolveTemplate(arg1:models.TemplateFile):Promise<models.TemplateFile>;
+
 export function SaveFavTemplate(arg1:models.TemplateFile):Promise<void>;
 
 export function SaveFileDialog():Promise<string>;
 
+export function SavePreset(arg1:string,arg2:models.Preset):Promise<void>;
+
 export function SaveYAMLToFile(arg1:string):Promise<void>;
 
+export function SetDefaultPreset(arg1:string,arg2:string):Promise<void>;
+
+export function SuggestValues(arg1:string,arg2:string,arg3:string,arg4:number):Promise<Array<recent.Entry>>;
+
 export function UpdateAppSettings(arg1:Record<string, any>):Promise<void>;
 
 export function UpdateFavTemplate(arg1:string,arg2:string,arg3:models.TemplateFile):Promise<void>;
 
+export function ValidateVariables(arg1:string,arg2:Record<string, any>):Promise<Record<string, string>>;
+
 export function ValidateYAMLTemplate(arg1:string):Promise<void>;
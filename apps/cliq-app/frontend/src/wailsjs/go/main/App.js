@@ -2,18 +2,78 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 
+export function AnalyzeCommand(arg1, arg2) {
+  return window['go']['main']['App']['AnalyzeCommand'](arg1, arg2);
+}
+
+export function ApplyPreset(arg1, arg2, arg3) {
+  return window['go']['main']['App']['ApplyPreset'](arg1, arg2, arg3);
+}
+
+export function CancelBatch(arg1) {
+  return window['go']['main']['App']['CancelBatch'](arg1);
+}
+
+export function CancelCommand(arg1) {
+  return window['go']['main']['App']['CancelCommand'](arg1);
+}
+
+export function CancelPipeline(arg1) {
+  return window['go']['main']['App']['CancelPipeline'](arg1);
+}
+
+export function CheckCommandRequirements(arg1) {
+  return window['go']['main']['App']['CheckCommandRequirements'](arg1);
+}
+
+export function CheckTemplateRequirements(arg1) {
+  return window['go']['main']['App']['CheckTemplateRequirements'](arg1);
+}
+
+export function ClearHistory() {
+  return window['go']['main']['App']['ClearHistory']();
+}
+
+export function ClearValueHistory() {
+  return window['go']['main']['App']['ClearValueHistory']();
+}
+
+export function ComputeVariables(arg1, arg2) {
+  return window['go']['main']['App']['ComputeVariables'](arg1, arg2);
+}
+
 export function DeleteFavTemplate(arg1) {
   return window['go']['main']['App']['DeleteFavTemplate'](arg1);
 }
 
-export function ExecuteCommand(arg1, arg2) {
-  return window['go']['main']['App']['ExecuteCommand'](arg1, arg2);
+export function DeleteHistoryEntry(arg1) {
+  return window['go']['main']['App']['DeleteHistoryEntry'](arg1);
+}
+
+export function DeletePreset(arg1, arg2) {
+  return window['go']['main']['App']['DeletePreset'](arg1, arg2);
+}
+
+export function ExecuteBatch(arg1, arg2, arg3) {
+  return window['go']['main']['App']['ExecuteBatch'](arg1, arg2, arg3);
+}
+
+export function ExecuteCommand(arg1, arg2, arg3) {
+  return window['go']['main']['App']['ExecuteCommand'](arg1, arg2, arg3);
+}
+
+export function ExecutePipeline(arg1, arg2, arg3) {
+  return window['go']['main']['App']['ExecutePipeline'](arg1, arg2, arg3);
 }
 
 export function ExportTemplateToFile(arg1, arg2) {
   return window['go']['main']['App']['ExportTemplateToFile'](arg1, arg2);
 }
 
+export function FieldStates(arg1, arg2) {
+  return window['go']['main']['App']['FieldStates'](arg1, arg2);
+}
+
 export function GenerateYAMLFromTemplate(arg1) {
   return window['go']['main']['App']['GenerateYAMLFromTemplate'](arg1);
 }
@@ -22,6 +82,10 @@ export function GetAppSettings() {
   return window['go']['main']['App']['GetAppSettings']();
 }
 
+export function GetBatch(arg1) {
+  return window['go']['main']['App']['GetBatch'](arg1);
+}
+
 export function GetCommandText(arg1, arg2) {
   return window['go']['main']['App']['GetCommandText'](arg1, arg2);
 }
@@ -30,6 +94,22 @@ export function GetFavTemplate(arg1) {
   return window['go']['main']['App']['GetFavTemplate'](arg1);
 }
 
+export function GetHistoryEntry(arg1) {
+  return window['go']['main']['App']['GetHistoryEntry'](arg1);
+}
+
+export function GetInstallPlan(arg1) {
+  return window['go']['main']['App']['GetInstallPlan'](arg1);
+}
+
+export function GetJob(arg1) {
+  return window['go']['main']['App']['GetJob'](arg1);
+}
+
+export function GetPipelineRun(arg1) {
+  return window['go']['main']['App']['GetPipelineRun'](arg1);
+}
+
 export function ImportTemplate() {
   return window['go']['main']['App']['ImportTemplate']();
 }
@@ -38,10 +118,42 @@ export function ImportTemplateFromURL(arg1) {
   return window['go']['main']['App']['ImportTemplateFromURL'](arg1);
 }
 
+export function InstallRequirement(arg1, arg2) {
+  return window['go']['main']['App']['InstallRequirement'](arg1, arg2);
+}
+
+export function ListBatches() {
+  return window['go']['main']['App']['ListBatches']();
+}
+
 export function ListFavTemplates() {
   return window['go']['main']['App']['ListFavTemplates']();
 }
 
+export function ListHistory(arg1) {
+  return window['go']['main']['App']['ListHistory'](arg1);
+}
+
+export function ListJobs() {
+  return window['go']['main']['App']['ListJobs']();
+}
+
+export function ListPipelineRuns() {
+  return window['go']['main']['App']['ListPipelineRuns']();
+}
+
+export function ListPresets(arg1) {
+  return window['go']['main']['App']['ListPresets'](arg1);
+}
+
+export function LoadVariableOptions(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['LoadVariableOptions'](arg1, arg2, arg3, arg4);
+}
+
+export function OpenDirectoryDialog() {
+  return window['go']['main']['App']['OpenDirectoryDialog']();
+}
+
 export function OpenFileDialog() {
   return window['go']['main']['App']['OpenFileDialog']();
 }
@@ -50,6 +162,10 @@ export function OpenFileDialogWithFilters(arg1) {
   return window['go']['main']['App']['OpenFileDialogWithFilters'](arg1);
 }
 
+export function OpenMultipleFilesDialog(arg1) {
+  return window['go']['main']['App']['OpenMultipleFilesDialog'](arg1);
+}
+
 export function ParseCommandToTemplate(arg1) {
   return window['go']['main']['App']['ParseCommandToTemplate'](arg1);
 }
@@ -58,6 +174,18 @@ export function ParseYAMLToTemplate(arg1) {
   return window['go']['main']['App']['ParseYAMLToTemplate'](arg1);
 }
 
+export function RenamePreset(arg1, arg2, arg3) {
+  return window['go']['main']['App']['RenamePreset'](arg1, arg2, arg3);
+}
+
+export function RerunHistoryEntry(arg1, arg2, arg3) {
+  return window['go']['main']['App']['RerunHistoryEntry'](arg1, arg2, arg3);
+}
+
+export function ResolveTemplate(arg1) {
+  return window['go']['main']['App']['ResolveTemplate'](arg1);
+}
+
 export function SaveFavTemplate(arg1) {
   return window['go']['main']['App']['SaveFavTemplate'](arg1);
 }
@@ -66,10 +194,22 @@ export function SaveFileDialog() {
   return window['go']['main']['App']['SaveFileDialog']();
 }
 
+export function SavePreset(arg1, arg2) {
+  return window['go']['main']['App']['SavePreset'](arg1, arg2);
+}
+
 export function SaveYAMLToFile(arg1) {
   return window['go']['main']['App']['SaveYAMLToFile'](arg1);
 }
 
+export function SetDefaultPreset(arg1, arg2) {
+  return window['go']['main']['App']['SetDefaultPreset'](arg1, arg2);
+}
+
+export function SuggestValues(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['SuggestValues'](arg1, arg2, arg3, arg4);
+}
+
 export function UpdateAppSettings(arg1) {
   return window['go']['main']['App']['UpdateAppSettings'](arg1);
 }
@@ -78,6 +218,10 @@ export function UpdateFavTemplate(arg1, arg2, arg3) {
   return window['go']['main']['App']['UpdateFavTemplate'](arg1, arg2, arg3);
 }
 
+export function ValidateVariables(arg1, arg2) {
+  return window['go']['main']['App']['ValidateVariables'](arg1, arg2);
+}
+
 export function ValidateYAMLTemplate(arg1) {
   return window['go']['main']['App']['ValidateYAMLTemplate'](arg1);
 }
@@ -1,7 +1,9 @@
 export namespace config {
 	
 	export class AppSettings {
-	    CliqHubBaseURL: string;
+	    cliq_hub_base_url: string;
+	    max_concurrent_jobs: number;
+	    remember_values: boolean;
 	
 	    static createFrom(source: any = {}) {
 	        return new AppSettings(source);
@@ -9,33 +11,768 @@ export namespace config {
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.CliqHubBaseURL = source["CliqHubBaseURL"];
+	        this.cliq_hub_base_url = source["cliq_hub_base_url"];
+	        this.max_concurrent_jobs = source["max_concurrent_jobs"];
+	        this.remember_values = source["remember_values"];
 	    }
 	}
 
 }
 
+export namespace deps {
+	
+	export class Plan {
+	    name: string;
+	    method: string;
+	    command_line: string;
+	    argv?: string[];
+	    url?: string;
+	    sha256?: string;
+	    dest?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Plan(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.method = source["method"];
+	        this.command_line = source["command_line"];
+	        this.argv = source["argv"];
+	        this.url = source["url"];
+	        this.sha256 = source["sha256"];
+	        this.dest = source["dest"];
+	    }
+	}
+	export class Result {
+	    name: string;
+	    status: string;
+	    path?: string;
+	    version?: string;
+	    min_version?: string;
+	    install_hint?: string;
+	    error?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Result(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.status = source["status"];
+	        this.path = source["path"];
+	        this.version = source["version"];
+	        this.min_version = source["min_version"];
+	        this.install_hint = source["install_hint"];
+	        this.error = source["error"];
+	    }
+	}
+	export class Report {
+	    ok: boolean;
+	    results: Result[];
+	    message?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Report(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.ok = source["ok"];
+	        this.results = this.convertValues(source["results"], Result);
+	        this.message = source["message"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+
+}
+
+export namespace executor {
+	
+	export class OutputLine {
+	    job_id: string;
+	    stream: string;
+	    line: string;
+	    // Go type: time
+	    time: any;
+	
+	    static createFrom(source: any = {}) {
+	        return new OutputLine(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.job_id = source["job_id"];
+	        this.stream = source["stream"];
+	        this.line = source["line"];
+	        this.time = this.convertValues(source["time"], null);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+
+}
+
 export namespace frontend {
 	
-	export class FileFilter {
-	    DisplayName: string;
-	    Pattern: string;
+	export class FileFilter {
+	    DisplayName: string;
+	    Pattern: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new FileFilter(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.DisplayName = source["DisplayName"];
+	        this.Pattern = source["Pattern"];
+	    }
+	}
+
+}
+
+export namespace handlers {
+	
+	export class BatchRequest {
+	    input_variable: string;
+	    source: string;
+	    output_variable: string;
+	    output_pattern: string;
+	    parallelism: number;
+	    confirmed: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new BatchRequest(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.input_variable = source["input_variable"];
+	        this.source = source["source"];
+	        this.output_variable = source["output_variable"];
+	        this.output_pattern = source["output_pattern"];
+	        this.parallelism = source["parallelism"];
+	        this.confirmed = source["confirmed"];
+	    }
+	}
+	export class Preset {
+	    name: string;
+	    description?: string;
+	    values: Record<string, any>;
+	    user: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new Preset(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.description = source["description"];
+	        this.values = source["values"];
+	        this.user = source["user"];
+	    }
+	}
+	export class CommandPresets {
+	    presets: Preset[];
+	    default: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new CommandPresets(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.presets = this.convertValues(source["presets"], Preset);
+	        this.default = source["default"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class FieldStates {
+	    hidden: string[];
+	    required: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new FieldStates(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.hidden = source["hidden"];
+	        this.required = source["required"];
+	    }
+	}
+	
+	export class VariableOptions {
+	    choices: models.Choice[];
+	    pending?: string[];
+	    cached: boolean;
+	    // Go type: time
+	    loaded_at: any;
+	
+	    static createFrom(source: any = {}) {
+	        return new VariableOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.choices = this.convertValues(source["choices"], models.Choice);
+	        this.pending = source["pending"];
+	        this.cached = source["cached"];
+	        this.loaded_at = this.convertValues(source["loaded_at"], null);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+
+}
+
+export namespace history {
+	
+	export class Entry {
+	    id: string;
+	    template_name: string;
+	    template_version: string;
+	    command_id: string;
+	    command_name: string;
+	    variables: Record<string, any>;
+	    argv: string[];
+	    workdir?: string;
+	    env?: Record<string, string>;
+	    stdin_file?: string;
+	    stdin?: string;
+	    redacted?: boolean;
+	    status: string;
+	    // Go type: time
+	    started_at: any;
+	    // Go type: time
+	    finished_at: any;
+	    exit_code: number;
+	    error?: string;
+	    output: string;
+	    output_truncated: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new Entry(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.template_name = source["template_name"];
+	        this.template_version = source["template_version"];
+	        this.command_id = source["command_id"];
+	        this.command_name = source["command_name"];
+	        this.variables = source["variables"];
+	        this.argv = source["argv"];
+	        this.workdir = source["workdir"];
+	        this.env = source["env"];
+	        this.stdin_file = source["stdin_file"];
+	        this.stdin = source["stdin"];
+	        this.redacted = source["redacted"];
+	        this.status = source["status"];
+	        this.started_at = this.convertValues(source["started_at"], null);
+	        this.finished_at = this.convertValues(source["finished_at"], null);
+	        this.exit_code = source["exit_code"];
+	        this.error = source["error"];
+	        this.output = source["output"];
+	        this.output_truncated = source["output_truncated"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class Filter {
+	    template_name: string;
+	    command_id: string;
+	    status: string;
+	    query: string;
+	    limit: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new Filter(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.template_name = source["template_name"];
+	        this.command_id = source["command_id"];
+	        this.status = source["status"];
+	        this.query = source["query"];
+	        this.limit = source["limit"];
+	    }
+	}
+
+}
+
+export namespace jobs {
+	
+	export class BatchItem {
+	    input: string;
+	    output?: string;
+	    job_id?: string;
+	    status: string;
+	    error?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new BatchItem(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.input = source["input"];
+	        this.output = source["output"];
+	        this.job_id = source["job_id"];
+	        this.status = source["status"];
+	        this.error = source["error"];
+	    }
+	}
+	export class Batch {
+	    id: string;
+	    template_name: string;
+	    command_id: string;
+	    command_name: string;
+	    parallelism: number;
+	    status: string;
+	    items: BatchItem[];
+	    total: number;
+	    succeeded: number;
+	    failed: number;
+	    canceled: number;
+	    // Go type: time
+	    created_at: any;
+	    // Go type: time
+	    finished_at?: any;
+	
+	    static createFrom(source: any = {}) {
+	        return new Batch(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.template_name = source["template_name"];
+	        this.command_id = source["command_id"];
+	        this.command_name = source["command_name"];
+	        this.parallelism = source["parallelism"];
+	        this.status = source["status"];
+	        this.items = this.convertValues(source["items"], BatchItem);
+	        this.total = source["total"];
+	        this.succeeded = source["succeeded"];
+	        this.failed = source["failed"];
+	        this.canceled = source["canceled"];
+	        this.created_at = this.convertValues(source["created_at"], null);
+	        this.finished_at = this.convertValues(source["finished_at"], null);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
+	export class Progress {
+	    job_id: string;
+	    percent: number;
+	    current?: number;
+	    total?: number;
+	    eta_ms?: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new Progress(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.job_id = source["job_id"];
+	        this.percent = source["percent"];
+	        this.current = source["current"];
+	        this.total = source["total"];
+	        this.eta_ms = source["eta_ms"];
+	    }
+	}
+	export class Job {
+	    id: string;
+	    template_name: string;
+	    template_version: string;
+	    command_id: string;
+	    command_name: string;
+	    variables?: Record<string, any>;
+	    argv: string[];
+	    workdir?: string;
+	    env?: Record<string, string>;
+	    stdin_file?: string;
+	    stdin?: string;
+	    status: string;
+	    // Go type: time
+	    created_at: any;
+	    // Go type: time
+	    started_at?: any;
+	    // Go type: time
+	    finished_at?: any;
+	    exit_code: number;
+	    duration_ms: number;
+	    error?: string;
+	    output?: executor.OutputLine[];
+	    result?: output.Result;
+	    progress?: Progress;
+	    redacted?: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new Job(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.template_name = source["template_name"];
+	        this.template_version = source["template_version"];
+	        this.command_id = source["command_id"];
+	        this.command_name = source["command_name"];
+	        this.variables = source["variables"];
+	        this.argv = source["argv"];
+	        this.workdir = source["workdir"];
+	        this.env = source["env"];
+	        this.stdin_file = source["stdin_file"];
+	        this.stdin = source["stdin"];
+	        this.status = source["status"];
+	        this.created_at = this.convertValues(source["created_at"], null);
+	        this.started_at = this.convertValues(source["started_at"], null);
+	        this.finished_at = this.convertValues(source["finished_at"], null);
+	        this.exit_code = source["exit_code"];
+	        this.duration_ms = source["duration_ms"];
+	        this.error = source["error"];
+	        this.output = this.convertValues(source["output"], executor.OutputLine);
+	        this.result = this.convertValues(source["result"], output.Result);
+	        this.progress = this.convertValues(source["progress"], Progress);
+	        this.redacted = source["redacted"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class StepState {
+	    id: string;
+	    name: string;
+	    command_id: string;
+	    continue_on_error: boolean;
+	    job_id?: string;
+	    status: string;
+	    output?: string;
+	    error?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new StepState(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.name = source["name"];
+	        this.command_id = source["command_id"];
+	        this.continue_on_error = source["continue_on_error"];
+	        this.job_id = source["job_id"];
+	        this.status = source["status"];
+	        this.output = source["output"];
+	        this.error = source["error"];
+	    }
+	}
+	export class PipelineRun {
+	    id: string;
+	    template_name: string;
+	    pipeline_id: string;
+	    pipeline_name: string;
+	    status: string;
+	    steps: StepState[];
+	    current: number;
+	    // Go type: time
+	    created_at: any;
+	    // Go type: time
+	    finished_at?: any;
+	
+	    static createFrom(source: any = {}) {
+	        return new PipelineRun(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.template_name = source["template_name"];
+	        this.pipeline_id = source["pipeline_id"];
+	        this.pipeline_name = source["pipeline_name"];
+	        this.status = source["status"];
+	        this.steps = this.convertValues(source["steps"], StepState);
+	        this.current = source["current"];
+	        this.created_at = this.convertValues(source["created_at"], null);
+	        this.finished_at = this.convertValues(source["finished_at"], null);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
+
+}
+
+export namespace models {
+	
+	export class Choice {
+	    value: string;
+	    label?: string;
 	
 	    static createFrom(source: any = {}) {
-	        return new FileFilter(source);
+	        return new Choice(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.DisplayName = source["DisplayName"];
-	        this.Pattern = source["Pattern"];
+	        this.value = source["value"];
+	        this.label = source["label"];
 	    }
 	}
-
-}
-
-export namespace models {
+	export class Preset {
+	    name: string;
+	    description?: string;
+	    values: Record<string, any>;
+	    default?: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new Preset(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.description = source["description"];
+	        this.values = source["values"];
+	        this.default = source["default"];
+	    }
+	}
+	export class Installer {
+	    method: string;
+	    os?: string;
+	    package?: string;
+	    url?: string;
+	    sha256?: string;
+	    dest?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Installer(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.method = source["method"];
+	        this.os = source["os"];
+	        this.package = source["package"];
+	        this.url = source["url"];
+	        this.sha256 = source["sha256"];
+	        this.dest = source["dest"];
+	    }
+	}
+	export class Requirement {
+	    name: string;
+	    min_version?: string;
+	    version_command?: string;
+	    version_pattern?: string;
+	    install?: Record<string, string>;
+	    installers?: Installer[];
+	
+	    static createFrom(source: any = {}) {
+	        return new Requirement(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.min_version = source["min_version"];
+	        this.version_command = source["version_command"];
+	        this.version_pattern = source["version_pattern"];
+	        this.install = source["install"];
+	        this.installers = this.convertValues(source["installers"], Installer);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class ProgressSpec {
+	    pattern: string;
+	    total_pattern?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ProgressSpec(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.pattern = source["pattern"];
+	        this.total_pattern = source["total_pattern"];
+	    }
+	}
+	export class OutputSpec {
+	    format: string;
+	    rows?: string;
+	    columns?: string[];
+	    delimiter?: string;
+	    pattern?: string;
+	    extract?: Record<string, string>;
+	
+	    static createFrom(source: any = {}) {
+	        return new OutputSpec(source);
+	    }
 	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.format = source["format"];
+	        this.rows = source["rows"];
+	        this.columns = source["columns"];
+	        this.delimiter = source["delimiter"];
+	        this.pattern = source["pattern"];
+	        this.extract = source["extract"];
+	    }
+	}
 	export class VariableDefinition {
 	    name: string;
 	    type: string;
@@ -43,6 +780,10 @@ export namespace models {
 	    label: string;
 	    description: string;
 	    required: boolean;
+	    env?: string;
+	    computed?: string;
+	    show_if?: string;
+	    required_if?: string;
 	    options?: Record<string, any>;
 	
 	    static createFrom(source: any = {}) {
@@ -57,6 +798,10 @@ export namespace models {
 	        this.label = source["label"];
 	        this.description = source["description"];
 	        this.required = source["required"];
+	        this.env = source["env"];
+	        this.computed = source["computed"];
+	        this.show_if = source["show_if"];
+	        this.required_if = source["required_if"];
 	        this.options = source["options"];
 	    }
 	}
@@ -66,6 +811,16 @@ export namespace models {
 	    description: string;
 	    command: string;
 	    variables: VariableDefinition[];
+	    timeout?: string;
+	    workdir?: string;
+	    env?: Record<string, string>;
+	    stdin?: string;
+	    output?: OutputSpec;
+	    progress?: ProgressSpec;
+	    requires?: Requirement[];
+	    presets?: Preset[];
+	    extends?: string;
+	    include?: string[];
 	
 	    static createFrom(source: any = {}) {
 	        return new Command(source);
@@ -78,6 +833,74 @@ export namespace models {
 	        this.description = source["description"];
 	        this.command = source["command"];
 	        this.variables = this.convertValues(source["variables"], VariableDefinition);
+	        this.timeout = source["timeout"];
+	        this.workdir = source["workdir"];
+	        this.env = source["env"];
+	        this.stdin = source["stdin"];
+	        this.output = this.convertValues(source["output"], OutputSpec);
+	        this.progress = this.convertValues(source["progress"], ProgressSpec);
+	        this.requires = this.convertValues(source["requires"], Requirement);
+	        this.presets = this.convertValues(source["presets"], Preset);
+	        this.extends = source["extends"];
+	        this.include = source["include"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
+	
+	export class PipelineStep {
+	    id?: string;
+	    name?: string;
+	    command: string;
+	    with?: Record<string, string>;
+	    continue_on_error?: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new PipelineStep(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.name = source["name"];
+	        this.command = source["command"];
+	        this.with = source["with"];
+	        this.continue_on_error = source["continue_on_error"];
+	    }
+	}
+	export class Pipeline {
+	    id: string;
+	    name: string;
+	    description: string;
+	    steps: PipelineStep[];
+	
+	    static createFrom(source: any = {}) {
+	        return new Pipeline(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.name = source["name"];
+	        this.description = source["description"];
+	        this.steps = this.convertValues(source["steps"], PipelineStep);
 	    }
 	
 		convertValues(a: any, classs: any, asMap: boolean = false): any {
@@ -98,13 +921,20 @@ export namespace models {
 		    return a;
 		}
 	}
+	
+	
+	
+	
 	export class TemplateFile {
 	    name: string;
 	    description: string;
 	    version: string;
 	    author: string;
 	    cliq_template_version: string;
+	    requires?: Requirement[];
+	    variables?: VariableDefinition[];
 	    cmds: Command[];
+	    pipelines?: Pipeline[];
 	
 	    static createFrom(source: any = {}) {
 	        return new TemplateFile(source);
@@ -117,7 +947,184 @@ export namespace models {
 	        this.version = source["version"];
 	        this.author = source["author"];
 	        this.cliq_template_version = source["cliq_template_version"];
+	        this.requires = this.convertValues(source["requires"], Requirement);
+	        this.variables = this.convertValues(source["variables"], VariableDefinition);
 	        this.cmds = this.convertValues(source["cmds"], Command);
+	        this.pipelines = this.convertValues(source["pipelines"], Pipeline);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+
+}
+
+export namespace output {
+	
+	export class Column {
+	    name: string;
+	    type: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Column(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.type = source["type"];
+	    }
+	}
+	export class Field {
+	    key: string;
+	    value: string;
+	    type: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Field(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.key = source["key"];
+	        this.value = source["value"];
+	        this.type = source["type"];
+	    }
+	}
+	export class Result {
+	    format: string;
+	    columns?: Column[];
+	    rows?: string[][];
+	    truncated?: boolean;
+	    fields?: Field[];
+	    values?: Record<string, string>;
+	    error?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Result(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.format = source["format"];
+	        this.columns = this.convertValues(source["columns"], Column);
+	        this.rows = source["rows"];
+	        this.truncated = source["truncated"];
+	        this.fields = this.convertValues(source["fields"], Field);
+	        this.values = source["values"];
+	        this.error = source["error"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+
+}
+
+export namespace recent {
+	
+	export class Entry {
+	    value: string;
+	    count: number;
+	    // Go type: time
+	    last_used: any;
+	
+	    static createFrom(source: any = {}) {
+	        return new Entry(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.value = source["value"];
+	        this.count = source["count"];
+	        this.last_used = this.convertValues(source["last_used"], null);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+
+}
+
+export namespace safety {
+	
+	export class Finding {
+	    rule: string;
+	    severity: string;
+	    message: string;
+	    arg?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Finding(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.rule = source["rule"];
+	        this.severity = source["severity"];
+	        this.message = source["message"];
+	        this.arg = source["arg"];
+	    }
+	}
+	export class Report {
+	    findings: Finding[];
+	    severity?: string;
+	    requires_confirmation: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new Report(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.findings = this.convertValues(source["findings"], Finding);
+	        this.severity = source["severity"];
+	        this.requires_confirmation = source["requires_confirmation"];
 	    }
 	
 		convertValues(a: any, classs: any, asMap: boolean = false): any {
@@ -0,0 +1,217 @@
+package handlers
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"cliq/jobs"
+	"repo/shared-go-lib/models"
+	"repo/shared-go-lib/render"
+)
+
+// defaultOutputPattern 未指定命名规则时, 输出文件与输入文件同目录并加 _out 后缀
+const defaultOutputPattern = "{dir}/{stem}_out{ext}"
+
+// BatchRequest 描述一次批量执行: 对 Source 匹配到的每个文件运行一次命令
+type BatchRequest struct {
+	// InputVariable 为 file_input 类型变量名, 为空时自动选择命令中唯一的 file_input 变量
+	InputVariable string `json:"input_variable"`
+	// Source 为目录 (匹配目录下所有文件) 或 glob 模式, 如 ~/Videos/*.mov
+	Source string `json:"source"`
+	// OutputVariable 为 file_output 类型变量名, 为空时自动选择命令中唯一的 file_output 变量 (可以没有)
+	OutputVariable string `json:"output_variable"`
+	// OutputPattern 为输出文件命名规则, 支持 {dir} {name} {stem} {ext} {index}.
+	// 相对路径相对于输入文件所在目录
+	OutputPattern string `json:"output_pattern"`
+	// Parallelism 为该批次同时运行的最大数量
+	Parallelism int `json:"parallelism"`
+	// Confirmed 表示用户已确认执行包含高风险操作的命令
+	Confirmed bool `json:"confirmed"`
+}
+
+// ExecuteBatch 对多个输入文件批量执行同一个命令, 返回批次 ID.
+// 每个文件都是一个独立的任务, 进度通过 jobs.EventBatch 事件推送
+func (fh *FileHandler) ExecuteBatch(template *models.TemplateFile, commandID string, variables map[string]interface{}, req BatchRequest) (string, error) {
+	if template == nil {
+		return "", fmt.Errorf("模板未加载")
+	}
+	command, found := findCommand(template, commandID)
+	if !found {
+		return "", fmt.Errorf("未找到命令: %s", commandID)
+	}
+
+	if err := preflight(template, command); err != nil {
+		return "", err
+	}
+
+	inputVar, err := pickVariable(command, req.InputVariable, models.VarTypeFileInput, true)
+	if err != nil {
+		return "", err
+	}
+	outputVar, err := pickVariable(command, req.OutputVariable, models.VarTypeFileOutput, false)
+	if err != nil {
+		return "", err
+	}
+
+	inputs, err := expandBatchSource(req.Source, inputVar.FileOptions().FileTypes)
+	if err != nil {
+		return "", err
+	}
+
+	pattern := req.OutputPattern
+	if pattern == "" {
+		pattern = defaultOutputPattern
+	}
+
+	entries := make([]jobs.BatchEntry, 0, len(inputs))
+	for i, input := range inputs {
+		values := make(map[string]interface{}, len(variables)+2)
+		for k, v := range variables {
+			values[k] = v
+		}
+		values[inputVar.Name] = input
+
+		output := ""
+		if outputVar != nil {
+			output = batchOutputPath(pattern, input, i+1)
+			if output == input {
+				return "", fmt.Errorf("输出文件与输入文件相同: %s, 请调整命名规则", input)
+			}
+			values[outputVar.Name] = output
+		}
+
+		spec, err := buildSpec(template, command, values)
+		if err != nil {
+			return "", err
+		}
+		if err := checkRisk(analyzeSpec(command.Command, command, spec), req.Confirmed); err != nil {
+			return "", fmt.Errorf("%s: %w", input, err)
+		}
+		entries = append(entries, jobs.BatchEntry{Input: input, Output: output, Spec: spec})
+	}
+
+	batch := fh.jobs.SubmitBatch(template.Name, command.ID, command.Name, entries, req.Parallelism)
+	return batch.ID, nil
+}
+
+// CancelBatch 停止批量任务: 不再启动剩余文件, 并取消正在运行的任务
+func (fh *FileHandler) CancelBatch(batchID string) error {
+	return fh.jobs.CancelBatch(batchID)
+}
+
+// ListBatches 列出所有批量任务, 最新的在前
+func (fh *FileHandler) ListBatches() []jobs.Batch {
+	return fh.jobs.ListBatches()
+}
+
+// GetBatch 获取批量任务的逐项状态和汇总
+func (fh *FileHandler) GetBatch(batchID string) (jobs.Batch, error) {
+	return fh.jobs.GetBatch(batchID)
+}
+
+// pickVariable 查找指定类型的变量. name 为空时, 命令中恰好有一个该类型变量则自动选择
+func pickVariable(command models.Command, name, varType string, required bool) (*models.VariableDefinition, error) {
+	var candidates []*models.VariableDefinition
+	for i := range command.Variables {
+		v := &command.Variables[i]
+		if name != "" && v.Name == name {
+			if v.Type != varType {
+				return nil, fmt.Errorf("变量 '%s' 的类型为 %s, 需要 %s", name, v.Type, varType)
+			}
+			return v, nil
+		}
+		if v.Type == varType {
+			candidates = append(candidates, v)
+		}
+	}
+	switch {
+	case name != "":
+		return nil, fmt.Errorf("命令中不存在变量: %s", name)
+	case len(candidates) == 1:
+		return candidates[0], nil
+	case len(candidates) > 1:
+		return nil, fmt.Errorf("命令中有多个 %s 变量, 请指定要使用的变量", varType)
+	case required:
+		return nil, fmt.Errorf("命令中没有 %s 变量, 无法批量执行", varType)
+	}
+	return nil, nil
+}
+
+// expandBatchSource 将目录或 glob 展开为排序后的文件列表, 并按扩展名过滤
+func expandBatchSource(source string, fileTypes []string) ([]string, error) {
+	source = render.ExpandHome(strings.TrimSpace(source))
+	if source == "" {
+		return nil, fmt.Errorf("请指定批量处理的目录或文件匹配模式")
+	}
+
+	var candidates []string
+	if info, err := os.Stat(source); err == nil && info.IsDir() {
+		entries, err := os.ReadDir(source)
+		if err != nil {
+			return nil, fmt.Errorf("读取目录失败: %w", err)
+		}
+		for _, e := range entries {
+			candidates = append(candidates, filepath.Join(source, e.Name()))
+		}
+	} else {
+		matches, err := filepath.Glob(source)
+		if err != nil {
+			return nil, fmt.Errorf("无效的文件匹配模式 '%s': %w", source, err)
+		}
+		candidates = matches
+	}
+
+	var files []string
+	for _, path := range candidates {
+		info, err := os.Stat(path)
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		if !matchesFileTypes(path, fileTypes) {
+			continue
+		}
+		files = append(files, path)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("没有匹配的文件: %s", source)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// matchesFileTypes 判断文件扩展名是否在允许列表中, 空列表或 ".*" 表示不限制
+func matchesFileTypes(path string, fileTypes []string) bool {
+	if len(fileTypes) == 0 {
+		return true
+	}
+	ext := strings.ToLower(filepath.Ext(path))
+	for _, t := range fileTypes {
+		if t == ".*" || t == "*" || strings.ToLower(t) == ext {
+			return true
+		}
+	}
+	return false
+}
+
+// batchOutputPath 根据命名规则生成输出文件路径
+func batchOutputPath(pattern, input string, index int) string {
+	dir := filepath.Dir(input)
+	name := filepath.Base(input)
+	ext := filepath.Ext(name)
+	out := strings.NewReplacer(
+		"{dir}", dir,
+		"{name}", name,
+		"{stem}", strings.TrimSuffix(name, ext),
+		"{ext}", ext,
+		"{index}", strconv.Itoa(index),
+	).Replace(pattern)
+	out = render.ExpandHome(out)
+	if !filepath.IsAbs(out) {
+		out = filepath.Join(dir, out)
+	}
+	return filepath.Clean(out)
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"repo/shared-go-lib/models"
+)
+
+func TestBatchOutputPath(t *testing.T) {
+	dir := filepath.FromSlash("/videos/raw")
+	input := filepath.Join(dir, "clip.final.mov")
+	home, _ := os.UserHomeDir()
+	tests := []struct {
+		pattern string
+		want    string
+	}{
+		{defaultOutputPattern, filepath.Join(dir, "clip.final_out.mov")},
+		{"{stem}.mp4", filepath.Join(dir, "clip.final.mp4")},
+		{"out/{name}", filepath.Join(dir, "out", "clip.final.mov")},
+		{"../done/{index}-{stem}{ext}", filepath.Join(filepath.Dir(dir), "done", "7-clip.final.mov")},
+		{filepath.FromSlash("/tmp/{stem}_{index}.mkv"), filepath.FromSlash("/tmp/clip.final_7.mkv")},
+		{"~/out/{stem}.mp4", filepath.Join(home, "out", "clip.final.mp4")},
+		{"{dir}/{name}", input},
+	}
+	for _, tt := range tests {
+		if got := batchOutputPath(tt.pattern, input, 7); got != tt.want {
+			t.Errorf("batchOutputPath(%q) = %q, want %q", tt.pattern, got, tt.want)
+		}
+	}
+}
+
+func TestExpandBatchSource(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"b.MOV", "a.mov", "c.mp4", "notes.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "sub.mov"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	join := func(names ...string) []string {
+		for i, name := range names {
+			names[i] = filepath.Join(dir, name)
+		}
+		return names
+	}
+	tests := []struct {
+		source    string
+		fileTypes []string
+		want      []string
+	}{
+		{dir, nil, join("a.mov", "b.MOV", "c.mp4", "notes.txt")},
+		{dir, []string{".mov"}, join("a.mov", "b.MOV")},
+		{dir, []string{".*"}, join("a.mov", "b.MOV", "c.mp4", "notes.txt")},
+		{filepath.Join(dir, "*.m*"), []string{".mp4"}, join("c.mp4")},
+		{" " + filepath.Join(dir, "?.mov") + " ", nil, join("a.mov")},
+	}
+	for _, tt := range tests {
+		got, err := expandBatchSource(tt.source, tt.fileTypes)
+		if err != nil {
+			t.Errorf("expandBatchSource(%q, %q) error: %v", tt.source, tt.fileTypes, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("expandBatchSource(%q, %q) = %q, want %q", tt.source, tt.fileTypes, got, tt.want)
+		}
+	}
+
+	failures := []struct {
+		source string
+		want   string
+	}{
+		{"", "请指定批量处理的目录或文件匹配模式"},
+		{filepath.Join(dir, "*.avi"), "没有匹配的文件"},
+		{filepath.Join(dir, "[a"), "无效的文件匹配模式"},
+	}
+	for _, tt := range failures {
+		if _, err := expandBatchSource(tt.source, nil); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("expandBatchSource(%q) error = %v, want it to contain %q", tt.source, err, tt.want)
+		}
+	}
+}
+
+func TestPickVariable(t *testing.T) {
+	command := models.Command{Variables: []models.VariableDefinition{
+		{Name: "in", Type: models.VarTypeFileInput},
+		{Name: "out", Type: models.VarTypeFileOutput},
+		{Name: "log", Type: models.VarTypeFileOutput},
+	}}
+	tests := []struct {
+		name, varType string
+		required      bool
+		want          string
+		err           string
+	}{
+		{"", models.VarTypeFileInput, true, "in", ""},
+		{"log", models.VarTypeFileOutput, false, "log", ""},
+		{"", models.VarTypeFileOutput, false, "", "命令中有多个 file_output 变量"},
+		{"in", models.VarTypeFileOutput, false, "", "变量 'in' 的类型为 file_input, 需要 file_output"},
+		{"x", models.VarTypeFileInput, true, "", "命令中不存在变量: x"},
+		{"", models.VarTypeText, false, "", ""},
+		{"", models.VarTypeText, true, "", "命令中没有 string 变量, 无法批量执行"},
+	}
+	for _, tt := range tests {
+		v, err := pickVariable(command, tt.name, tt.varType, tt.required)
+		got := ""
+		if v != nil {
+			got = v.Name
+		}
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("pickVariable(%q, %s) error = %v, want it to contain %q", tt.name, tt.varType, err, tt.err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("pickVariable(%q, %s) = %q, %v, want %q", tt.name, tt.varType, got, err, tt.want)
+		}
+	}
+}
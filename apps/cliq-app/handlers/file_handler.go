@@ -5,25 +5,61 @@ import (
     "crypto/md5"
     "fmt"
     "os"
-    "os/exec"
     "path/filepath"
     "strings"
+    "sync/atomic"
+    "time"
 
     "github.com/wailsapp/wails/v2/pkg/runtime"
 
+    "cliq/history"
+    "cliq/jobs"
+    "cliq/output"
+    "cliq/presets"
+    "cliq/progress"
+    "cliq/recent"
+    "repo/shared-go-lib/inputs"
     "repo/shared-go-lib/models"
+    "repo/shared-go-lib/render"
 
     "gopkg.in/yaml.v3"
 )
 
 // FileHandler handles file-related operations
 type FileHandler struct {
-	ctx context.Context
+	ctx     context.Context
+	jobs    *jobs.Manager
+	history *history.Store // nil 表示无法确定配置目录, 不记录历史
+	options *optionCache   // options_from 命令的结果
+	presets *presets.Store // 用户保存的预设, nil 表示不可用
+	recent  *recent.Store  // 记录的输入值, nil 表示不可用
+	// remember 为 false 时不记录输入值, 由设置 remember_values 控制
+	remember atomic.Bool
 }
 
 // NewFileHandler creates a new file handler
 func NewFileHandler() *FileHandler {
-	return &FileHandler{}
+	fh := &FileHandler{options: newOptionCache()}
+	fh.jobs = jobs.NewManager(jobs.DefaultConcurrency, fh.emit)
+	if dir, err := history.DefaultDir(); err == nil {
+		fh.history = history.NewStore(dir)
+	}
+	if dir, err := presets.DefaultDir(); err == nil {
+		fh.presets = presets.NewStore(dir)
+	}
+	if dir, err := recent.DefaultDir(); err == nil {
+		fh.recent = recent.NewStore(dir)
+	}
+	fh.remember.Store(true)
+	fh.jobs.OnFinish(fh.recordHistory)
+	return fh
+}
+
+// emit 向前端推送事件, 应用启动前的事件直接丢弃
+func (fh *FileHandler) emit(name string, data interface{}) {
+	if fh.ctx != nil {
+		runtime.EventsEmit(fh.ctx, name, data)
+	}
 }
 
 // startup is called when the app starts. The context is saved
@@ -68,6 +104,32 @@ func (fh *FileHandler) OpenFileDialogWithFilters(filters []runtime.FileFilter) (
 	return runtime.OpenFileDialog(fh.ctx, options)
 }
 
+// OpenMultipleFilesDialog opens a file dialog that allows selecting several files
+func (fh *FileHandler) OpenMultipleFilesDialog(filters []runtime.FileFilter) ([]string, error) {
+	options := runtime.OpenDialogOptions{
+		Title:   "选择输入文件",
+		Filters: filters,
+	}
+	if len(filters) == 0 {
+		options.Filters = []runtime.FileFilter{
+			{
+				DisplayName: "所有文件 (*.*)",
+				Pattern:     "*.*",
+			},
+		}
+	}
+
+	return runtime.OpenMultipleFilesDialog(fh.ctx, options)
+}
+
+// OpenDirectoryDialog opens a dialog for selecting a directory
+func (fh *FileHandler) OpenDirectoryDialog() (string, error) {
+	return runtime.OpenDirectoryDialog(fh.ctx, runtime.OpenDialogOptions{
+		Title:                "选择目录",
+		CanCreateDirectories: true,
+	})
+}
+
 // SaveFileDialog opens a save file dialog and returns the selected file path
 func (fh *FileHandler) SaveFileDialog() (string, error) {
 	options := runtime.SaveDialogOptions{
@@ -148,20 +210,25 @@ func (fh *FileHandler) ExportTemplateToFile(template *models.TemplateFile, fileP
 	return nil
 }
 
-func getCommandParts(cmdTemplateStr string, variables map[string]interface{}) ([]string, error) {
-	// 替换命令模板中的变量
-	commandStr := cmdTemplateStr
-	for name, value := range variables {
-		// TODO: 根据变量类型进行格式化，例如布尔值转换为 --flag 或空
-		commandStr = strings.ReplaceAll(commandStr, fmt.Sprintf("{{%s}}", name), fmt.Sprintf("%v", value))
+// findCommand 根据 commandID 在模板中查找命令
+func findCommand(template *models.TemplateFile, commandID string) (models.Command, bool) {
+	for _, cmd := range template.Cmds {
+		if cmd.ID == commandID {
+			return cmd, true
+		}
 	}
+	return models.Command{}, false
+}
 
-	parts := strings.Fields(commandStr)
-	return parts, nil
+// getCommandParts 根据变量定义将命令模板渲染为 argv, 每个变量值作为一个完整的参数, 不会因空格被拆分
+func getCommandParts(command models.Command, variables map[string]interface{}) ([]string, error) {
+	return render.RenderCommand(command, variables)
 }
 
-// ExecuteCommand executes a shell command with the given input and output file paths
-func (fh *FileHandler) ExecuteCommand(template *models.TemplateFile, commandID string, variables map[string]interface{}) (string, error) {
+// ExecuteCommand 将命令提交到任务队列并立即返回 job ID. 状态变化、输出和退出信息
+// 通过 jobs.EventStatus / jobs.EventOutput / jobs.EventExit 事件推送.
+// 包含高风险操作的命令只有在 confirmed 为 true 时才会执行, 见 AnalyzeCommand
+func (fh *FileHandler) ExecuteCommand(template *models.TemplateFile, commandID string, variables map[string]interface{}, confirmed bool) (string, error) {
 	if template == nil {
 		return "", fmt.Errorf("模板未加载")
 	}
@@ -169,42 +236,239 @@ func (fh *FileHandler) ExecuteCommand(template *models.TemplateFile, commandID s
 		variables = make(map[string]interface{})
 	}
 	// 根据 commandID 查找对应的命令
-	var selectedCommand models.Command
-	found := false
-	for _, cmd := range template.Cmds {
-		if cmd.ID == commandID {
-			selectedCommand = cmd
-			found = true
-			break
-		}
-	}
-
+	selectedCommand, found := findCommand(template, commandID)
 	if !found {
 		return "", fmt.Errorf("未找到命令: %s", commandID)
 	}
 
+	jobID, err := fh.submitCommand(template, selectedCommand, variables, confirmed)
+	if err != nil {
+		return "", err
+	}
+	fh.rememberValues(template, selectedCommand, variables)
+	return jobID, nil
+}
+
+// submitCommand 渲染命令并提交到任务队列, 返回 job ID
+func (fh *FileHandler) submitCommand(template *models.TemplateFile, command models.Command, variables map[string]interface{}, confirmed bool) (string, error) {
+	if err := preflight(template, command); err != nil {
+		return "", err
+	}
+	spec, err := buildSpec(template, command, variables)
+	if err != nil {
+		return "", err
+	}
+	if err := checkRisk(analyzeSpec(command.Command, command, spec), confirmed); err != nil {
+		return "", err
+	}
+	job := fh.jobs.Submit(spec)
+	return job.ID, nil
+}
+
+// buildSpec 渲染命令的 argv、工作目录和环境变量, 生成任务描述
+func buildSpec(template *models.TemplateFile, command models.Command, variables map[string]interface{}) (jobs.Spec, error) {
+	// 用户未填写的推导变量 (computed 或带插值的 default) 按表达式补全
+	variables, err := render.ApplyComputed(command, variables)
+	if err != nil {
+		return jobs.Spec{}, fmt.Errorf("计算推导变量失败: %w", err)
+	}
+	// show_if 为假的变量不参与执行, 也不记录到历史中
+	variables, err = render.VisibleValues(command, variables)
+	if err != nil {
+		return jobs.Spec{}, fmt.Errorf("解析显示条件失败: %w", err)
+	}
+
 	// 替换命令模板中的变量
-	cmdTemplateStr := selectedCommand.Command
-	parts, err := getCommandParts(cmdTemplateStr, variables)
+	parts, err := getCommandParts(command, variables)
 	if err != nil {
-		return "", fmt.Errorf("获取命令文本失败: %w", err)
+		return jobs.Spec{}, fmt.Errorf("获取命令文本失败: %w", err)
 	}
 	if len(parts) == 0 {
-		return "", fmt.Errorf("命令为空")
+		return jobs.Spec{}, fmt.Errorf("命令为空")
+	}
+
+	timeout, err := commandTimeout(command)
+	if err != nil {
+		return jobs.Spec{}, err
+	}
+
+	dir, err := render.Workdir(command, variables)
+	if err != nil {
+		return jobs.Spec{}, fmt.Errorf("解析工作目录失败: %w", err)
+	}
+	if dir != "" {
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			return jobs.Spec{}, fmt.Errorf("工作目录不存在: %s", dir)
+		}
+	}
+	if err := inputs.Validate(command, variables, dir); err != nil {
+		return jobs.Spec{}, err
+	}
+
+	env, err := render.Env(command, variables)
+	if err != nil {
+		return jobs.Spec{}, fmt.Errorf("解析环境变量失败: %w", err)
+	}
+
+	var tracker *progress.Tracker
+	if command.Progress != nil {
+		if tracker, err = progress.NewTracker(*command.Progress); err != nil {
+			return jobs.Spec{}, fmt.Errorf("命令 '%s' 的 progress 无效: %w", command.Name, err)
+		}
 	}
 
-	name := parts[0]
-	args := parts[1:]
+	stdinFile, stdin := commandStdin(command, variables, dir)
+	if stdinFile != "" {
+		if info, err := os.Stat(stdinFile); err != nil || info.IsDir() {
+			return jobs.Spec{}, fmt.Errorf("标准输入文件不存在: %s", stdinFile)
+		}
+	}
+
+	return jobs.Spec{
+		TemplateName:    template.Name,
+		TemplateVersion: template.Version,
+		CommandID:       command.ID,
+		CommandName:     command.Name,
+		Variables:       withoutSecrets(command, variables),
+		Argv:            parts,
+		Dir:             dir,
+		Env:             env,
+		StdinFile:       stdinFile,
+		Stdin:           stdin,
+		Timeout:         timeout,
+		Parse:           outputParser(command),
+		Progress:        tracker,
+		Secrets:         render.Secrets(command, variables),
+	}, nil
+}
+
+// withoutSecrets 返回去掉 secret 变量的参数副本, 用于任务记录和执行历史.
+// 由 secret 推导出的值 (如 computed: "{{token|upper}}") 同样去掉, 重新执行时会再次推导
+func withoutSecrets(command models.Command, variables map[string]interface{}) map[string]interface{} {
+	secrets := render.Secrets(command, variables)
+	out := make(map[string]interface{}, len(variables))
+	for k, v := range variables {
+		if s, ok := v.(string); ok && render.Redact(s, secrets) != s {
+			continue
+		}
+		out[k] = v
+	}
+	for _, def := range command.Variables {
+		if def.Type == models.VarTypeSecret {
+			delete(out, def.Name)
+		}
+	}
+	return out
+}
 
-	cmd := exec.Command(name, args...)
+// outputParser 返回按命令 output 声明解析标准输出的函数, 未声明时返回 nil
+func outputParser(command models.Command) jobs.Parser {
+	if command.Output == nil {
+		return nil
+	}
+	spec := *command.Output
+	return func(stdout string) *output.Result {
+		return output.Parse(spec, stdout)
+	}
+}
 
-	// 执行命令并获取输出
-	out, err := cmd.CombinedOutput()
+// commandStdin 返回命令的标准输入. 相对路径的输入文件相对于工作目录, 与预览中的 "cd DIR && cmd < file" 一致
+func commandStdin(command models.Command, variables map[string]interface{}, dir string) (string, string) {
+	file, text := render.Stdin(command, variables)
+	if file != "" && dir != "" && !filepath.IsAbs(file) {
+		file = filepath.Join(dir, file)
+	}
+	return file, text
+}
+
+// commandTimeout 解析命令的 timeout 字段, 未设置时返回 0
+func commandTimeout(command models.Command) (time.Duration, error) {
+	if command.Timeout == "" {
+		return 0, nil
+	}
+	timeout, err := time.ParseDuration(command.Timeout)
+	if err != nil || timeout <= 0 {
+		return 0, fmt.Errorf("命令 '%s' 的 timeout 无效: %s", command.Name, command.Timeout)
+	}
+	return timeout, nil
+}
+
+// CancelCommand 取消排队中或正在运行的任务. 运行中的命令会先请求进程组退出, 超过宽限期后强制结束
+func (fh *FileHandler) CancelCommand(jobID string) error {
+	return fh.jobs.Cancel(jobID)
+}
+
+// ListJobs 列出任务队列中所有任务 (不含输出), 最新的在前
+func (fh *FileHandler) ListJobs() []jobs.Job {
+	return fh.jobs.List()
+}
+
+// GetJob 获取任务详情, 包含最近的输出
+func (fh *FileHandler) GetJob(jobID string) (jobs.Job, error) {
+	return fh.jobs.Get(jobID)
+}
+
+// SetMaxConcurrentJobs 设置可同时运行的任务数
+func (fh *FileHandler) SetMaxConcurrentJobs(n int) {
+	fh.jobs.SetConcurrency(n)
+}
+
+// ComputeVariables 返回命令中推导变量的值, 供表单预填输出路径等字段. 已有值的变量保持不变
+func (fh *FileHandler) ComputeVariables(command models.Command, variables map[string]interface{}) (map[string]interface{}, error) {
+	computed, err := render.Computed(command, variables)
 	if err != nil {
-		return "", fmt.Errorf("执行命令失败: %w\n%s", err, string(out))
+		return nil, fmt.Errorf("计算推导变量失败: %w", err)
 	}
+	return computed, nil
+}
 
-	return strings.TrimSpace(string(out)), nil
+// FieldStates 为按 show_if / required_if 计算出的表单状态
+type FieldStates struct {
+	Hidden   []string `json:"hidden"`   // 隐藏的变量
+	Required []string `json:"required"` // 当前必填的变量, 包含 required: true 的
+}
+
+// FieldStates 按当前的值计算变量的 show_if / required_if, 供表单隐藏字段和标记必填
+func (fh *FileHandler) FieldStates(command models.Command, variables map[string]interface{}) (FieldStates, error) {
+	variables, err := render.ApplyComputed(command, variables)
+	if err != nil {
+		return FieldStates{}, fmt.Errorf("计算推导变量失败: %w", err)
+	}
+	states, err := render.FieldStates(command.Variables, variables)
+	if err != nil {
+		return FieldStates{}, fmt.Errorf("解析显示条件失败: %w", err)
+	}
+	result := FieldStates{Hidden: []string{}, Required: []string{}}
+	for _, def := range command.Variables {
+		switch st := states[def.Name]; {
+		case st.Hidden:
+			result.Hidden = append(result.Hidden, def.Name)
+		case st.Required:
+			result.Required = append(result.Required, def.Name)
+		}
+	}
+	return result, nil
+}
+
+// ValidateVariables 按变量定义检查提交的值, 返回每个字段的错误信息, 全部有效时为空.
+// 与执行前的检查相同: 必填、数值范围、pattern、下拉选项和输入文件是否存在
+func (fh *FileHandler) ValidateVariables(template *models.TemplateFile, commandID string, variables map[string]interface{}) (inputs.Errors, error) {
+	if template == nil {
+		return nil, fmt.Errorf("模板未加载")
+	}
+	command, found := findCommand(template, commandID)
+	if !found {
+		return nil, fmt.Errorf("未找到命令: %s", commandID)
+	}
+	variables, err := render.ApplyComputed(command, variables)
+	if err != nil {
+		return nil, fmt.Errorf("计算推导变量失败: %w", err)
+	}
+	dir, err := render.Workdir(command, variables)
+	if err != nil {
+		return nil, fmt.Errorf("解析工作目录失败: %w", err)
+	}
+	return inputs.Check(command, variables, dir), nil
 }
 
 func (fh *FileHandler) GetCommandText(template *models.TemplateFile, commandID string, variables map[string]interface{}) (string, error) {
@@ -214,27 +478,44 @@ func (fh *FileHandler) GetCommandText(template *models.TemplateFile, commandID s
 	if variables == nil {
 		variables = make(map[string]interface{})
 	}
-	var selectedCommand models.Command
-	found := false
-	for _, cmd := range template.Cmds {
-		if cmd.ID == commandID {
-			selectedCommand = cmd
-			found = true
-			break
-		}
-	}
-
+	selectedCommand, found := findCommand(template, commandID)
 	if !found {
 		return "", fmt.Errorf("未找到命令: %s", commandID)
 	}
+	variables, err := render.ApplyComputed(selectedCommand, variables)
+	if err != nil {
+		return "", fmt.Errorf("计算推导变量失败: %w", err)
+	}
+	dir, err := render.Workdir(selectedCommand, variables)
+	if err != nil {
+		return "", fmt.Errorf("解析工作目录失败: %w", err)
+	}
+	if err := inputs.Validate(selectedCommand, variables, dir); err != nil {
+		return "", err
+	}
 
 	// 替换命令模板中的变量
-	cmdTemplateStr := selectedCommand.Command
-	parts, err := getCommandParts(cmdTemplateStr, variables)
+	parts, err := getCommandParts(selectedCommand, variables)
 	if err != nil {
 		return "", fmt.Errorf("获取命令文本失败: %w", err)
 	}
-	return strings.Join(parts, " "), nil
+	text := render.Join(parts)
+
+	// 预览中以 shell 写法展示环境变量和工作目录, 便于复制到终端执行
+	env, err := render.Env(selectedCommand, variables)
+	if err != nil {
+		return "", fmt.Errorf("解析环境变量失败: %w", err)
+	}
+	if len(env) > 0 {
+		text = render.EnvPrefix(env) + " " + text
+	}
+	if stdinFile, stdin := render.Stdin(selectedCommand, variables); stdinFile != "" || stdin != "" {
+		text += " " + render.StdinRedirect(stdinFile, stdin)
+	}
+	if dir != "" {
+		text = "cd " + render.Quote(dir) + " && " + text
+	}
+	return render.Redact(text, render.Secrets(selectedCommand, variables)), nil
 }
 
 // getHashForTemplateName 生成基于模板名称的安全哈希值，防止路径遍历和特殊字符问题
@@ -465,6 +746,13 @@ func (fh *FileHandler) DeleteFavTemplate(templateName string) error {
 		return fmt.Errorf("删除收藏模板文件失败: %w", err)
 	}
 
+	// 用户预设随收藏模板一起删除
+	if fh.presets != nil {
+		if err := fh.presets.Remove(templateName); err != nil {
+			fmt.Printf("删除模板 %s 的预设失败: %v\n", templateName, err)
+		}
+	}
+
 	return nil
 }
 
@@ -574,6 +862,13 @@ func (fh *FileHandler) UpdateFavTemplate(oldTemplateName string, newTemplateName
 		if err != nil {
 			return fmt.Errorf("写入新模板文件失败: %w", err)
 		}
+
+		// 用户预设按模板名称保存, 随模板一起改名
+		if fh.presets != nil {
+			if err := fh.presets.Rename(oldTemplateName, newTemplateName); err != nil {
+				fmt.Printf("迁移模板 %s 的预设失败: %v\n", oldTemplateName, err)
+			}
+		}
 	} else {
 		// 名称未变更，直接更新原文件
 		// 序列化更新后的模板为YAML
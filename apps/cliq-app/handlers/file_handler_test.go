@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"reflect"
+	"testing"
+
+	"repo/shared-go-lib/models"
+	"repo/shared-go-lib/render"
+)
+
+// Secrets and the values derived from them stay out of the job snapshot,
+// including the text of filtered placeholders.
+func TestBuildSpecSecrets(t *testing.T) {
+	template := &models.TemplateFile{Name: "t"}
+	command := models.Command{
+		ID:      "c",
+		Command: "echo {{token|upper}} {{user}}",
+		Env:     map[string]string{"AUTH": "{{auth}}"},
+		Variables: []models.VariableDefinition{
+			{Name: "token", Type: models.VarTypeSecret},
+			{Name: "user", Type: models.VarTypeText},
+			{Name: "auth", Type: models.VarTypeText, Computed: "{{user}}:{{token|lower}}"},
+		},
+	}
+	spec, err := buildSpec(template, command, map[string]interface{}{"token": "Abc", "user": "bob"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]interface{}{"user": "bob"}; !reflect.DeepEqual(spec.Variables, want) {
+		t.Errorf("Variables = %v, want %v", spec.Variables, want)
+	}
+	if got := render.Redact(render.Join(spec.Argv), spec.Secrets); got != "echo "+render.Mask+" bob" {
+		t.Errorf("redacted argv = %q", got)
+	}
+	if got := render.Redact(spec.Env["AUTH"], spec.Secrets); got != "bob:"+render.Mask {
+		t.Errorf("redacted env = %q", got)
+	}
+}
@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+
+	"cliq/history"
+	"cliq/jobs"
+	"repo/shared-go-lib/models"
+	"repo/shared-go-lib/render"
+	"repo/shared-go-lib/safety"
+	templ "repo/shared-go-lib/template"
+)
+
+// recordHistory 在任务结束后写入执行历史
+func (fh *FileHandler) recordHistory(job jobs.Job) {
+	if fh.history == nil || job.StartedAt == nil || job.FinishedAt == nil {
+		return
+	}
+
+	lines := make([]string, len(job.Output))
+	for i, line := range job.Output {
+		lines[i] = line.Line
+	}
+	output, truncated := history.TruncateOutput(strings.Join(lines, "\n"))
+
+	entry := history.Entry{
+		ID:              job.ID,
+		TemplateName:    job.TemplateName,
+		TemplateVersion: job.TemplateVersion,
+		CommandID:       job.CommandID,
+		CommandName:     job.CommandName,
+		Variables:       job.Variables,
+		Argv:            job.Argv,
+		Dir:             job.Dir,
+		Env:             job.Env,
+		StdinFile:       job.StdinFile,
+		Stdin:           job.Stdin,
+		Redacted:        job.Redacted,
+		Status:          string(job.Status),
+		StartedAt:       *job.StartedAt,
+		FinishedAt:      *job.FinishedAt,
+		ExitCode:        job.ExitCode,
+		Error:           job.Error,
+		Output:          output,
+		OutputTruncated: truncated,
+	}
+	if err := fh.history.Save(entry); err != nil {
+		fmt.Printf("保存执行历史失败: %v\n", err)
+	}
+}
+
+// historyStore 返回历史记录存储, 不可用时返回错误
+func (fh *FileHandler) historyStore() (*history.Store, error) {
+	if fh.history == nil {
+		return nil, fmt.Errorf("执行历史不可用: 无法确定用户配置目录")
+	}
+	return fh.history, nil
+}
+
+// ListHistory 按条件列出执行历史, 最新的在前
+func (fh *FileHandler) ListHistory(filter history.Filter) ([]history.Entry, error) {
+	store, err := fh.historyStore()
+	if err != nil {
+		return nil, err
+	}
+	return store.List(filter)
+}
+
+// GetHistoryEntry 获取一条执行历史
+func (fh *FileHandler) GetHistoryEntry(id string) (*history.Entry, error) {
+	store, err := fh.historyStore()
+	if err != nil {
+		return nil, err
+	}
+	return store.Get(id)
+}
+
+// DeleteHistoryEntry 删除一条执行历史
+func (fh *FileHandler) DeleteHistoryEntry(id string) error {
+	store, err := fh.historyStore()
+	if err != nil {
+		return err
+	}
+	return store.Delete(id)
+}
+
+// ClearHistory 清空执行历史
+func (fh *FileHandler) ClearHistory() error {
+	store, err := fh.historyStore()
+	if err != nil {
+		return err
+	}
+	return store.Clear()
+}
+
+// resolvedFavTemplate 读取收藏模板并展开 extends / include, 与当前模板一样可以直接执行
+func (fh *FileHandler) resolvedFavTemplate(name string) (*models.TemplateFile, error) {
+	template, err := fh.GetFavTemplate(name)
+	if err != nil {
+		return nil, err
+	}
+	return templ.Resolve(template)
+}
+
+// RerunHistoryEntry 重新运行一条历史记录. variables 为 nil 时使用当时的参数.
+// 优先使用当前加载的模板, 其次从收藏中查找同名模板重新渲染命令;
+// 找不到模板时只能按原参数重新运行记录下来的 argv
+func (fh *FileHandler) RerunHistoryEntry(current *models.TemplateFile, id string, variables map[string]interface{}, confirmed bool) (string, error) {
+	store, err := fh.historyStore()
+	if err != nil {
+		return "", err
+	}
+	entry, err := store.Get(id)
+	if err != nil {
+		return "", err
+	}
+
+	edited := variables != nil
+	if !edited {
+		variables = entry.Variables
+	}
+
+	template := current
+	if template == nil || template.Name != entry.TemplateName {
+		template, _ = fh.resolvedFavTemplate(entry.TemplateName)
+	}
+	if template != nil {
+		if command, ok := findCommand(template, entry.CommandID); ok {
+			return fh.submitCommand(template, command, variables, confirmed)
+		}
+	}
+
+	if edited {
+		return "", fmt.Errorf("找不到模板 '%s' 中的命令 '%s', 无法使用修改后的参数重新运行", entry.TemplateName, entry.CommandName)
+	}
+	if entry.Redacted {
+		return "", fmt.Errorf("该记录中的密钥未被保存, 找不到模板 '%s' 时无法重新运行", entry.TemplateName)
+	}
+	spec := jobs.Spec{
+		TemplateName:    entry.TemplateName,
+		TemplateVersion: entry.TemplateVersion,
+		CommandID:       entry.CommandID,
+		CommandName:     entry.CommandName,
+		Variables:       entry.Variables,
+		Argv:            entry.Argv,
+		Dir:             entry.Dir,
+		Env:             entry.Env,
+		StdinFile:       entry.StdinFile,
+		Stdin:           entry.Stdin,
+	}
+	report := safety.Analyze(safety.Input{Command: render.Join(entry.Argv), Argv: entry.Argv})
+	if err := checkRisk(report, confirmed); err != nil {
+		return "", err
+	}
+	job := fh.jobs.Submit(spec)
+	return job.ID, nil
+}
@@ -0,0 +1,235 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"cliq/executor"
+	"cliq/output"
+	"repo/shared-go-lib/inputs"
+	"repo/shared-go-lib/models"
+	"repo/shared-go-lib/render"
+	"repo/shared-go-lib/safety"
+)
+
+// maxSourceOutput 为选项命令标准输出的上限, 超出时报错而不是截断后解析
+const maxSourceOutput = 4 * 1024 * 1024
+
+// VariableOptions 为 options_from 命令产生的下拉选项
+type VariableOptions struct {
+	Choices []models.Choice `json:"choices"`
+	// Pending 为命令依赖但尚未填写或无效的变量, 此时不运行命令, Choices 为空
+	Pending []string `json:"pending,omitempty"`
+	// Cached 表示选项来自缓存, LoadedAt 为命令运行的时间
+	Cached   bool      `json:"cached"`
+	LoadedAt time.Time `json:"loaded_at"`
+}
+
+// optionCache 缓存选项命令的结果, 键为渲染后的命令、工作目录和环境变量,
+// 依赖的变量值变化后自然对应新的缓存项
+type optionCache struct {
+	mu      sync.Mutex
+	entries map[string]optionEntry
+}
+
+type optionEntry struct {
+	options VariableOptions
+	expires time.Time
+}
+
+func newOptionCache() *optionCache {
+	return &optionCache{entries: map[string]optionEntry{}}
+}
+
+func (c *optionCache) get(key string) (VariableOptions, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok || time.Now().After(e.expires) {
+		delete(c.entries, key)
+		return VariableOptions{}, false
+	}
+	return e.options, true
+}
+
+func (c *optionCache) put(key string, options VariableOptions, ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	for k, e := range c.entries {
+		if now.After(e.expires) {
+			delete(c.entries, k)
+		}
+	}
+	c.entries[key] = optionEntry{options: options, expires: now.Add(ttl)}
+}
+
+// LoadVariableOptions 运行 select / multi_select 变量的 options_from 命令并返回选项.
+// 命令使用其他变量的当前值渲染, 与 ExecuteCommand 一样检查依赖、参数和高风险操作,
+// 但不进入任务队列. 结果按 cache 设置缓存, refresh 为 true 时重新运行
+func (fh *FileHandler) LoadVariableOptions(template *models.TemplateFile, commandID, variable string, variables map[string]interface{}, refresh bool) (VariableOptions, error) {
+	if template == nil {
+		return VariableOptions{}, fmt.Errorf("模板未加载")
+	}
+	command, found := findCommand(template, commandID)
+	if !found {
+		return VariableOptions{}, fmt.Errorf("未找到命令: %s", commandID)
+	}
+	var src *models.OptionSource
+	for _, def := range command.Variables {
+		if def.Name == variable {
+			src = def.OptionSource()
+		}
+	}
+	if src == nil {
+		return VariableOptions{}, fmt.Errorf("变量 '%s' 没有 options_from", variable)
+	}
+	if variables == nil {
+		variables = make(map[string]interface{})
+	}
+
+	variables, err := render.ApplyComputed(command, variables)
+	if err != nil {
+		return VariableOptions{}, fmt.Errorf("计算推导变量失败: %w", err)
+	}
+	tmpl, err := render.Parse(src.Command)
+	if err != nil {
+		return VariableOptions{}, fmt.Errorf("变量 '%s' 的 options_from 无效: %w", variable, err)
+	}
+	dir, err := render.Workdir(command, variables)
+	if err != nil {
+		return VariableOptions{}, fmt.Errorf("解析工作目录失败: %w", err)
+	}
+
+	// 依赖的变量未填写或无效时等待用户输入, 不运行命令
+	errs := inputs.Check(command, variables, dir)
+	var pending []string
+	for _, name := range tmpl.Variables() {
+		if _, bad := errs[name]; bad {
+			pending = append(pending, name)
+		}
+	}
+	if len(pending) > 0 {
+		return VariableOptions{Choices: []models.Choice{}, Pending: pending}, nil
+	}
+
+	if err := preflight(template, command); err != nil {
+		return VariableOptions{}, err
+	}
+	argv, err := tmpl.Render(variables, command.Variables)
+	if err != nil {
+		return VariableOptions{}, fmt.Errorf("渲染选项命令失败: %w", err)
+	}
+	if len(argv) == 0 {
+		return VariableOptions{}, fmt.Errorf("选项命令为空")
+	}
+	if dir != "" {
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			return VariableOptions{}, fmt.Errorf("工作目录不存在: %s", dir)
+		}
+	}
+	env, err := render.Env(command, variables)
+	if err != nil {
+		return VariableOptions{}, fmt.Errorf("解析环境变量失败: %w", err)
+	}
+	// 选项命令在后台自动运行, 无法请求确认, 包含高风险操作时拒绝执行
+	if err := checkRisk(safety.Analyze(safety.Input{Command: src.Command, Argv: argv}), false); err != nil {
+		return VariableOptions{}, fmt.Errorf("选项命令不会自动运行: %w", err)
+	}
+
+	key := sourceKey(template, command, variable, argv, dir, env)
+	if !refresh {
+		if cached, ok := fh.options.get(key); ok {
+			cached.Cached = true
+			return cached, nil
+		}
+	}
+
+	secrets := render.Secrets(command, variables)
+	stdout, err := runSource(*src, executor.Command{Argv: argv, Dir: dir, Env: env})
+	if err != nil {
+		return VariableOptions{}, fmt.Errorf("%s", render.Redact(err.Error(), secrets))
+	}
+	choices, err := output.Choices(*src, stdout)
+	if err != nil {
+		return VariableOptions{}, fmt.Errorf("读取选项失败: %w", err)
+	}
+	if choices == nil {
+		choices = []models.Choice{}
+	}
+	result := VariableOptions{Choices: choices, LoadedAt: time.Now()}
+	if ttl := src.CacheDuration(); ttl > 0 {
+		fh.options.put(key, result, ttl)
+	}
+	return result, nil
+}
+
+// runSource 运行选项命令并返回标准输出. 命令失败时错误中带上最后一行错误输出
+func runSource(src models.OptionSource, c executor.Command) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), src.TimeoutDuration())
+	defer cancel()
+
+	// 标准输出按原始字节解析, 不经过按行拆分
+	stdout := &sourceOutput{cancel: cancel}
+	c.Stdout = stdout
+	var lastErr string
+	res := executor.Run(ctx, executor.NewJobID(), c, func(line executor.OutputLine) {
+		if line.Stream == executor.StreamStderr && strings.TrimSpace(line.Line) != "" {
+			lastErr = line.Line
+		}
+	})
+	switch {
+	case stdout.overflow:
+		return "", fmt.Errorf("选项命令的输出超过 %d MB", maxSourceOutput/1024/1024)
+	case res.Error != "" && res.ExitCode <= 0:
+		return "", fmt.Errorf("选项命令运行失败: %s", res.Error)
+	case res.ExitCode != 0:
+		msg := fmt.Sprintf("选项命令运行失败 (退出码 %d)", res.ExitCode)
+		if lastErr != "" {
+			msg += ": " + lastErr
+		}
+		return "", fmt.Errorf("%s", msg)
+	}
+	return stdout.buf.String(), nil
+}
+
+// sourceOutput 保存选项命令的标准输出, 超过 maxSourceOutput 时取消命令
+type sourceOutput struct {
+	buf      bytes.Buffer
+	cancel   context.CancelFunc
+	overflow bool
+}
+
+func (o *sourceOutput) Write(p []byte) (int, error) {
+	if o.overflow {
+		return len(p), nil
+	}
+	if o.buf.Len()+len(p) > maxSourceOutput {
+		o.overflow = true
+		o.cancel()
+		return len(p), nil
+	}
+	o.buf.Write(p)
+	return len(p), nil
+}
+
+// sourceKey 为选项命令的缓存键
+func sourceKey(template *models.TemplateFile, command models.Command, variable string, argv []string, dir string, env map[string]string) string {
+	names := make([]string, 0, len(env))
+	for name := range env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	parts := []string{template.Name, template.Version, command.ID, variable, dir}
+	for _, name := range names {
+		parts = append(parts, name+"="+env[name])
+	}
+	parts = append(parts, argv...)
+	return strings.Join(parts, "\x00")
+}
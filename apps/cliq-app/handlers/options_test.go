@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+
+	"repo/shared-go-lib/models"
+)
+
+func optionsTemplate(source map[string]interface{}) *models.TemplateFile {
+	return &models.TemplateFile{
+		Name: "t",
+		Cmds: []models.Command{{
+			ID:      "c",
+			Command: "tool {{branch}}",
+			Variables: []models.VariableDefinition{
+				{Name: "prefix", Type: models.VarTypeText, Required: true},
+				{Name: "branch", Type: models.VarTypeSelect, Options: map[string]interface{}{"options_from": source}},
+			},
+		}},
+	}
+}
+
+func TestLoadVariableOptions(t *testing.T) {
+	if _, err := exec.LookPath("printf"); err != nil {
+		t.Skip("printf is not available")
+	}
+	fh := NewFileHandler()
+	template := optionsTemplate(map[string]interface{}{"command": `printf '%s\n' {{prefix}}-a {{prefix}}-b`})
+
+	got, err := fh.LoadVariableOptions(template, "c", "branch", nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Choices) != 0 || !reflect.DeepEqual(got.Pending, []string{"prefix"}) {
+		t.Errorf("options before prefix is set = %+v, want prefix pending", got)
+	}
+
+	values := map[string]interface{}{"prefix": "dev"}
+	got, err = fh.LoadVariableOptions(template, "c", "branch", values, false)
+	want := []models.Choice{{Value: "dev-a"}, {Value: "dev-b"}}
+	if err != nil || !reflect.DeepEqual(got.Choices, want) || got.Cached {
+		t.Fatalf("LoadVariableOptions = %+v, %v, want %v", got, err, want)
+	}
+	if got, _ = fh.LoadVariableOptions(template, "c", "branch", values, false); !got.Cached {
+		t.Errorf("second load was not cached")
+	}
+	if got, _ = fh.LoadVariableOptions(template, "c", "branch", values, true); got.Cached {
+		t.Errorf("refresh returned the cached choices")
+	}
+	// other values of the variables the command uses are cached separately
+	got, _ = fh.LoadVariableOptions(template, "c", "branch", map[string]interface{}{"prefix": "rel"}, false)
+	if got.Cached || len(got.Choices) != 2 || got.Choices[0].Value != "rel-a" {
+		t.Errorf("options for another prefix = %+v", got)
+	}
+
+	uncached := optionsTemplate(map[string]interface{}{"command": "printf x", "cache": "0"})
+	fh.LoadVariableOptions(uncached, "c", "branch", values, false)
+	if got, _ = fh.LoadVariableOptions(uncached, "c", "branch", values, false); got.Cached {
+		t.Errorf("cache: 0 returned cached choices")
+	}
+}
+
+func TestLoadVariableOptionsErrors(t *testing.T) {
+	if _, err := exec.LookPath("cat"); err != nil {
+		t.Skip("cat is not available")
+	}
+	values := map[string]interface{}{"prefix": "x"}
+	tests := []struct {
+		name     string
+		template *models.TemplateFile
+		variable string
+		want     string
+	}{
+		{"no options_from", optionsTemplate(map[string]interface{}{"command": "cat"}), "prefix", "变量 'prefix' 没有 options_from"},
+		{"unknown command", &models.TemplateFile{Name: "t"}, "branch", "未找到命令: c"},
+		{"failing command", optionsTemplate(map[string]interface{}{"command": "cat /nonexistent/{{prefix}}"}), "branch", "选项命令运行失败 (退出码 1): cat: /nonexistent/x"},
+		{"invalid json", optionsTemplate(map[string]interface{}{"command": "cat /dev/null", "format": "json"}), "branch", "读取选项失败"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewFileHandler().LoadVariableOptions(tt.template, "c", tt.variable, values, false)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("LoadVariableOptions error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
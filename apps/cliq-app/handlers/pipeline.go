@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+
+	"cliq/jobs"
+	"repo/shared-go-lib/models"
+	"repo/shared-go-lib/render"
+)
+
+// findPipeline 根据 pipelineID 在模板中查找工作流
+func findPipeline(template *models.TemplateFile, pipelineID string) (models.Pipeline, bool) {
+	for _, p := range template.Pipelines {
+		if p.ID == pipelineID {
+			return p, true
+		}
+	}
+	return models.Pipeline{}, false
+}
+
+// ExecutePipeline 按顺序运行工作流的各个步骤, 返回运行 ID.
+// 每个步骤都是一个普通任务, 输出与历史和单个命令相同; 整体进度通过 jobs.EventPipeline 事件推送.
+// 步骤的参数依赖前面步骤的结果, 因此在步骤开始前检查高风险操作, 未确认时该步骤失败
+func (fh *FileHandler) ExecutePipeline(template *models.TemplateFile, pipelineID string, variables map[string]interface{}, confirmed bool) (string, error) {
+	if template == nil {
+		return "", fmt.Errorf("模板未加载")
+	}
+	pipeline, found := findPipeline(template, pipelineID)
+	if !found {
+		return "", fmt.Errorf("未找到工作流: %s", pipelineID)
+	}
+	if len(pipeline.Steps) == 0 {
+		return "", fmt.Errorf("工作流 '%s' 没有步骤", pipeline.Name)
+	}
+
+	commands := make([]models.Command, 0, len(pipeline.Steps))
+	for _, step := range pipeline.Steps {
+		if command, ok := findCommand(template, step.Command); ok {
+			commands = append(commands, command)
+		}
+	}
+	if err := preflight(template, commands...); err != nil {
+		return "", err
+	}
+
+	steps := make([]jobs.PipelineStep, len(pipeline.Steps))
+	for i, step := range pipeline.Steps {
+		command, ok := findCommand(template, step.Command)
+		if !ok {
+			return "", fmt.Errorf("工作流步骤 '%s' 引用的命令不存在: %s", step.StepID(), step.Command)
+		}
+		if _, err := commandTimeout(command); err != nil {
+			return "", err
+		}
+		name := step.Name
+		if name == "" {
+			name = command.Name
+		}
+
+		step := step
+		steps[i] = jobs.PipelineStep{
+			ID:              step.StepID(),
+			Name:            name,
+			CommandID:       command.ID,
+			ContinueOnError: step.ContinueOnError,
+			Prepare: func(results map[string]jobs.StepResult) (jobs.Spec, string, error) {
+				values, err := stepValues(step, variables, results)
+				if err != nil {
+					return jobs.Spec{}, "", err
+				}
+				spec, err := buildSpec(template, command, values)
+				if err != nil {
+					return jobs.Spec{}, "", err
+				}
+				if err := checkRisk(analyzeSpec(command.Command, command, spec), confirmed); err != nil {
+					return jobs.Spec{}, "", err
+				}
+				return spec, stepOutputFile(command, values), nil
+			},
+		}
+	}
+
+	run := fh.jobs.SubmitPipeline(template.Name, pipeline.ID, pipeline.Name, steps)
+	return run.ID, nil
+}
+
+// CancelPipeline 取消正在运行的步骤, 并跳过剩余步骤
+func (fh *FileHandler) CancelPipeline(runID string) error {
+	return fh.jobs.CancelPipeline(runID)
+}
+
+// ListPipelineRuns 列出所有工作流运行记录, 最新的在前
+func (fh *FileHandler) ListPipelineRuns() []jobs.PipelineRun {
+	return fh.jobs.ListPipelines()
+}
+
+// GetPipelineRun 获取工作流运行的逐步状态
+func (fh *FileHandler) GetPipelineRun(runID string) (jobs.PipelineRun, error) {
+	return fh.jobs.GetPipeline(runID)
+}
+
+// stepValues 计算步骤的变量值: 工作流变量加上步骤 with 中的赋值
+func stepValues(step models.PipelineStep, variables map[string]interface{}, results map[string]jobs.StepResult) (map[string]interface{}, error) {
+	values := make(map[string]interface{}, len(variables)+len(step.With))
+	for k, v := range variables {
+		values[k] = v
+	}
+	for name, expr := range step.With {
+		v, err := resolveStepValue(expr, variables, results)
+		if err != nil {
+			return nil, fmt.Errorf("变量 '%s': %w", name, err)
+		}
+		values[name] = v
+	}
+	return values, nil
+}
+
+// resolveStepValue 替换赋值表达式中的引用. 表达式只有一个工作流变量引用时保留原始值 (如布尔值、列表)
+func resolveStepValue(expr string, variables map[string]interface{}, results map[string]jobs.StepResult) (interface{}, error) {
+	parts, err := render.ParseStepExpr(expr)
+	if err != nil {
+		return nil, fmt.Errorf("无效的步骤引用: %w", err)
+	}
+	if name, ok := soleVariable(parts); ok {
+		return variables[name], nil
+	}
+
+	var sb strings.Builder
+	for _, part := range parts {
+		switch {
+		case part.Var != "":
+			if v, ok := variables[part.Var]; ok && v != nil {
+				sb.WriteString(fmt.Sprint(v))
+			}
+		case part.Ref != nil:
+			result, ok := results[part.Ref.Step]
+			if !ok {
+				return nil, fmt.Errorf("步骤 '%s' 尚未运行", part.Ref.Step)
+			}
+			switch part.Ref.Field {
+			case render.StepOutput:
+				sb.WriteString(result.Output)
+			case render.StepStdout:
+				sb.WriteString(result.Stdout)
+			case render.StepValues:
+				v, ok := result.Values[part.Ref.Value]
+				if !ok {
+					return nil, fmt.Errorf("步骤 '%s' 的输出中没有提取到 '%s'", part.Ref.Step, part.Ref.Value)
+				}
+				sb.WriteString(v)
+			}
+		default:
+			sb.WriteString(part.Text)
+		}
+	}
+	return sb.String(), nil
+}
+
+// soleVariable 返回表达式中唯一的工作流变量引用, 两侧的空白不计
+func soleVariable(parts []render.StepPart) (string, bool) {
+	name := ""
+	for _, part := range parts {
+		switch {
+		case part.Var != "" && name == "":
+			name = part.Var
+		case part.Var == "" && part.Ref == nil && strings.TrimSpace(part.Text) == "":
+		default:
+			return "", false
+		}
+	}
+	return name, name != ""
+}
+
+// stepOutputFile 返回命令第一个 file_output 变量的值, 供后续步骤通过 {{steps.<id>.output}} 引用
+func stepOutputFile(command models.Command, values map[string]interface{}) string {
+	for _, v := range command.Variables {
+		if v.Type != models.VarTypeFileOutput {
+			continue
+		}
+		if value, ok := values[v.Name].(string); ok {
+			return render.ExpandHome(value)
+		}
+	}
+	return ""
+}
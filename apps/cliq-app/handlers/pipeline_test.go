@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"cliq/jobs"
+)
+
+func TestResolveStepValue(t *testing.T) {
+	variables := map[string]interface{}{"name": "clip", "fast": true, "files": []interface{}{"a", "b"}, "none": nil}
+	results := map[string]jobs.StepResult{
+		"enc":   {Output: "/out/clip.mp4", Stdout: "done\n"},
+		"probe": {Values: map[string]string{"duration": "12.5"}},
+	}
+	tests := []struct {
+		expr string
+		want interface{}
+	}{
+		{"{{fast}}", true},
+		{" {{ files }} ", []interface{}{"a", "b"}},
+		{"{{missing}}", nil},
+		{"{{name}}.txt", "clip.txt"},
+		{"{{name}}-{{none}}-{{missing}}", "clip--"},
+		{"{{steps.enc.output}}", "/out/clip.mp4"},
+		{"log: {{steps.enc.stdout}}", "log: done\n"},
+		{"-t {{steps.probe.values.duration}}", "-t 12.5"},
+		{"{{name}} {{name}}", "clip clip"},
+	}
+	for _, tt := range tests {
+		got, err := resolveStepValue(tt.expr, variables, results)
+		if err != nil {
+			t.Errorf("resolveStepValue(%q) error: %v", tt.expr, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("resolveStepValue(%q) = %#v, want %#v", tt.expr, got, tt.want)
+		}
+	}
+
+	failures := []struct {
+		expr string
+		want string
+	}{
+		{"{{steps.later.output}}", "步骤 'later' 尚未运行"},
+		{"{{steps.probe.values.size}}", "步骤 'probe' 的输出中没有提取到 'size'"},
+		{"{{steps.enc.file}}", "无效的步骤引用"},
+	}
+	for _, tt := range failures {
+		if _, err := resolveStepValue(tt.expr, variables, results); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("resolveStepValue(%q) error = %v, want it to contain %q", tt.expr, err, tt.want)
+		}
+	}
+}
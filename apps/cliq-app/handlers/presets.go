@@ -0,0 +1,266 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+
+	"cliq/presets"
+	"repo/shared-go-lib/models"
+)
+
+// Preset 为表单中列出的一个预设
+type Preset struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Values      map[string]interface{} `json:"values"`
+	// User 表示用户保存的预设, 可以重命名和删除; 否则来自模板
+	User bool `json:"user"`
+}
+
+// CommandPresets 为一个命令的全部预设, 模板自带的在前
+type CommandPresets struct {
+	Presets []Preset `json:"presets"`
+	// Default 为打开命令时自动填入的预设, 为空表示没有
+	Default string `json:"default"`
+}
+
+// presetStore 返回预设存储, 不可用时返回错误
+func (fh *FileHandler) presetStore() (*presets.Store, error) {
+	if fh.presets == nil {
+		return nil, fmt.Errorf("预设不可用: 无法确定用户配置目录")
+	}
+	return fh.presets, nil
+}
+
+// ListPresets 列出命令的预设. 用户设置的默认预设优先于模板中 default: true 的预设
+func (fh *FileHandler) ListPresets(template *models.TemplateFile, commandID string) (CommandPresets, error) {
+	command, err := presetCommand(template, commandID)
+	if err != nil {
+		return CommandPresets{}, err
+	}
+	store, err := fh.presetStore()
+	if err != nil {
+		return CommandPresets{}, err
+	}
+	saved, err := store.Get(template.Name, commandID)
+	if err != nil {
+		return CommandPresets{}, err
+	}
+
+	result := CommandPresets{Presets: []Preset{}}
+	for _, p := range command.Presets {
+		result.Presets = append(result.Presets, Preset{Name: p.Name, Description: p.Description, Values: p.Values})
+		if p.Default {
+			result.Default = p.Name
+		}
+	}
+	for _, p := range saved.Presets {
+		result.Presets = append(result.Presets, Preset{Name: p.Name, Description: p.Description, Values: p.Values, User: true})
+	}
+	if saved.Default != nil {
+		result.Default = *saved.Default
+	}
+	// 默认预设已被删除或模板更新后不再存在时忽略
+	if result.Default != "" && findPreset(result.Presets, result.Default) == nil {
+		result.Default = ""
+	}
+	return result, nil
+}
+
+// ApplyPreset 将预设的值填入 variables 并返回新的变量值. 预设之外的变量保持不变,
+// 命令中已不存在的变量和密钥被忽略
+func (fh *FileHandler) ApplyPreset(template *models.TemplateFile, commandID, name string, variables map[string]interface{}) (map[string]interface{}, error) {
+	command, err := presetCommand(template, commandID)
+	if err != nil {
+		return nil, err
+	}
+	list, err := fh.ListPresets(template, commandID)
+	if err != nil {
+		return nil, err
+	}
+	preset := findPreset(list.Presets, name)
+	if preset == nil {
+		return nil, fmt.Errorf("预设不存在: %s", name)
+	}
+	result := make(map[string]interface{}, len(variables)+len(preset.Values))
+	for k, v := range variables {
+		result[k] = v
+	}
+	for k, v := range presetValues(command, preset.Values) {
+		result[k] = v
+	}
+	return result, nil
+}
+
+// SavePreset 保存用户预设, 同名的用户预设被覆盖. 密钥不会保存.
+// preset.Default 为 true 时同时设为默认预设
+func (fh *FileHandler) SavePreset(template *models.TemplateFile, commandID string, preset models.Preset) error {
+	command, err := presetCommand(template, commandID)
+	if err != nil {
+		return err
+	}
+	store, err := fh.presetStore()
+	if err != nil {
+		return err
+	}
+	name := strings.TrimSpace(preset.Name)
+	if name == "" {
+		return fmt.Errorf("预设名称不能为空")
+	}
+	if templatePreset(command, name) {
+		return fmt.Errorf("模板中已有名为 '%s' 的预设", name)
+	}
+	values := presetValues(command, preset.Values)
+	if len(values) == 0 {
+		return fmt.Errorf("预设中没有可保存的值")
+	}
+	saved := models.Preset{Name: name, Description: strings.TrimSpace(preset.Description), Values: values}
+
+	return store.Update(template.Name, commandID, func(c *presets.Command) error {
+		replaced := false
+		for i := range c.Presets {
+			if c.Presets[i].Name == name {
+				c.Presets[i], replaced = saved, true
+			}
+		}
+		if !replaced {
+			c.Presets = append(c.Presets, saved)
+		}
+		if preset.Default {
+			c.Default = &name
+		}
+		return nil
+	})
+}
+
+// RenamePreset 重命名用户预设, 模板自带的预设不能重命名
+func (fh *FileHandler) RenamePreset(template *models.TemplateFile, commandID, oldName, newName string) error {
+	command, err := presetCommand(template, commandID)
+	if err != nil {
+		return err
+	}
+	store, err := fh.presetStore()
+	if err != nil {
+		return err
+	}
+	newName = strings.TrimSpace(newName)
+	if newName == "" {
+		return fmt.Errorf("预设名称不能为空")
+	}
+	if templatePreset(command, newName) {
+		return fmt.Errorf("模板中已有名为 '%s' 的预设", newName)
+	}
+
+	return store.Update(template.Name, commandID, func(c *presets.Command) error {
+		index := -1
+		for i, p := range c.Presets {
+			switch {
+			case p.Name == oldName:
+				index = i
+			case p.Name == newName:
+				return fmt.Errorf("已有名为 '%s' 的预设", newName)
+			}
+		}
+		if index < 0 {
+			return userPresetMissing(command, oldName)
+		}
+		c.Presets[index].Name = newName
+		if c.Default != nil && *c.Default == oldName {
+			c.Default = &newName
+		}
+		return nil
+	})
+}
+
+// DeletePreset 删除用户预设, 模板自带的预设不能删除
+func (fh *FileHandler) DeletePreset(template *models.TemplateFile, commandID, name string) error {
+	command, err := presetCommand(template, commandID)
+	if err != nil {
+		return err
+	}
+	store, err := fh.presetStore()
+	if err != nil {
+		return err
+	}
+
+	return store.Update(template.Name, commandID, func(c *presets.Command) error {
+		for i, p := range c.Presets {
+			if p.Name == name {
+				c.Presets = append(c.Presets[:i], c.Presets[i+1:]...)
+				if c.Default != nil && *c.Default == name {
+					c.Default = nil
+				}
+				return nil
+			}
+		}
+		return userPresetMissing(command, name)
+	})
+}
+
+// SetDefaultPreset 设置打开命令时自动填入的预设, 可以是模板自带的或用户的预设.
+// name 为空表示不自动填入, 包括模板中 default: true 的预设
+func (fh *FileHandler) SetDefaultPreset(template *models.TemplateFile, commandID, name string) error {
+	list, err := fh.ListPresets(template, commandID)
+	if err != nil {
+		return err
+	}
+	if name != "" && findPreset(list.Presets, name) == nil {
+		return fmt.Errorf("预设不存在: %s", name)
+	}
+	store, err := fh.presetStore()
+	if err != nil {
+		return err
+	}
+	return store.Update(template.Name, commandID, func(c *presets.Command) error {
+		c.Default = &name
+		return nil
+	})
+}
+
+// presetCommand 查找预设所属的命令
+func presetCommand(template *models.TemplateFile, commandID string) (models.Command, error) {
+	if template == nil {
+		return models.Command{}, fmt.Errorf("模板未加载")
+	}
+	command, found := findCommand(template, commandID)
+	if !found {
+		return models.Command{}, fmt.Errorf("未找到命令: %s", commandID)
+	}
+	return command, nil
+}
+
+// presetValues 只保留命令中存在且不是密钥的变量
+func presetValues(command models.Command, values map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(values))
+	for _, def := range command.Variables {
+		if v, ok := values[def.Name]; ok && def.Type != models.VarTypeSecret {
+			result[def.Name] = v
+		}
+	}
+	return result
+}
+
+func findPreset(list []Preset, name string) *Preset {
+	for i := range list {
+		if list[i].Name == name {
+			return &list[i]
+		}
+	}
+	return nil
+}
+
+func templatePreset(command models.Command, name string) bool {
+	for _, p := range command.Presets {
+		if p.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+func userPresetMissing(command models.Command, name string) error {
+	if templatePreset(command, name) {
+		return fmt.Errorf("模板自带的预设 '%s' 不能修改", name)
+	}
+	return fmt.Errorf("预设不存在: %s", name)
+}
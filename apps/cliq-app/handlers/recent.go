@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+
+	"cliq/recent"
+	"repo/shared-go-lib/models"
+	"repo/shared-go-lib/render"
+)
+
+// rememberedTypes 为记录输入值的变量类型. 密钥从不记录, 布尔值、选项和列表不需要提示
+var rememberedTypes = map[string]bool{
+	models.VarTypeText:       true,
+	models.VarTypeTextarea:   true,
+	models.VarTypeNumber:     true,
+	models.VarTypeFileInput:  true,
+	models.VarTypeFileOutput: true,
+	models.VarTypeDirectory:  true,
+}
+
+// SetRememberValues 开启或关闭输入值记录. 关闭后不再记录, 也不再返回提示, 已有记录保留
+func (fh *FileHandler) SetRememberValues(enabled bool) {
+	fh.remember.Store(enabled)
+}
+
+// rememberValues 在命令提交后记录用户输入的值, 隐藏的变量和密钥不记录
+func (fh *FileHandler) rememberValues(template *models.TemplateFile, command models.Command, variables map[string]interface{}) {
+	if fh.recent == nil || !fh.remember.Load() {
+		return
+	}
+	visible, err := render.VisibleValues(command, variables)
+	if err != nil {
+		return
+	}
+	values := map[string]string{}
+	for _, def := range command.Variables {
+		if !rememberedTypes[def.Type] {
+			continue
+		}
+		switch v := visible[def.Name].(type) {
+		case string:
+			values[def.Name] = strings.TrimSpace(v)
+		case float64, int, int64:
+			values[def.Name] = fmt.Sprint(v)
+		}
+	}
+	if err := fh.recent.Record(template.Name, command.ID, values); err != nil {
+		fmt.Printf("保存输入记录失败: %v\n", err)
+	}
+}
+
+// SuggestValues 返回变量以前输入过的值, 按使用次数和最近使用时间排序.
+// query 不为空时只返回包含它的值 (不区分大小写), limit <= 0 表示不限制
+func (fh *FileHandler) SuggestValues(template *models.TemplateFile, commandID, variable, query string, limit int) ([]recent.Entry, error) {
+	if template == nil {
+		return nil, fmt.Errorf("模板未加载")
+	}
+	command, found := findCommand(template, commandID)
+	if !found {
+		return nil, fmt.Errorf("未找到命令: %s", commandID)
+	}
+	if fh.recent == nil || !fh.remember.Load() {
+		return []recent.Entry{}, nil
+	}
+	for _, def := range command.Variables {
+		if def.Name == variable && rememberedTypes[def.Type] {
+			return fh.recent.Suggest(template.Name, commandID, variable, query, limit)
+		}
+	}
+	return []recent.Entry{}, nil
+}
+
+// ClearValueHistory 清除所有记录的输入值
+func (fh *FileHandler) ClearValueHistory() error {
+	if fh.recent == nil {
+		return fmt.Errorf("输入记录不可用: 无法确定用户配置目录")
+	}
+	return fh.recent.Clear()
+}
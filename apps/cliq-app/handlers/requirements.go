@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"cliq/deps"
+	"cliq/jobs"
+	"repo/shared-go-lib/models"
+)
+
+// EventDepsChecked 在安装任务结束、重新检查依赖后推送, 携带 DepsCheckedEvent
+const EventDepsChecked = "deps:checked"
+
+// DepsCheckedEvent 为安装后重新检查依赖的结果
+type DepsCheckedEvent struct {
+	JobID  string      `json:"job_id"`
+	Name   string      `json:"name"`
+	Report deps.Report `json:"report"`
+}
+
+// CheckTemplateRequirements 检查模板及其所有命令声明的依赖, 在导入模板时调用
+func (fh *FileHandler) CheckTemplateRequirements(template *models.TemplateFile) (deps.Report, error) {
+	if template == nil {
+		return deps.Report{}, fmt.Errorf("模板不能为空")
+	}
+	return deps.Check(deps.ForTemplate(template)), nil
+}
+
+// CheckCommandRequirements 检查执行命令所需的依赖, 报告缺少或版本过低的工具
+func (fh *FileHandler) CheckCommandRequirements(template *models.TemplateFile, commandID string) (deps.Report, error) {
+	if template == nil {
+		return deps.Report{}, fmt.Errorf("模板未加载")
+	}
+	command, found := findCommand(template, commandID)
+	if !found {
+		return deps.Report{}, fmt.Errorf("未找到命令: %s", commandID)
+	}
+	return deps.Check(deps.ForCommand(template, command)), nil
+}
+
+// GetInstallPlan 返回当前平台安装依赖将要执行的操作, 供用户确认
+func (fh *FileHandler) GetInstallPlan(template *models.TemplateFile, name string) (deps.Plan, error) {
+	req, err := findRequirement(template, name)
+	if err != nil {
+		return deps.Plan{}, err
+	}
+	return deps.PlanFor(req)
+}
+
+// InstallRequirement 运行依赖的安装程序, 返回 job ID. 安装会修改系统, 与高风险命令一样
+// 只在 confirmed 为 true (用户已确认 GetInstallPlan 展示的操作) 时执行.
+// 安装输出与普通命令一样通过任务事件推送, 结束后重新检查模板依赖并推送 EventDepsChecked
+func (fh *FileHandler) InstallRequirement(template *models.TemplateFile, name string, confirmed bool) (string, error) {
+	req, err := findRequirement(template, name)
+	if err != nil {
+		return "", err
+	}
+	plan, err := deps.PlanFor(req)
+	if err != nil {
+		return "", err
+	}
+	if !confirmed {
+		return "", fmt.Errorf("安装 %s 需要确认后才能执行: %s", name, plan.CommandLine)
+	}
+
+	spec := jobs.Spec{
+		TemplateName:    template.Name,
+		TemplateVersion: template.Version,
+		CommandName:     "安装 " + name,
+		Argv:            plan.Argv,
+	}
+	if plan.Method == models.InstallDownload {
+		spec.Task = func(ctx context.Context, out func(string)) error {
+			return deps.Download(ctx, plan, out)
+		}
+	}
+	job := fh.jobs.Submit(spec)
+
+	go func() {
+		if _, err := fh.jobs.Wait(job.ID); err != nil {
+			return
+		}
+		fh.emit(EventDepsChecked, DepsCheckedEvent{
+			JobID:  job.ID,
+			Name:   name,
+			Report: deps.Check(deps.ForTemplate(template)),
+		})
+	}()
+	return job.ID, nil
+}
+
+// findRequirement 在模板及其命令中查找指定名称的依赖
+func findRequirement(template *models.TemplateFile, name string) (models.Requirement, error) {
+	if template == nil {
+		return models.Requirement{}, fmt.Errorf("模板未加载")
+	}
+	for _, r := range deps.ForTemplate(template) {
+		if r.Name == name {
+			return r, nil
+		}
+	}
+	return models.Requirement{}, fmt.Errorf("模板中没有声明依赖: %s", name)
+}
+
+// preflight 在执行前检查依赖, 缺少工具时返回说明缺少什么的错误, 而不是让命令以启动失败结束
+func preflight(template *models.TemplateFile, commands ...models.Command) error {
+	var reqs []models.Requirement
+	for _, c := range commands {
+		reqs = append(reqs, deps.ForCommand(template, c)...)
+	}
+	if len(reqs) == 0 {
+		return nil
+	}
+	if report := deps.Check(reqs); !report.OK {
+		return errors.New(report.Message)
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"strings"
+	"testing"
+
+	"repo/shared-go-lib/models"
+)
+
+// Installing changes the system, so the backend refuses to install until
+// the frontend says the user confirmed the plan.
+func TestInstallRequirementNeedsConfirmation(t *testing.T) {
+	template := &models.TemplateFile{
+		Name: "t",
+		Requires: []models.Requirement{{Name: "jq", Installers: []models.Installer{
+			{Method: models.InstallDownload, URL: "https://x/jq", SHA256: strings.Repeat("a", 64)},
+		}}},
+	}
+	fh := NewFileHandler()
+	_, err := fh.InstallRequirement(template, "jq", false)
+	if err == nil || !strings.Contains(err.Error(), "安装 jq 需要确认后才能执行: 下载 https://x/jq") {
+		t.Errorf("InstallRequirement error = %v, want a confirmation error", err)
+	}
+	if jobs := fh.jobs.List(); len(jobs) != 0 {
+		t.Errorf("unconfirmed install submitted %d jobs", len(jobs))
+	}
+
+	if _, err := fh.InstallRequirement(template, "ffmpeg", true); err == nil || !strings.Contains(err.Error(), "模板中没有声明依赖: ffmpeg") {
+		t.Errorf("InstallRequirement error = %v, want an unknown requirement error", err)
+	}
+}
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+
+	"cliq/jobs"
+	"repo/shared-go-lib/models"
+	"repo/shared-go-lib/safety"
+)
+
+// AnalyzeCommand 渲染命令并检查其中的高风险操作, 供前端在执行前展示并请求确认
+func (fh *FileHandler) AnalyzeCommand(template *models.TemplateFile, commandID string, variables map[string]interface{}) (safety.Report, error) {
+	if template == nil {
+		return safety.Report{}, fmt.Errorf("模板未加载")
+	}
+	if variables == nil {
+		variables = make(map[string]interface{})
+	}
+	command, found := findCommand(template, commandID)
+	if !found {
+		return safety.Report{}, fmt.Errorf("未找到命令: %s", commandID)
+	}
+	spec, err := buildSpec(template, command, variables)
+	if err != nil {
+		return safety.Report{}, err
+	}
+	return analyzeSpec(command.Command, command, spec), nil
+}
+
+// analyzeSpec 检查即将执行的任务. file_output 变量的值视为命令会写入的路径
+func analyzeSpec(raw string, command models.Command, spec jobs.Spec) safety.Report {
+	var outputs []string
+	for _, v := range command.Variables {
+		if v.Type != models.VarTypeFileOutput {
+			continue
+		}
+		if value, ok := spec.Variables[v.Name].(string); ok {
+			outputs = append(outputs, value)
+		}
+	}
+	return safety.Analyze(safety.Input{Command: raw, Argv: spec.Argv, Outputs: outputs})
+}
+
+// checkRisk 在用户未确认时拒绝执行包含高风险操作的命令
+func checkRisk(report safety.Report, confirmed bool) error {
+	if confirmed || !report.RequiresConfirmation {
+		return nil
+	}
+	parts := make([]string, len(report.Findings))
+	for i, f := range report.Findings {
+		parts[i] = f.Message
+		if f.Arg != "" {
+			parts[i] += fmt.Sprintf(" (%s)", f.Arg)
+		}
+	}
+	return fmt.Errorf("命令包含高风险操作, 需要确认后才能执行: %s", strings.Join(parts, "; "))
+}
@@ -0,0 +1,294 @@
+// Package history persists finished command executions under the cliq
+// config directory, one JSON file per execution.
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// MaxOutputBytes is the amount of output kept per entry; older output is
+	// dropped from the front.
+	MaxOutputBytes = 32 * 1024
+	// maxEntries is the number of entries kept; the oldest are removed.
+	maxEntries = 500
+)
+
+// Entry is one recorded execution.
+type Entry struct {
+	ID              string                 `json:"id"`
+	TemplateName    string                 `json:"template_name"`
+	TemplateVersion string                 `json:"template_version"`
+	CommandID       string                 `json:"command_id"`
+	CommandName     string                 `json:"command_name"`
+	Variables       map[string]interface{} `json:"variables"`
+	Argv            []string               `json:"argv"`
+	Dir             string                 `json:"workdir,omitempty"`
+	Env             map[string]string      `json:"env,omitempty"`
+	StdinFile       string                 `json:"stdin_file,omitempty"`
+	Stdin           string                 `json:"stdin,omitempty"`
+	Redacted        bool                   `json:"redacted,omitempty"` // 密钥已在 Argv/Env/Stdin/Output 中遮盖, 且不在 Variables 中
+	Status          string                 `json:"status"`
+	StartedAt       time.Time              `json:"started_at"`
+	FinishedAt      time.Time              `json:"finished_at"`
+	ExitCode        int                    `json:"exit_code"`
+	Error           string                 `json:"error,omitempty"`
+	Output          string                 `json:"output"`
+	OutputTruncated bool                   `json:"output_truncated"`
+}
+
+// Filter narrows down List results. Empty fields match everything.
+type Filter struct {
+	TemplateName string `json:"template_name"`
+	CommandID    string `json:"command_id"`
+	Status       string `json:"status"`
+	// Query matches case-insensitively against the command name and argv.
+	Query string `json:"query"`
+	// Limit caps the number of returned entries; 0 means no limit.
+	Limit int `json:"limit"`
+}
+
+// Store reads and writes history entries in a directory.
+type Store struct {
+	mu  sync.Mutex
+	dir string
+	// index maps entry IDs to their start time. It is loaded from the
+	// directory on first use and kept up to date afterwards, so pruning
+	// old entries does not have to read every file again.
+	index map[string]time.Time
+}
+
+// DefaultDir returns the history directory next to fav_templates.
+func DefaultDir() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("获取用户配置目录失败: %w", err)
+	}
+	return filepath.Join(configDir, "cliq", "history"), nil
+}
+
+// NewStore creates a store that keeps its files in dir.
+func NewStore(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+// TruncateOutput keeps the last MaxOutputBytes of output.
+func TruncateOutput(output string) (string, bool) {
+	if len(output) <= MaxOutputBytes {
+		return output, false
+	}
+	cut := output[len(output)-MaxOutputBytes:]
+	if i := strings.IndexByte(cut, '\n'); i >= 0 && i < len(cut)-1 {
+		cut = cut[i+1:]
+	}
+	return cut, true
+}
+
+// Save writes an entry and removes the oldest entries beyond the limit.
+func (s *Store) Save(e Entry) error {
+	if e.ID == "" {
+		return fmt.Errorf("历史记录 ID 不能为空")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return fmt.Errorf("创建历史记录目录失败: %w", err)
+	}
+	data, err := json.MarshalIndent(e, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化历史记录失败: %w", err)
+	}
+	if err := os.WriteFile(s.path(e.ID), data, 0644); err != nil {
+		return fmt.Errorf("写入历史记录失败: %w", err)
+	}
+
+	if err := s.loadIndex(); err != nil {
+		return err
+	}
+	s.index[e.ID] = e.StartedAt
+	s.prune()
+	return nil
+}
+
+// List returns the entries matching f, newest first.
+func (s *Store) List(f Filter) ([]Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := s.readAll()
+	if err != nil {
+		return nil, err
+	}
+	s.setIndex(entries)
+	query := strings.ToLower(strings.TrimSpace(f.Query))
+	out := make([]Entry, 0, len(entries))
+	for _, e := range entries {
+		if f.TemplateName != "" && e.TemplateName != f.TemplateName {
+			continue
+		}
+		if f.CommandID != "" && e.CommandID != f.CommandID {
+			continue
+		}
+		if f.Status != "" && e.Status != f.Status {
+			continue
+		}
+		if query != "" && !strings.Contains(strings.ToLower(e.CommandName+" "+strings.Join(e.Argv, " ")), query) {
+			continue
+		}
+		out = append(out, e)
+		if f.Limit > 0 && len(out) >= f.Limit {
+			break
+		}
+	}
+	return out, nil
+}
+
+// Get returns a single entry.
+func (s *Store) Get(id string) (*Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.read(id)
+}
+
+// Delete removes a single entry.
+func (s *Store) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !validID(id) {
+		return fmt.Errorf("无效的历史记录 ID: %s", id)
+	}
+	if err := os.Remove(s.path(id)); err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("历史记录不存在: %s", id)
+		}
+		return fmt.Errorf("删除历史记录失败: %w", err)
+	}
+	delete(s.index, id)
+	return nil
+}
+
+// Clear removes all entries.
+func (s *Store) Clear() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries, err := s.readAll()
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if err := os.Remove(s.path(e.ID)); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("删除历史记录失败: %w", err)
+		}
+	}
+	s.index = map[string]time.Time{}
+	return nil
+}
+
+// loadIndex reads the directory once to build the index.
+func (s *Store) loadIndex() error {
+	if s.index != nil {
+		return nil
+	}
+	entries, err := s.readAll()
+	if err != nil {
+		return err
+	}
+	s.setIndex(entries)
+	return nil
+}
+
+func (s *Store) setIndex(entries []Entry) {
+	s.index = make(map[string]time.Time, len(entries))
+	for _, e := range entries {
+		s.index[e.ID] = e.StartedAt
+	}
+}
+
+// prune removes the oldest entries beyond maxEntries.
+func (s *Store) prune() {
+	if len(s.index) <= maxEntries {
+		return
+	}
+	ids := make([]string, 0, len(s.index))
+	for id := range s.index {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(a, b int) bool { return s.index[ids[a]].After(s.index[ids[b]]) })
+	for _, id := range ids[maxEntries:] {
+		if err := os.Remove(s.path(id)); err != nil && !os.IsNotExist(err) {
+			continue // 下次写入时再试
+		}
+		delete(s.index, id)
+	}
+}
+
+func (s *Store) path(id string) string {
+	return filepath.Join(s.dir, id+".json")
+}
+
+func (s *Store) read(id string) (*Entry, error) {
+	if !validID(id) {
+		return nil, fmt.Errorf("无效的历史记录 ID: %s", id)
+	}
+	data, err := os.ReadFile(s.path(id))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("历史记录不存在: %s", id)
+		}
+		return nil, fmt.Errorf("读取历史记录失败: %w", err)
+	}
+	var e Entry
+	if err := json.Unmarshal(data, &e); err != nil {
+		return nil, fmt.Errorf("解析历史记录失败 (%s): %w", id, err)
+	}
+	return &e, nil
+}
+
+// readAll loads every readable entry, newest first. Unreadable files are
+// skipped so one corrupt file does not hide the rest of the history.
+func (s *Store) readAll() ([]Entry, error) {
+	files, err := os.ReadDir(s.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("读取历史记录目录失败: %w", err)
+	}
+	var entries []Entry
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), ".json") {
+			continue
+		}
+		e, err := s.read(strings.TrimSuffix(file.Name(), ".json"))
+		if err != nil {
+			fmt.Printf("跳过历史记录 %s: %v\n", file.Name(), err)
+			continue
+		}
+		entries = append(entries, *e)
+	}
+	sort.Slice(entries, func(a, b int) bool { return entries[a].StartedAt.After(entries[b].StartedAt) })
+	return entries, nil
+}
+
+// validID guards against path traversal through IDs coming from the UI.
+func validID(id string) bool {
+	if id == "" {
+		return false
+	}
+	for _, c := range id {
+		if !((c >= '0' && c <= '9') || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c == '-' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
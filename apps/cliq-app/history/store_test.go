@@ -0,0 +1,183 @@
+package history
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var base = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+func entry(id, command, status string, minute int) Entry {
+	return Entry{
+		ID:           id,
+		TemplateName: "ffmpeg",
+		CommandID:    command,
+		CommandName:  "Run " + command,
+		Variables:    map[string]interface{}{"crf": 23.0, "in": "a.mov"},
+		Argv:         []string{"ffmpeg", "-i", "a.mov", command},
+		Status:       status,
+		StartedAt:    base.Add(time.Duration(minute) * time.Minute),
+		FinishedAt:   base.Add(time.Duration(minute)*time.Minute + time.Second),
+		Output:       "done\n",
+	}
+}
+
+func ids(entries []Entry) []string {
+	var out []string
+	for _, e := range entries {
+		out = append(out, e.ID)
+	}
+	return out
+}
+
+func TestStoreSaveAndList(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "history")
+	s := NewStore(dir)
+	if got, err := s.List(Filter{}); err != nil || len(got) != 0 {
+		t.Fatalf("List of a missing directory = %v, %v", got, err)
+	}
+	for _, e := range []Entry{
+		entry("a", "encode", "succeeded", 1),
+		entry("b", "probe", "failed", 3),
+		entry("c", "encode", "failed", 2),
+	} {
+		if err := s.Save(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// a new store reads what an earlier one wrote
+	s = NewStore(dir)
+	got, err := s.Get("a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := entry("a", "encode", "succeeded", 1); !reflect.DeepEqual(*got, want) {
+		t.Errorf("Get = %+v, want %+v", *got, want)
+	}
+
+	tests := []struct {
+		name string
+		f    Filter
+		want []string
+	}{
+		{"all, newest first", Filter{}, []string{"b", "c", "a"}},
+		{"command", Filter{CommandID: "encode"}, []string{"c", "a"}},
+		{"status", Filter{Status: "failed"}, []string{"b", "c"}},
+		{"template", Filter{TemplateName: "other"}, nil},
+		{"query matches the name", Filter{Query: "run PROBE"}, []string{"b"}},
+		{"query matches argv", Filter{Query: "a.mov encode"}, []string{"c", "a"}},
+		{"limit", Filter{Limit: 2}, []string{"b", "c"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := s.List(tt.f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(ids(got), tt.want) {
+				t.Errorf("List(%+v) = %q, want %q", tt.f, ids(got), tt.want)
+			}
+		})
+	}
+}
+
+func TestStoreSkipsCorruptFiles(t *testing.T) {
+	dir := t.TempDir()
+	s := NewStore(dir)
+	if err := s.Save(entry("a", "encode", "succeeded", 1)); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "broken.json"), []byte("{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := s.List(Filter{})
+	if err != nil || !reflect.DeepEqual(ids(got), []string{"a"}) {
+		t.Errorf("List = %q, %v", ids(got), err)
+	}
+	if _, err := s.Get("broken"); err == nil || !strings.Contains(err.Error(), "解析历史记录失败") {
+		t.Errorf("Get of a corrupt entry error = %v", err)
+	}
+}
+
+func TestStorePrune(t *testing.T) {
+	dir := t.TempDir()
+	s := NewStore(dir)
+	// saved out of order: the entries that started first are dropped
+	for i := maxEntries + 4; i >= 0; i-- {
+		if err := s.Save(entry(fmt.Sprintf("e%03d", i), "encode", "succeeded", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != maxEntries {
+		t.Errorf("%d files kept, want %d", len(files), maxEntries)
+	}
+	for _, id := range []string{"e000", "e004"} {
+		if _, err := s.Get(id); err == nil {
+			t.Errorf("old entry %s was kept", id)
+		}
+	}
+	if _, err := s.Get("e005"); err != nil {
+		t.Errorf("entry e005 was pruned: %v", err)
+	}
+}
+
+func TestStoreDeleteAndClear(t *testing.T) {
+	s := NewStore(t.TempDir())
+	for i, id := range []string{"a", "b", "c"} {
+		if err := s.Save(entry(id, "encode", "succeeded", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Delete("b"); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := s.List(Filter{}); !reflect.DeepEqual(ids(got), []string{"c", "a"}) {
+		t.Errorf("List after Delete = %q", ids(got))
+	}
+	if err := s.Delete("b"); err == nil || !strings.Contains(err.Error(), "历史记录不存在") {
+		t.Errorf("second Delete error = %v", err)
+	}
+	if err := s.Clear(); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := s.List(Filter{}); len(got) != 0 {
+		t.Errorf("List after Clear = %q", ids(got))
+	}
+}
+
+func TestStoreRejectsBadIDs(t *testing.T) {
+	s := NewStore(t.TempDir())
+	if err := s.Save(Entry{}); err == nil {
+		t.Error("Save accepted an entry without ID")
+	}
+	for _, id := range []string{"", "../x", `a\b`, "a.json"} {
+		if _, err := s.Get(id); err == nil || !strings.Contains(err.Error(), "无效的历史记录 ID") {
+			t.Errorf("Get(%q) error = %v", id, err)
+		}
+		if err := s.Delete(id); err == nil || !strings.Contains(err.Error(), "无效的历史记录 ID") {
+			t.Errorf("Delete(%q) error = %v", id, err)
+		}
+	}
+}
+
+func TestTruncateOutput(t *testing.T) {
+	short := "a\nb\n"
+	if got, cut := TruncateOutput(short); got != short || cut {
+		t.Errorf("TruncateOutput(short) = %q, %v", got, cut)
+	}
+	long := "first line\n" + strings.Repeat("x", MaxOutputBytes-5) + "\nlast\n"
+	got, cut := TruncateOutput(long)
+	if !cut || got != "last\n" {
+		t.Errorf("TruncateOutput(long) = %q, %v, want it to start at a line", got[:min(len(got), 20)], cut)
+	}
+}
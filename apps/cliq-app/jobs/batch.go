@@ -0,0 +1,281 @@
+package jobs
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"cliq/executor"
+)
+
+// EventBatch carries a Batch snapshot whenever one of its items changes.
+const EventBatch = "batch:status"
+
+// maxFinishedBatches is the number of finished batches kept in memory.
+const maxFinishedBatches = 50
+
+// BatchEntry is one unit of work in a batch.
+type BatchEntry struct {
+	// Input is the file the entry was created for.
+	Input string
+	// Output is the derived output file, if the command has one.
+	Output string
+	Spec   Spec
+}
+
+// BatchItem reports the state of one entry.
+type BatchItem struct {
+	Input  string `json:"input"`
+	Output string `json:"output,omitempty"`
+	JobID  string `json:"job_id,omitempty"`
+	Status Status `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Batch is a snapshot of a group of jobs running the same command over
+// many inputs. Status is succeeded only when every item succeeded.
+type Batch struct {
+	ID           string      `json:"id"`
+	TemplateName string      `json:"template_name"`
+	CommandID    string      `json:"command_id"`
+	CommandName  string      `json:"command_name"`
+	Parallelism  int         `json:"parallelism"`
+	Status       Status      `json:"status"`
+	Items        []BatchItem `json:"items"`
+	Total        int         `json:"total"`
+	Succeeded    int         `json:"succeeded"`
+	Failed       int         `json:"failed"`
+	Canceled     int         `json:"canceled"`
+	CreatedAt    time.Time   `json:"created_at"`
+	FinishedAt   *time.Time  `json:"finished_at,omitempty"`
+}
+
+type batch struct {
+	Batch
+	entries []BatchEntry
+	// canceled is set by CancelBatch under m.mu; no item is submitted
+	// after it is set.
+	canceled bool
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// SubmitBatch starts running entries with at most parallelism of them
+// submitted to the job queue at a time. The global concurrency limit still
+// applies on top of that.
+func (m *Manager) SubmitBatch(templateName, commandID, commandName string, entries []BatchEntry, parallelism int) Batch {
+	if parallelism < 1 {
+		parallelism = DefaultConcurrency
+	}
+	b := &batch{
+		Batch: Batch{
+			ID:           executor.NewJobID(),
+			TemplateName: templateName,
+			CommandID:    commandID,
+			CommandName:  commandName,
+			Parallelism:  parallelism,
+			Status:       StatusRunning,
+			Items:        make([]BatchItem, len(entries)),
+			Total:        len(entries),
+			CreatedAt:    time.Now(),
+		},
+		entries: entries,
+		stop:    make(chan struct{}),
+	}
+	for i, e := range entries {
+		b.Items[i] = BatchItem{Input: e.Input, Output: e.Output, Status: StatusQueued}
+	}
+
+	m.mu.Lock()
+	if m.batches == nil {
+		m.batches = make(map[string]*batch)
+	}
+	m.batches[b.ID] = b
+	snapshot := b.snapshot()
+	m.mu.Unlock()
+
+	m.emit(EventBatch, snapshot)
+	go m.runBatch(b)
+	return snapshot
+}
+
+// CancelBatch stops submitting further items and cancels the running ones.
+func (m *Manager) CancelBatch(id string) error {
+	m.mu.Lock()
+	b, ok := m.batches[id]
+	if !ok {
+		m.mu.Unlock()
+		return fmt.Errorf("批量任务不存在: %s", id)
+	}
+	if b.FinishedAt != nil {
+		m.mu.Unlock()
+		return fmt.Errorf("批量任务已结束: %s", id)
+	}
+	b.canceled = true
+	var active []string
+	for _, item := range b.Items {
+		if item.JobID != "" && (item.Status == StatusQueued || item.Status == StatusRunning) {
+			active = append(active, item.JobID)
+		}
+	}
+	m.mu.Unlock()
+
+	b.stopOnce.Do(func() { close(b.stop) })
+	for _, jobID := range active {
+		_ = m.Cancel(jobID)
+	}
+	return nil
+}
+
+// ListBatches returns all known batches, newest first.
+func (m *Manager) ListBatches() []Batch {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]Batch, 0, len(m.batches))
+	for _, b := range m.batches {
+		out = append(out, b.snapshot())
+	}
+	sort.Slice(out, func(a, b int) bool { return out[a].CreatedAt.After(out[b].CreatedAt) })
+	return out
+}
+
+// GetBatch returns a single batch.
+func (m *Manager) GetBatch(id string) (Batch, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	b, ok := m.batches[id]
+	if !ok {
+		return Batch{}, fmt.Errorf("批量任务不存在: %s", id)
+	}
+	return b.snapshot(), nil
+}
+
+// runBatch feeds entries into the job queue and collects their results.
+func (m *Manager) runBatch(b *batch) {
+	slots := make(chan struct{}, b.Parallelism)
+	var wg sync.WaitGroup
+
+submit:
+	for i := range b.entries {
+		select {
+		case slots <- struct{}{}:
+		case <-b.stop:
+			break submit
+		}
+		select {
+		case <-b.stop:
+			<-slots
+			break submit
+		default:
+		}
+
+		// 提交和记录 JobID 在同一把锁下完成, CancelBatch 不会漏掉刚提交的任务
+		j := newJob(b.entries[i].Spec)
+		m.mu.Lock()
+		if b.canceled {
+			m.mu.Unlock()
+			<-slots
+			break submit
+		}
+		jobSnapshot := m.enqueueLocked(j)
+		b.Items[i].JobID = j.ID
+		snapshot := b.snapshot()
+		m.mu.Unlock()
+		m.emit(EventStatus, jobSnapshot)
+		m.emit(EventBatch, snapshot)
+		m.schedule()
+
+		wg.Add(1)
+		go func(i int, j *job) {
+			defer wg.Done()
+			select {
+			case <-j.started:
+				m.updateItem(b, i, func(item *BatchItem) { item.Status = StatusRunning })
+			case <-j.done:
+			}
+			<-j.done
+			m.mu.Lock()
+			status, errMsg := j.Status, j.Error
+			m.mu.Unlock()
+			m.updateItem(b, i, func(item *BatchItem) {
+				item.Status = status
+				item.Error = errMsg
+			})
+			<-slots
+		}(i, j)
+	}
+	wg.Wait()
+
+	m.mu.Lock()
+	for i := range b.Items {
+		if b.Items[i].JobID == "" {
+			b.Items[i].Status = StatusCanceled
+		}
+	}
+	b.count()
+	now := time.Now()
+	b.FinishedAt = &now
+	switch {
+	case b.Canceled > 0 && b.Failed == 0:
+		b.Status = StatusCanceled
+	case b.Failed > 0 || b.Canceled > 0:
+		b.Status = StatusFailed
+	default:
+		b.Status = StatusSucceeded
+	}
+	snapshot := b.snapshot()
+	m.pruneBatchesLocked()
+	m.mu.Unlock()
+
+	m.emit(EventBatch, snapshot)
+}
+
+// updateItem applies fn to an item and publishes the new batch state.
+func (m *Manager) updateItem(b *batch, i int, fn func(*BatchItem)) {
+	m.mu.Lock()
+	fn(&b.Items[i])
+	b.count()
+	snapshot := b.snapshot()
+	m.mu.Unlock()
+	m.emit(EventBatch, snapshot)
+}
+
+// pruneBatchesLocked drops the oldest finished batches beyond the limit.
+func (m *Manager) pruneBatchesLocked() {
+	var finished []*batch
+	for _, b := range m.batches {
+		if b.FinishedAt != nil {
+			finished = append(finished, b)
+		}
+	}
+	if len(finished) <= maxFinishedBatches {
+		return
+	}
+	sort.Slice(finished, func(a, b int) bool { return finished[a].FinishedAt.Before(*finished[b].FinishedAt) })
+	for _, b := range finished[:len(finished)-maxFinishedBatches] {
+		delete(m.batches, b.ID)
+	}
+}
+
+// count refreshes the summary counters. The caller must hold m.mu.
+func (b *batch) count() {
+	b.Succeeded, b.Failed, b.Canceled = 0, 0, 0
+	for _, item := range b.Items {
+		switch item.Status {
+		case StatusSucceeded:
+			b.Succeeded++
+		case StatusFailed:
+			b.Failed++
+		case StatusCanceled:
+			b.Canceled++
+		}
+	}
+}
+
+// snapshot copies the public state of a batch. The caller must hold m.mu.
+func (b *batch) snapshot() Batch {
+	s := b.Batch
+	s.Items = append([]BatchItem(nil), b.Items...)
+	return s
+}
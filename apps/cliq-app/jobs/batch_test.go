@@ -0,0 +1,113 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func waitBatch(t *testing.T, m *Manager, id string) Batch {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		b, err := m.GetBatch(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b.FinishedAt != nil {
+			return b
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("batch %s did not finish", id)
+	return Batch{}
+}
+
+func TestBatchParallelism(t *testing.T) {
+	m := NewManager(4, nil)
+	g := newGate()
+	var entries []BatchEntry
+	for _, name := range []string{"a", "b", "c"} {
+		entries = append(entries, BatchEntry{Input: name, Output: name + ".out", Spec: Spec{Task: g.task(name)}})
+	}
+	b := m.SubmitBatch("t", "c", "cmd", entries, 1)
+	if b.Total != 3 || b.Status != StatusRunning || b.Items[0].Output != "a.out" {
+		t.Errorf("submitted batch = %+v", b)
+	}
+
+	// items run one at a time and in order, although the manager has room for more
+	for _, want := range []string{"a", "b", "c"} {
+		if got := g.next(t, time.Second); got != want {
+			t.Fatalf("started %q, want %q", got, want)
+		}
+		if name := g.next(t, 50*time.Millisecond); name != "" {
+			t.Fatalf("%s started beyond the batch parallelism", name)
+		}
+		g.release <- struct{}{}
+	}
+	b = waitBatch(t, m, b.ID)
+	if b.Status != StatusSucceeded || b.Succeeded != 3 || b.Failed != 0 {
+		t.Errorf("finished batch = %+v", b)
+	}
+	for _, item := range b.Items {
+		if item.JobID == "" || item.Status != StatusSucceeded {
+			t.Errorf("item = %+v", item)
+		}
+	}
+}
+
+func TestBatchFailures(t *testing.T) {
+	m := NewManager(2, nil)
+	ok := func(ctx context.Context, out func(string)) error { return nil }
+	fail := func(ctx context.Context, out func(string)) error { return errors.New("bad input") }
+	b := m.SubmitBatch("t", "c", "cmd", []BatchEntry{
+		{Input: "a", Spec: Spec{Task: ok}},
+		{Input: "b", Spec: Spec{Task: fail}},
+		{Input: "c", Spec: Spec{Task: ok}},
+	}, 0)
+	if b.Parallelism != DefaultConcurrency {
+		t.Errorf("parallelism = %d, want the default", b.Parallelism)
+	}
+	b = waitBatch(t, m, b.ID)
+	if b.Status != StatusFailed || b.Succeeded != 2 || b.Failed != 1 || b.Items[1].Error != "bad input" {
+		t.Errorf("batch = %+v", b)
+	}
+}
+
+func TestCancelBatch(t *testing.T) {
+	m := NewManager(4, nil)
+	g := newGate()
+	b := m.SubmitBatch("t", "c", "cmd", []BatchEntry{
+		{Input: "a", Spec: Spec{Task: g.task("a")}},
+		{Input: "b", Spec: Spec{Task: g.task("b")}},
+		{Input: "c", Spec: Spec{Task: g.task("c")}},
+	}, 2)
+	g.next(t, time.Second)
+	g.next(t, time.Second)
+
+	if err := m.CancelBatch(b.ID); err != nil {
+		t.Fatal(err)
+	}
+	b = waitBatch(t, m, b.ID)
+	if b.Status != StatusCanceled || b.Canceled != 3 {
+		t.Errorf("canceled batch = %+v", b)
+	}
+	if b.Items[2].JobID != "" {
+		t.Errorf("the third item was submitted after the batch was canceled")
+	}
+	if name := g.next(t, 50*time.Millisecond); name != "" {
+		t.Errorf("%s started after the batch was canceled", name)
+	}
+
+	if err := m.CancelBatch(b.ID); err == nil || !strings.Contains(err.Error(), "批量任务已结束") {
+		t.Errorf("CancelBatch of a finished batch error = %v", err)
+	}
+	if err := m.CancelBatch("missing"); err == nil || !strings.Contains(err.Error(), "批量任务不存在") {
+		t.Errorf("CancelBatch of an unknown batch error = %v", err)
+	}
+	if list := m.ListBatches(); len(list) != 1 || list[0].ID != b.ID {
+		t.Errorf("ListBatches = %+v", list)
+	}
+}
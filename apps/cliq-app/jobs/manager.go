@@ -0,0 +1,610 @@
+// Package jobs queues command executions and runs a bounded number of them
+// in parallel. Job state lives in the Go process, so it survives reloads of
+// the frontend.
+package jobs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"cliq/executor"
+	"cliq/output"
+	"cliq/progress"
+	"repo/shared-go-lib/render"
+)
+
+// Status is the lifecycle state of a job.
+type Status string
+
+const (
+	StatusQueued    Status = "queued"
+	StatusRunning   Status = "running"
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+	StatusCanceled  Status = "canceled"
+)
+
+// Event names emitted by the manager.
+const (
+	// EventOutput carries one executor.OutputLine.
+	EventOutput = "command:output"
+	// EventExit carries the executor.Result of a finished process.
+	EventExit = "command:exit"
+	// EventStatus carries a Job snapshot whenever its status changes.
+	EventStatus = "job:status"
+	// EventProgress carries a Progress of a running job.
+	EventProgress = "job:progress"
+)
+
+const (
+	// DefaultConcurrency is used when no valid limit is configured.
+	DefaultConcurrency = 2
+	// maxOutputLines is the number of most recent output lines kept per job.
+	maxOutputLines = 1000
+	// maxFinishedJobs is the number of finished jobs kept in memory.
+	maxFinishedJobs = 200
+	// maxCapturedStdout caps the stdout kept for jobs with CaptureStdout.
+	maxCapturedStdout = 1 << 20
+	// progressInterval throttles progress events of a job.
+	progressInterval = 250 * time.Millisecond
+)
+
+// Spec describes the command a job runs.
+type Spec struct {
+	TemplateName    string
+	TemplateVersion string
+	CommandID       string
+	CommandName     string
+	// Variables are the submitted form values, kept for history and re-runs.
+	Variables map[string]interface{}
+	Argv      []string
+	// Dir is the working directory; empty means the app's.
+	Dir string
+	// Env is added to the inherited environment.
+	Env map[string]string
+	// StdinFile is streamed to the process's stdin; Stdin is used as the
+	// input text when it is empty.
+	StdinFile string
+	Stdin     string
+	// Timeout limits the run time once the job has started; zero means none.
+	Timeout time.Duration
+	// Task runs in place of Argv when set, for work done in-process such as
+	// downloads. Lines passed to out are streamed like process output.
+	Task Task
+	// CaptureStdout keeps the raw stdout (up to maxCapturedStdout) in
+	// addition to the recent output lines, for pipelines that pass it on.
+	CaptureStdout bool
+	// Parse turns the stdout of a successful run into Job.Result. Setting it
+	// implies CaptureStdout.
+	Parse Parser
+	// Progress reads the progress from the output lines while the job runs.
+	Progress *progress.Tracker
+	// Secrets are the values of secret variables. The job runs with them,
+	// but its argv, env, stdin, output and error only ever show
+	// render.Mask in their place.
+	Secrets []string
+}
+
+// Job is a snapshot of a queued, running or finished execution.
+type Job struct {
+	ID              string                 `json:"id"`
+	TemplateName    string                 `json:"template_name"`
+	TemplateVersion string                 `json:"template_version"`
+	CommandID       string                 `json:"command_id"`
+	CommandName     string                 `json:"command_name"`
+	Variables       map[string]interface{} `json:"variables,omitempty"`
+	Argv            []string               `json:"argv"`
+	Dir             string                 `json:"workdir,omitempty"`
+	Env             map[string]string      `json:"env,omitempty"`
+	StdinFile       string                 `json:"stdin_file,omitempty"`
+	Stdin           string                 `json:"stdin,omitempty"`
+	Status          Status                 `json:"status"`
+	CreatedAt       time.Time              `json:"created_at"`
+	StartedAt       *time.Time             `json:"started_at,omitempty"`
+	FinishedAt      *time.Time             `json:"finished_at,omitempty"`
+	ExitCode        int                    `json:"exit_code"`
+	DurationMs      int64                  `json:"duration_ms"`
+	Error           string                 `json:"error,omitempty"`
+	Output          []executor.OutputLine  `json:"output,omitempty"`
+	// Result is the parsed stdout of jobs submitted with a Parser.
+	Result *output.Result `json:"result,omitempty"`
+	// Progress is the latest progress of jobs submitted with a tracker.
+	Progress *Progress `json:"progress,omitempty"`
+	// Redacted is set when secrets were masked, so Argv cannot be run as is.
+	Redacted bool `json:"redacted,omitempty"`
+}
+
+// Progress is the progress of a running job.
+type Progress struct {
+	JobID   string  `json:"job_id"`
+	Percent float64 `json:"percent"`
+	Current float64 `json:"current,omitempty"`
+	Total   float64 `json:"total,omitempty"`
+	// EtaMs is the estimated remaining time, extrapolated from the time
+	// spent so far; zero when it cannot be estimated yet.
+	EtaMs int64 `json:"eta_ms,omitempty"`
+}
+
+// Task is in-process work run as a job. It must return promptly once ctx
+// is done.
+type Task func(ctx context.Context, out func(line string)) error
+
+// Parser parses the captured stdout of a finished job.
+type Parser func(stdout string) *output.Result
+
+// Emitter publishes an event to the frontend.
+type Emitter func(name string, data interface{})
+
+// FinishHook is called once for every job that reaches a final status,
+// with a snapshot that includes the buffered output.
+type FinishHook func(Job)
+
+type job struct {
+	Job
+	timeout time.Duration
+	cancel  context.CancelFunc
+	started chan struct{} // closed once the job has left the queue and runs
+	done    chan struct{} // closed once the job has reached a final status
+	task    Task
+	parse   Parser
+	// stdout is the captured stdout, nil unless CaptureStdout or Parse is set.
+	stdout  *stdoutCapture
+	tracker *progress.Tracker
+	// lastProgress is when the last progress event was emitted.
+	lastProgress time.Time
+	// argv, env and stdin are what actually runs; the fields of Job have
+	// the secrets masked.
+	argv    []string
+	env     map[string]string
+	stdin   string
+	secrets []string
+}
+
+// Manager runs jobs from a FIFO queue with a concurrency limit.
+type Manager struct {
+	mu        sync.Mutex
+	jobs      map[string]*job
+	queue     []*job
+	running   int
+	limit     int
+	emit      Emitter
+	onDone    FinishHook
+	batches   map[string]*batch
+	pipelines map[string]*pipelineRun
+}
+
+// NewManager creates a manager that runs at most limit jobs at once and
+// reports progress through emit.
+func NewManager(limit int, emit Emitter) *Manager {
+	if limit < 1 {
+		limit = DefaultConcurrency
+	}
+	if emit == nil {
+		emit = func(string, interface{}) {}
+	}
+	return &Manager{jobs: make(map[string]*job), limit: limit, emit: emit}
+}
+
+// SetConcurrency changes the limit. Raising it starts queued jobs right
+// away; lowering it lets running jobs finish.
+func (m *Manager) SetConcurrency(limit int) {
+	if limit < 1 {
+		limit = DefaultConcurrency
+	}
+	m.mu.Lock()
+	m.limit = limit
+	m.mu.Unlock()
+	m.schedule()
+}
+
+// OnFinish registers the hook called when a job has finished.
+func (m *Manager) OnFinish(hook FinishHook) {
+	m.mu.Lock()
+	m.onDone = hook
+	m.mu.Unlock()
+}
+
+// Submit queues a new job and returns its snapshot.
+func (m *Manager) Submit(spec Spec) Job {
+	j := m.submit(spec)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return j.snapshot(false)
+}
+
+// submit queues a new job and returns the internal job.
+func (m *Manager) submit(spec Spec) *job {
+	j := newJob(spec)
+	m.mu.Lock()
+	snapshot := m.enqueueLocked(j)
+	m.mu.Unlock()
+
+	m.emit(EventStatus, snapshot)
+	m.schedule()
+	return j
+}
+
+// newJob creates a queued job for spec without submitting it.
+func newJob(spec Spec) *job {
+	j := &job{
+		Job: Job{
+			ID:              executor.NewJobID(),
+			TemplateName:    spec.TemplateName,
+			TemplateVersion: spec.TemplateVersion,
+			CommandID:       spec.CommandID,
+			CommandName:     spec.CommandName,
+			Variables:       spec.Variables,
+			Argv:            redactArgv(spec.Argv, spec.Secrets),
+			Dir:             spec.Dir,
+			Env:             redactEnv(spec.Env, spec.Secrets),
+			StdinFile:       spec.StdinFile,
+			Stdin:           render.Redact(spec.Stdin, spec.Secrets),
+			Status:          StatusQueued,
+			CreatedAt:       time.Now(),
+			ExitCode:        -1,
+			Redacted:        len(spec.Secrets) > 0,
+		},
+		argv:    spec.Argv,
+		env:     spec.Env,
+		stdin:   spec.Stdin,
+		secrets: spec.Secrets,
+		timeout: spec.Timeout,
+		started: make(chan struct{}),
+		done:    make(chan struct{}),
+		task:    spec.Task,
+		parse:   spec.Parse,
+		tracker: spec.Progress,
+	}
+	if spec.CaptureStdout || spec.Parse != nil {
+		j.stdout = &stdoutCapture{}
+	}
+	return j
+}
+
+// enqueueLocked adds j to the queue and returns the snapshot to emit. The
+// caller must hold m.mu and call schedule afterwards.
+func (m *Manager) enqueueLocked(j *job) Job {
+	m.jobs[j.ID] = j
+	m.queue = append(m.queue, j)
+	return j.snapshot(false)
+}
+
+// Cancel removes a queued job from the queue or stops a running one.
+func (m *Manager) Cancel(id string) error {
+	m.mu.Lock()
+	j, ok := m.jobs[id]
+	if !ok {
+		m.mu.Unlock()
+		return fmt.Errorf("任务不存在: %s", id)
+	}
+	switch j.Status {
+	case StatusQueued:
+		for i, q := range m.queue {
+			if q == j {
+				m.queue = append(m.queue[:i], m.queue[i+1:]...)
+				break
+			}
+		}
+		now := time.Now()
+		j.Status = StatusCanceled
+		j.FinishedAt = &now
+		j.Error = "命令已取消"
+		close(j.done)
+		snapshot := j.snapshot(false)
+		m.pruneLocked()
+		m.mu.Unlock()
+		m.emit(EventExit, executor.Result{
+			JobID:      j.ID,
+			ExitCode:   -1,
+			FinishedAt: now,
+			Canceled:   true,
+			Error:      snapshot.Error,
+		})
+		m.emit(EventStatus, snapshot)
+		return nil
+	case StatusRunning:
+		cancel := j.cancel
+		m.mu.Unlock()
+		cancel()
+		return nil
+	default:
+		m.mu.Unlock()
+		return fmt.Errorf("任务已结束: %s", id)
+	}
+}
+
+// List returns all known jobs, newest first, without their output.
+func (m *Manager) List() []Job {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]Job, 0, len(m.jobs))
+	for _, j := range m.jobs {
+		out = append(out, j.snapshot(false))
+	}
+	sort.Slice(out, func(a, b int) bool { return out[a].CreatedAt.After(out[b].CreatedAt) })
+	return out
+}
+
+// Get returns a job including its buffered output.
+func (m *Manager) Get(id string) (Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	j, ok := m.jobs[id]
+	if !ok {
+		return Job{}, fmt.Errorf("任务不存在: %s", id)
+	}
+	return j.snapshot(true), nil
+}
+
+// Wait blocks until a job has reached a final status and returns its
+// snapshot including the buffered output.
+func (m *Manager) Wait(id string) (Job, error) {
+	m.mu.Lock()
+	j, ok := m.jobs[id]
+	m.mu.Unlock()
+	if !ok {
+		return Job{}, fmt.Errorf("任务不存在: %s", id)
+	}
+	<-j.done
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return j.snapshot(true), nil
+}
+
+// schedule starts queued jobs while there is capacity.
+func (m *Manager) schedule() {
+	for {
+		m.mu.Lock()
+		if m.running >= m.limit || len(m.queue) == 0 {
+			m.mu.Unlock()
+			return
+		}
+		j := m.queue[0]
+		m.queue = m.queue[1:]
+
+		ctx, cancel := jobContext(j.timeout)
+		now := time.Now()
+		j.cancel = cancel
+		j.Status = StatusRunning
+		j.StartedAt = &now
+		close(j.started)
+		m.running++
+		snapshot := j.snapshot(false)
+		m.mu.Unlock()
+
+		m.emit(EventStatus, snapshot)
+		go m.run(ctx, j)
+	}
+}
+
+// jobContext creates the context a job runs under; the timeout clock starts
+// when the job leaves the queue.
+func jobContext(timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout > 0 {
+		return context.WithTimeout(context.Background(), timeout)
+	}
+	return context.WithCancel(context.Background())
+}
+
+// run executes a job and records its result.
+func (m *Manager) run(ctx context.Context, j *job) {
+	onLine := func(line executor.OutputLine) {
+		line.Line = render.Redact(line.Line, j.secrets)
+		m.mu.Lock()
+		j.Output = append(j.Output, line)
+		if len(j.Output) > maxOutputLines {
+			j.Output = j.Output[len(j.Output)-maxOutputLines:]
+		}
+		if j.task != nil && j.stdout != nil {
+			// 进程的标准输出按原始字节捕获, 任务只有输出行
+			j.stdout.Write([]byte(line.Line + "\n"))
+		}
+		p := m.trackProgress(j, line.Line)
+		m.mu.Unlock()
+		m.emit(EventOutput, line)
+		if p != nil {
+			m.emit(EventProgress, *p)
+		}
+	}
+	var res executor.Result
+	if j.task != nil {
+		res = runTask(ctx, j.ID, j.task, onLine)
+	} else {
+		c := executor.Command{Argv: j.argv, Dir: j.Dir, Env: j.env, StdinFile: j.StdinFile, Stdin: j.stdin}
+		if j.stdout != nil {
+			c.Stdout = j.stdout
+		}
+		res = executor.Run(ctx, j.ID, c, onLine)
+	}
+	j.cancel()
+	res.Error = render.Redact(res.Error, j.secrets)
+
+	// 解析可能较慢, 不在持有锁时进行
+	var result *output.Result
+	if j.parse != nil && !res.Canceled && res.Error == "" {
+		stdout, truncated := j.capturedStdout()
+		result = j.parse(stdout)
+		if truncated {
+			msg := fmt.Sprintf("输出超过 %d MB, 只解析了开头部分", maxCapturedStdout>>20)
+			if result.Error != "" {
+				msg += "; " + result.Error
+			}
+			result.Error = msg
+		}
+	}
+
+	m.mu.Lock()
+	finished := res.FinishedAt
+	j.FinishedAt = &finished
+	j.ExitCode = res.ExitCode
+	j.DurationMs = res.DurationMs
+	j.Error = res.Error
+	switch {
+	case res.Canceled:
+		j.Status = StatusCanceled
+	case res.Error != "":
+		j.Status = StatusFailed
+	default:
+		j.Status = StatusSucceeded
+		j.Result = result
+		if j.Progress != nil {
+			done := *j.Progress
+			done.Percent, done.EtaMs = 100, 0
+			j.Progress = &done
+		}
+	}
+	m.running--
+	close(j.done)
+	snapshot := j.snapshot(false)
+	final := j.snapshot(true)
+	onDone := m.onDone
+	m.pruneLocked()
+	m.mu.Unlock()
+
+	m.emit(EventExit, res)
+	m.emit(EventStatus, snapshot)
+	if onDone != nil {
+		onDone(final)
+	}
+	m.schedule()
+}
+
+// trackProgress feeds a line to the job's tracker and returns the progress
+// to emit, if any. Events are throttled to one per progressInterval, except
+// for reaching 100%. The caller must hold m.mu.
+func (m *Manager) trackProgress(j *job, line string) *Progress {
+	if j.tracker == nil {
+		return nil
+	}
+	u, ok := j.tracker.Feed(line)
+	if !ok {
+		return nil
+	}
+	p := &Progress{JobID: j.ID, Percent: u.Percent, Current: u.Current, Total: u.Total}
+	if j.StartedAt != nil && u.Percent > 0 && u.Percent < 100 {
+		elapsed := time.Since(*j.StartedAt)
+		p.EtaMs = int64(float64(elapsed.Milliseconds()) * (100 - u.Percent) / u.Percent)
+	}
+	j.Progress = p
+
+	now := time.Now()
+	if now.Sub(j.lastProgress) < progressInterval && u.Percent < 100 {
+		return nil
+	}
+	j.lastProgress = now
+	snapshot := *p
+	return &snapshot
+}
+
+// runTask runs an in-process task and reports it like a finished process:
+// exit code 0 on success and 1 on error.
+func runTask(ctx context.Context, jobID string, task Task, onLine func(executor.OutputLine)) executor.Result {
+	res := executor.Result{JobID: jobID, ExitCode: -1, StartedAt: time.Now()}
+	err := task(ctx, func(line string) {
+		onLine(executor.OutputLine{JobID: jobID, Stream: executor.StreamStdout, Line: line, Time: time.Now()})
+	})
+	res.FinishedAt = time.Now()
+	res.DurationMs = res.FinishedAt.Sub(res.StartedAt).Milliseconds()
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		res.TimedOut = true
+		res.Error = "命令执行超时"
+	case ctx.Err() != nil:
+		res.Canceled = true
+		res.Error = "命令已取消"
+	case err != nil:
+		res.ExitCode = 1
+		res.Error = err.Error()
+	default:
+		res.ExitCode = 0
+	}
+	return res
+}
+
+// stdoutCapture keeps the raw stdout of a job, up to maxCapturedStdout
+// bytes.
+type stdoutCapture struct {
+	mu        sync.Mutex
+	buf       bytes.Buffer
+	truncated bool
+}
+
+func (c *stdoutCapture) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if room := maxCapturedStdout - c.buf.Len(); len(p) > room {
+		c.buf.Write(p[:room])
+		c.truncated = true
+	} else {
+		c.buf.Write(p)
+	}
+	return len(p), nil
+}
+
+// capturedStdout returns the captured stdout with secrets masked and
+// whether it was cut at maxCapturedStdout.
+func (j *job) capturedStdout() (string, bool) {
+	if j.stdout == nil {
+		return "", false
+	}
+	j.stdout.mu.Lock()
+	defer j.stdout.mu.Unlock()
+	return render.Redact(j.stdout.buf.String(), j.secrets), j.stdout.truncated
+}
+
+// pruneLocked drops the oldest finished jobs beyond maxFinishedJobs.
+func (m *Manager) pruneLocked() {
+	var finished []*job
+	for _, j := range m.jobs {
+		if j.FinishedAt != nil {
+			finished = append(finished, j)
+		}
+	}
+	if len(finished) <= maxFinishedJobs {
+		return
+	}
+	sort.Slice(finished, func(a, b int) bool { return finished[a].FinishedAt.Before(*finished[b].FinishedAt) })
+	for _, j := range finished[:len(finished)-maxFinishedJobs] {
+		delete(m.jobs, j.ID)
+	}
+}
+
+// redactArgv returns argv with the secrets masked.
+func redactArgv(argv []string, secrets []string) []string {
+	if len(secrets) == 0 {
+		return argv
+	}
+	out := make([]string, len(argv))
+	for i, arg := range argv {
+		out[i] = render.Redact(arg, secrets)
+	}
+	return out
+}
+
+// redactEnv returns env with the secrets masked.
+func redactEnv(env map[string]string, secrets []string) map[string]string {
+	if len(secrets) == 0 {
+		return env
+	}
+	out := make(map[string]string, len(env))
+	for k, v := range env {
+		out[k] = render.Redact(v, secrets)
+	}
+	return out
+}
+
+// snapshot copies the public state of a job. The caller must hold m.mu.
+func (j *job) snapshot(withOutput bool) Job {
+	s := j.Job
+	s.Argv = append([]string(nil), j.Argv...)
+	s.Output = nil
+	if withOutput {
+		s.Output = append([]executor.OutputLine(nil), j.Output...)
+	}
+	return s
+}
@@ -0,0 +1,293 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"cliq/output"
+	"repo/shared-go-lib/render"
+)
+
+// gate hands out tasks that report when they start and then run until the
+// gate releases one of them or their job is stopped.
+type gate struct {
+	started chan string
+	release chan struct{}
+	running atomic.Int32
+	peak    atomic.Int32
+}
+
+func newGate() *gate {
+	return &gate{started: make(chan string, 100), release: make(chan struct{})}
+}
+
+func (g *gate) task(name string) Task {
+	return func(ctx context.Context, out func(string)) error {
+		n := g.running.Add(1)
+		defer g.running.Add(-1)
+		for {
+			peak := g.peak.Load()
+			if n <= peak || g.peak.CompareAndSwap(peak, n) {
+				break
+			}
+		}
+		g.started <- name
+		select {
+		case <-g.release:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// next returns the name of the next task to start, or "" if none starts
+// within wait.
+func (g *gate) next(t *testing.T, wait time.Duration) string {
+	t.Helper()
+	select {
+	case name := <-g.started:
+		return name
+	case <-time.After(wait):
+		return ""
+	}
+}
+
+func waitJob(t *testing.T, m *Manager, id string) Job {
+	t.Helper()
+	done := make(chan Job, 1)
+	go func() {
+		j, _ := m.Wait(id)
+		done <- j
+	}()
+	select {
+	case j := <-done:
+		return j
+	case <-time.After(5 * time.Second):
+		t.Fatalf("job %s did not finish", id)
+		return Job{}
+	}
+}
+
+func TestQueueOrderAndConcurrency(t *testing.T) {
+	m := NewManager(2, nil)
+	g := newGate()
+	names := []string{"a", "b", "c", "d", "e"}
+	ids := map[string]string{}
+	for _, name := range names {
+		ids[name] = m.Submit(Spec{CommandName: name, Task: g.task(name)}).ID
+	}
+
+	first := []string{g.next(t, time.Second), g.next(t, time.Second)}
+	sort.Strings(first)
+	if !reflect.DeepEqual(first, []string{"a", "b"}) {
+		t.Fatalf("first started = %q, want a and b", first)
+	}
+	if name := g.next(t, 50*time.Millisecond); name != "" {
+		t.Fatalf("%s started beyond the limit", name)
+	}
+	for _, name := range []string{"c", "d", "e"} {
+		if j, _ := m.Get(ids[name]); j.Status != StatusQueued {
+			t.Errorf("%s status = %s, want queued", name, j.Status)
+		}
+	}
+
+	// every finished job lets the oldest queued one start
+	for _, want := range []string{"c", "d", "e"} {
+		g.release <- struct{}{}
+		if got := g.next(t, time.Second); got != want {
+			t.Fatalf("started %q, want %q", got, want)
+		}
+	}
+	g.release <- struct{}{}
+	g.release <- struct{}{}
+	for _, name := range names {
+		if j := waitJob(t, m, ids[name]); j.Status != StatusSucceeded || j.ExitCode != 0 {
+			t.Errorf("%s = %+v", name, j)
+		}
+	}
+	if peak := g.peak.Load(); peak != 2 {
+		t.Errorf("%d jobs ran at once, want 2", peak)
+	}
+
+	list := m.List()
+	if len(list) != len(names) {
+		t.Fatalf("List returned %d jobs", len(list))
+	}
+	for i := 1; i < len(list); i++ {
+		if list[i].CreatedAt.After(list[i-1].CreatedAt) {
+			t.Errorf("List is not newest first")
+		}
+	}
+}
+
+func TestSetConcurrency(t *testing.T) {
+	m := NewManager(0, nil)
+	g := newGate()
+	for _, name := range []string{"a", "b", "c", "d"} {
+		m.Submit(Spec{Task: g.task(name)})
+	}
+	for i := 0; i < DefaultConcurrency; i++ {
+		g.next(t, time.Second)
+	}
+	if name := g.next(t, 50*time.Millisecond); name != "" {
+		t.Fatalf("%s started beyond the default limit", name)
+	}
+
+	m.SetConcurrency(4)
+	for i := 0; i < 2; i++ {
+		if g.next(t, time.Second) == "" {
+			t.Fatal("raising the limit did not start the queued jobs")
+		}
+	}
+	for i := 0; i < 4; i++ {
+		g.release <- struct{}{}
+	}
+}
+
+func TestCancel(t *testing.T) {
+	m := NewManager(1, nil)
+	g := newGate()
+	running := m.Submit(Spec{Task: g.task("running")})
+	queued := m.Submit(Spec{Task: g.task("queued")})
+	g.next(t, time.Second)
+
+	if err := m.Cancel(queued.ID); err != nil {
+		t.Fatal(err)
+	}
+	j := waitJob(t, m, queued.ID)
+	if j.Status != StatusCanceled || j.Error != "命令已取消" || j.StartedAt != nil {
+		t.Errorf("canceled queued job = %+v", j)
+	}
+
+	if err := m.Cancel(running.ID); err != nil {
+		t.Fatal(err)
+	}
+	j = waitJob(t, m, running.ID)
+	if j.Status != StatusCanceled || j.ExitCode != -1 {
+		t.Errorf("canceled running job = %+v", j)
+	}
+	if name := g.next(t, 50*time.Millisecond); name != "" {
+		t.Errorf("canceled job %s started", name)
+	}
+
+	if err := m.Cancel(running.ID); err == nil || !strings.Contains(err.Error(), "任务已结束") {
+		t.Errorf("Cancel of a finished job error = %v", err)
+	}
+	if err := m.Cancel("missing"); err == nil || !strings.Contains(err.Error(), "任务不存在") {
+		t.Errorf("Cancel of an unknown job error = %v", err)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	m := NewManager(1, nil)
+	g := newGate()
+	j := waitJob(t, m, m.Submit(Spec{Timeout: 50 * time.Millisecond, Task: g.task("slow")}).ID)
+	if j.Status != StatusFailed || j.Error != "命令执行超时" {
+		t.Errorf("timed out job = %+v", j)
+	}
+	g.next(t, time.Second)
+
+	// the timeout clock starts when the job leaves the queue
+	first := m.Submit(Spec{Task: g.task("first")})
+	g.next(t, time.Second)
+	quick := m.Submit(Spec{Timeout: 50 * time.Millisecond, Task: func(ctx context.Context, out func(string)) error {
+		return nil
+	}})
+	time.Sleep(150 * time.Millisecond)
+	g.release <- struct{}{}
+	waitJob(t, m, first.ID)
+	if j := waitJob(t, m, quick.ID); j.Status != StatusSucceeded {
+		t.Errorf("job timed out while queued: %+v", j)
+	}
+}
+
+func TestJobResults(t *testing.T) {
+	var mu sync.Mutex
+	var events []string
+	m := NewManager(1, func(name string, data interface{}) {
+		if j, ok := data.(Job); ok && name == EventStatus {
+			mu.Lock()
+			events = append(events, j.CommandName+" "+string(j.Status))
+			mu.Unlock()
+		}
+	})
+	finished := make(chan Job, 10)
+	m.OnFinish(func(j Job) { finished <- j })
+
+	failed := waitJob(t, m, m.Submit(Spec{CommandName: "fail", Task: func(ctx context.Context, out func(string)) error {
+		out("working")
+		return errors.New("boom")
+	}}).ID)
+	if failed.Status != StatusFailed || failed.ExitCode != 1 || failed.Error != "boom" || len(failed.Output) != 1 {
+		t.Errorf("failed job = %+v", failed)
+	}
+
+	parsed := waitJob(t, m, m.Submit(Spec{
+		CommandName: "parse",
+		Argv:        []string{"tool", "--token", "s3cret"},
+		Env:         map[string]string{"TOKEN": "s3cret"},
+		Secrets:     []string{"s3cret"},
+		Parse: func(stdout string) *output.Result {
+			return &output.Result{Values: map[string]string{"stdout": stdout}}
+		},
+		Task: func(ctx context.Context, out func(string)) error {
+			out("a s3cret")
+			out("b")
+			return nil
+		},
+	}).ID)
+	masked := "a " + render.Mask
+	if parsed.Status != StatusSucceeded || !parsed.Redacted {
+		t.Errorf("parsed job = %+v", parsed)
+	}
+	if got := []string{parsed.Argv[2], parsed.Env["TOKEN"], parsed.Output[0].Line}; !reflect.DeepEqual(got, []string{render.Mask, render.Mask, masked}) {
+		t.Errorf("argv, env and output = %q, want the secret masked", got)
+	}
+	if parsed.Result == nil || parsed.Result.Values["stdout"] != masked+"\nb\n" {
+		t.Errorf("result = %+v", parsed.Result)
+	}
+
+	for _, want := range []string{"fail", "parse"} {
+		select {
+		case j := <-finished:
+			if j.CommandName != want || len(j.Output) == 0 {
+				t.Errorf("OnFinish got %+v, want %s with its output", j, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("OnFinish was not called for %s", want)
+		}
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	want := []string{"fail queued", "fail running", "fail failed", "parse queued", "parse running", "parse succeeded"}
+	if !reflect.DeepEqual(events, want) {
+		t.Errorf("status events = %q, want %q", events, want)
+	}
+}
+
+func TestPruneFinishedJobs(t *testing.T) {
+	m := NewManager(4, nil)
+	var last string
+	for i := 0; i < maxFinishedJobs+5; i++ {
+		last = m.Submit(Spec{Task: func(ctx context.Context, out func(string)) error { return nil }}).ID
+	}
+	waitJob(t, m, last)
+	deadline := time.Now().Add(5 * time.Second)
+	for len(m.List()) > maxFinishedJobs && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := len(m.List()); n != maxFinishedJobs {
+		t.Errorf("%d jobs kept, want %d", n, maxFinishedJobs)
+	}
+	if _, err := m.Get(last); err != nil {
+		t.Errorf("the newest job was pruned: %v", err)
+	}
+}
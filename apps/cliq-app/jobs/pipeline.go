@@ -0,0 +1,303 @@
+package jobs
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"cliq/executor"
+)
+
+// EventPipeline carries a PipelineRun snapshot whenever one of its steps
+// changes.
+const EventPipeline = "pipeline:status"
+
+// StatusSkipped marks pipeline steps that never ran because an earlier step
+// failed or the run was cancelled.
+const StatusSkipped Status = "skipped"
+
+// StatusCompletedWithErrors marks pipeline runs that ran every step but
+// where steps marked ContinueOnError failed.
+const StatusCompletedWithErrors Status = "completed_with_errors"
+
+// maxFinishedPipelines is the number of finished pipeline runs kept in memory.
+const maxFinishedPipelines = 50
+
+// StepResult is what a finished step hands on to the steps after it.
+type StepResult struct {
+	Status Status
+	// Output is the file the step wrote, if its command has one.
+	Output string
+	// Stdout is the captured standard output without the trailing newline.
+	Stdout string
+	// Values are the named values extracted from the step's parsed output.
+	Values map[string]string
+}
+
+// PipelineStep is one step of a pipeline run. Steps are prepared lazily so
+// that they can use the results of the steps before them.
+type PipelineStep struct {
+	ID              string
+	Name            string
+	CommandID       string
+	ContinueOnError bool
+	// Prepare builds the job for the step from the results of the steps
+	// that have finished, keyed by step ID. output is the file the step
+	// will write, if any.
+	Prepare func(results map[string]StepResult) (spec Spec, output string, err error)
+}
+
+// StepState reports the state of one step.
+type StepState struct {
+	ID              string `json:"id"`
+	Name            string `json:"name"`
+	CommandID       string `json:"command_id"`
+	ContinueOnError bool   `json:"continue_on_error"`
+	JobID           string `json:"job_id,omitempty"`
+	Status          Status `json:"status"`
+	Output          string `json:"output,omitempty"`
+	Error           string `json:"error,omitempty"`
+}
+
+// PipelineRun is a snapshot of a pipeline execution. Each step runs as a
+// regular job, so its output, exit and history are reported the same way
+// as for single commands.
+type PipelineRun struct {
+	ID           string      `json:"id"`
+	TemplateName string      `json:"template_name"`
+	PipelineID   string      `json:"pipeline_id"`
+	PipelineName string      `json:"pipeline_name"`
+	Status       Status      `json:"status"`
+	Steps        []StepState `json:"steps"`
+	// Current is the index of the running step, or -1.
+	Current    int        `json:"current"`
+	CreatedAt  time.Time  `json:"created_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+type pipelineRun struct {
+	PipelineRun
+	steps    []PipelineStep
+	stop     chan struct{}
+	stopOnce sync.Once
+	jobID    string // job of the running step
+}
+
+// SubmitPipeline starts running steps one after another.
+func (m *Manager) SubmitPipeline(templateName, pipelineID, pipelineName string, steps []PipelineStep) PipelineRun {
+	p := &pipelineRun{
+		PipelineRun: PipelineRun{
+			ID:           executor.NewJobID(),
+			TemplateName: templateName,
+			PipelineID:   pipelineID,
+			PipelineName: pipelineName,
+			Status:       StatusRunning,
+			Steps:        make([]StepState, len(steps)),
+			Current:      -1,
+			CreatedAt:    time.Now(),
+		},
+		steps: steps,
+		stop:  make(chan struct{}),
+	}
+	for i, s := range steps {
+		p.Steps[i] = StepState{
+			ID:              s.ID,
+			Name:            s.Name,
+			CommandID:       s.CommandID,
+			ContinueOnError: s.ContinueOnError,
+			Status:          StatusQueued,
+		}
+	}
+
+	m.mu.Lock()
+	if m.pipelines == nil {
+		m.pipelines = make(map[string]*pipelineRun)
+	}
+	m.pipelines[p.ID] = p
+	snapshot := p.snapshot()
+	m.mu.Unlock()
+
+	m.emit(EventPipeline, snapshot)
+	go m.runPipeline(p)
+	return snapshot
+}
+
+// CancelPipeline cancels the running step and skips the remaining ones.
+func (m *Manager) CancelPipeline(id string) error {
+	m.mu.Lock()
+	p, ok := m.pipelines[id]
+	if !ok {
+		m.mu.Unlock()
+		return fmt.Errorf("工作流不存在: %s", id)
+	}
+	if p.FinishedAt != nil {
+		m.mu.Unlock()
+		return fmt.Errorf("工作流已结束: %s", id)
+	}
+	jobID := p.jobID
+	m.mu.Unlock()
+
+	p.stopOnce.Do(func() { close(p.stop) })
+	if jobID != "" {
+		_ = m.Cancel(jobID)
+	}
+	return nil
+}
+
+// ListPipelines returns all known pipeline runs, newest first.
+func (m *Manager) ListPipelines() []PipelineRun {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]PipelineRun, 0, len(m.pipelines))
+	for _, p := range m.pipelines {
+		out = append(out, p.snapshot())
+	}
+	sort.Slice(out, func(a, b int) bool { return out[a].CreatedAt.After(out[b].CreatedAt) })
+	return out
+}
+
+// GetPipeline returns a single pipeline run.
+func (m *Manager) GetPipeline(id string) (PipelineRun, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	p, ok := m.pipelines[id]
+	if !ok {
+		return PipelineRun{}, fmt.Errorf("工作流不存在: %s", id)
+	}
+	return p.snapshot(), nil
+}
+
+// runPipeline runs the steps in order. A failed step stops the pipeline
+// unless it is marked ContinueOnError; the run then ends as
+// StatusCompletedWithErrors instead of StatusSucceeded.
+func (m *Manager) runPipeline(p *pipelineRun) {
+	results := make(map[string]StepResult, len(p.steps))
+	status := StatusSucceeded
+	stepFailed := false
+
+	for i, step := range p.steps {
+		if status != StatusSucceeded {
+			m.updateStep(p, i, func(s *StepState) { s.Status = StatusSkipped })
+			continue
+		}
+		select {
+		case <-p.stop:
+			status = StatusCanceled
+			m.updateStep(p, i, func(s *StepState) { s.Status = StatusSkipped })
+			continue
+		default:
+		}
+
+		result := m.runStep(p, i, step, results)
+		results[step.ID] = result
+		switch {
+		case result.Status == StatusCanceled:
+			status = StatusCanceled
+		case result.Status != StatusSucceeded && !step.ContinueOnError:
+			status = StatusFailed
+		case result.Status != StatusSucceeded:
+			stepFailed = true
+		}
+	}
+	if status == StatusSucceeded && stepFailed {
+		status = StatusCompletedWithErrors
+	}
+
+	m.mu.Lock()
+	now := time.Now()
+	p.Status = status
+	p.Current = -1
+	p.jobID = ""
+	p.FinishedAt = &now
+	snapshot := p.snapshot()
+	m.prunePipelinesLocked()
+	m.mu.Unlock()
+
+	m.emit(EventPipeline, snapshot)
+}
+
+// runStep prepares a step, runs it as a job and waits for it to finish.
+func (m *Manager) runStep(p *pipelineRun, i int, step PipelineStep, results map[string]StepResult) StepResult {
+	spec, output, err := step.Prepare(results)
+	if err != nil {
+		m.updateStep(p, i, func(s *StepState) {
+			s.Status = StatusFailed
+			s.Error = err.Error()
+		})
+		return StepResult{Status: StatusFailed}
+	}
+	spec.CaptureStdout = true
+
+	j := m.submit(spec)
+	m.mu.Lock()
+	p.jobID = j.ID
+	m.mu.Unlock()
+	m.updateStep(p, i, func(s *StepState) {
+		s.JobID = j.ID
+		s.Output = output
+		s.Status = StatusRunning
+		p.Current = i
+	})
+
+	// CancelPipeline may have read jobID before it was set
+	select {
+	case <-p.stop:
+		_ = m.Cancel(j.ID)
+	case <-j.done:
+	}
+	<-j.done
+
+	stdout, _ := j.capturedStdout()
+	m.mu.Lock()
+	result := StepResult{
+		Status: j.Status,
+		Output: output,
+		Stdout: strings.TrimRight(stdout, "\r\n"),
+	}
+	if j.Result != nil {
+		result.Values = j.Result.Values
+	}
+	errMsg := j.Error
+	m.mu.Unlock()
+
+	m.updateStep(p, i, func(s *StepState) {
+		s.Status = result.Status
+		s.Error = errMsg
+	})
+	return result
+}
+
+// updateStep applies fn to a step and publishes the new run state.
+func (m *Manager) updateStep(p *pipelineRun, i int, fn func(*StepState)) {
+	m.mu.Lock()
+	fn(&p.Steps[i])
+	snapshot := p.snapshot()
+	m.mu.Unlock()
+	m.emit(EventPipeline, snapshot)
+}
+
+// prunePipelinesLocked drops the oldest finished runs beyond the limit.
+func (m *Manager) prunePipelinesLocked() {
+	var finished []*pipelineRun
+	for _, p := range m.pipelines {
+		if p.FinishedAt != nil {
+			finished = append(finished, p)
+		}
+	}
+	if len(finished) <= maxFinishedPipelines {
+		return
+	}
+	sort.Slice(finished, func(a, b int) bool { return finished[a].FinishedAt.Before(*finished[b].FinishedAt) })
+	for _, p := range finished[:len(finished)-maxFinishedPipelines] {
+		delete(m.pipelines, p.ID)
+	}
+}
+
+// snapshot copies the public state of a run. The caller must hold m.mu.
+func (p *pipelineRun) snapshot() PipelineRun {
+	s := p.PipelineRun
+	s.Steps = append([]StepState(nil), p.Steps...)
+	return s
+}
@@ -0,0 +1,43 @@
+package jobs
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"cliq/progress"
+	"repo/shared-go-lib/models"
+)
+
+func TestJobProgress(t *testing.T) {
+	var mu sync.Mutex
+	var events []float64
+	m := NewManager(1, func(name string, data interface{}) {
+		if p, ok := data.(Progress); ok && name == EventProgress {
+			mu.Lock()
+			events = append(events, p.Percent)
+			mu.Unlock()
+		}
+	})
+	tracker, err := progress.NewTracker(models.ProgressSpec{Pattern: `(?P<percent>\d+)%`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	j := waitJob(t, m, m.Submit(Spec{Progress: tracker, Task: func(ctx context.Context, out func(string)) error {
+		for _, line := range []string{"10%", "20%", "noise", "30%", "90%"} {
+			out(line)
+		}
+		return nil
+	}}).ID)
+
+	// the first update is sent right away, later ones are throttled
+	mu.Lock()
+	defer mu.Unlock()
+	if len(events) != 1 || events[0] != 10 {
+		t.Errorf("progress events = %v, want only the first", events)
+	}
+	// a finished job shows 100% whatever the last line said
+	if j.Status != StatusSucceeded || j.Progress == nil || j.Progress.Percent != 100 || j.Progress.EtaMs != 0 {
+		t.Errorf("finished job progress = %+v", j.Progress)
+	}
+}
@@ -0,0 +1,58 @@
+package output
+
+import (
+	"fmt"
+	"strings"
+
+	"repo/shared-go-lib/models"
+)
+
+// maxChoices caps the choices read from an option source.
+const maxChoices = 1000
+
+// Choices extracts the choices of a select from the output of its
+// `options_from` command: every non-empty line, or the values found at the
+// source's JSON path. Duplicate values are dropped.
+func Choices(src models.OptionSource, stdout string) ([]models.Choice, error) {
+	var choices []models.Choice
+	seen := map[string]bool{}
+	add := func(c models.Choice) {
+		if c.Value == "" || seen[c.Value] || len(choices) >= maxChoices {
+			return
+		}
+		seen[c.Value] = true
+		choices = append(choices, c)
+	}
+
+	if src.OutputFormat() == models.SourceLines {
+		for _, line := range strings.Split(stdout, "\n") {
+			add(models.Choice{Value: strings.TrimSpace(line)})
+		}
+		return choices, nil
+	}
+
+	data, err := decodeJSON(stdout)
+	if err != nil {
+		return nil, fmt.Errorf("解析输出失败: %v", err)
+	}
+	node, ok := lookup(data, src.JSONPath)
+	if !ok {
+		return nil, fmt.Errorf("输出中没有 '%s'", src.JSONPath)
+	}
+	items, isList := node.([]interface{})
+	if !isList {
+		items = []interface{}{node}
+	}
+	for _, item := range items {
+		value, ok := lookup(item, src.Value)
+		if !ok || kind(value) == TypeJSON {
+			continue // 没有该字段, 或不是单个值
+		}
+		c := models.Choice{Value: text(value)}
+		if label, ok := lookup(item, src.Label); ok && src.Label != "" {
+			c.Label = text(label)
+		}
+		add(c)
+	}
+	return choices, nil
+}
@@ -0,0 +1,65 @@
+package output
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"repo/shared-go-lib/models"
+)
+
+func TestChoices(t *testing.T) {
+	tests := []struct {
+		name string
+		src  models.OptionSource
+		out  string
+		want []models.Choice
+	}{
+		{"lines", models.OptionSource{Command: "git branch"}, "main\n  dev \n\nmain\n",
+			[]models.Choice{{Value: "main"}, {Value: "dev"}}},
+		{"json list", models.OptionSource{Command: "x", Format: models.SourceJSON}, `["a", 2, true, null]`,
+			[]models.Choice{{Value: "a"}, {Value: "2"}, {Value: "true"}}},
+		{"json path, value and label", models.OptionSource{Command: "x", JSONPath: "items", Value: "metadata.name", Label: "spec.title"},
+			`{"items": [
+				{"metadata": {"name": "web"}, "spec": {"title": "Web server"}},
+				{"metadata": {"name": "db"}},
+				{"metadata": {"name": {"nested": 1}}},
+				{"spec": {"title": "no name"}}
+			]}`,
+			[]models.Choice{{Value: "web", Label: "Web server"}, {Value: "db"}}},
+		{"single object", models.OptionSource{Command: "x", JSONPath: "current", Value: "id"}, `{"current": {"id": 7}}`,
+			[]models.Choice{{Value: "7"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Choices(tt.src, tt.out)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Choices = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	var many strings.Builder
+	for i := 0; i < maxChoices+5; i++ {
+		many.WriteString(strings.Repeat("y", i+1) + "\n")
+	}
+	if got, _ := Choices(models.OptionSource{Command: "x"}, many.String()); len(got) != maxChoices {
+		t.Errorf("got %d choices, want at most %d", len(got), maxChoices)
+	}
+
+	for _, tt := range []struct {
+		src  models.OptionSource
+		out  string
+		want string
+	}{
+		{models.OptionSource{Command: "x", Format: models.SourceJSON}, "{", "解析输出失败"},
+		{models.OptionSource{Command: "x", JSONPath: "items"}, `{"other": []}`, "输出中没有 'items'"},
+	} {
+		if _, err := Choices(tt.src, tt.out); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Choices(%q) error = %v, want it to contain %q", tt.out, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,242 @@
+// Package output parses the standard output of a command according to its
+// `output` spec into a table or a set of key/value fields.
+package output
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+
+	"repo/shared-go-lib/models"
+)
+
+// Column types.
+const (
+	TypeString  = "string"
+	TypeNumber  = "number"
+	TypeBoolean = "boolean"
+	// TypeJSON marks cells holding nested objects or arrays, shown as JSON.
+	TypeJSON = "json"
+)
+
+// maxRows caps the rows of a table; the rest are dropped and Truncated is
+// set.
+const maxRows = 1000
+
+// Column is one column of a table.
+type Column struct {
+	Name string `json:"name"`
+	// Type is the common type of the non-empty cells, TypeString when they
+	// differ.
+	Type string `json:"type"`
+}
+
+// Field is one key/value pair.
+type Field struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+	Type  string `json:"type"`
+}
+
+// Result is the parsed output. A table has Columns and Rows, an object or a
+// single value has Fields.
+type Result struct {
+	Format    string     `json:"format"`
+	Columns   []Column   `json:"columns,omitempty"`
+	Rows      [][]string `json:"rows,omitempty"`
+	Truncated bool       `json:"truncated,omitempty"`
+	Fields    []Field    `json:"fields,omitempty"`
+	// Values are the named values of the spec's extract section.
+	Values map[string]string `json:"values,omitempty"`
+	Error  string            `json:"error,omitempty"`
+}
+
+// Parse parses stdout according to spec. Errors are reported in
+// Result.Error rather than failing the command, which has already run.
+func Parse(spec models.OutputSpec, stdout string) *Result {
+	res := &Result{Format: spec.Format}
+	data, err := decode(spec, stdout)
+	if err != nil {
+		res.Error = fmt.Sprintf("解析输出失败: %v", err)
+		return res
+	}
+
+	node, ok := lookup(data, spec.Rows)
+	if !ok {
+		res.Error = fmt.Sprintf("输出中没有 '%s'", spec.Rows)
+	} else {
+		present(res, node, spec.Columns)
+	}
+
+	var missing []string
+	for name, path := range spec.Extract {
+		v, ok := lookup(data, path)
+		if !ok {
+			missing = append(missing, path)
+			continue
+		}
+		if res.Values == nil {
+			res.Values = map[string]string{}
+		}
+		res.Values[name] = text(v)
+	}
+	if len(missing) > 0 && res.Error == "" {
+		sort.Strings(missing)
+		res.Error = fmt.Sprintf("输出中没有 %s", strings.Join(missing, ", "))
+	}
+	return res
+}
+
+// decode turns stdout into a tree. CSV and regex output become a list of
+// objects, one per row, so Rows, Columns and Extract work the same for all
+// formats.
+func decode(spec models.OutputSpec, stdout string) (interface{}, error) {
+	switch spec.Format {
+	case models.OutputJSON:
+		return decodeJSON(stdout)
+	case models.OutputCSV:
+		return decodeCSV(stdout, spec.Delimiter)
+	case models.OutputRegex:
+		return decodeRegex(stdout, spec.Pattern)
+	}
+	return nil, fmt.Errorf("不支持的输出格式: %s", spec.Format)
+}
+
+func decodeCSV(stdout, delimiter string) (interface{}, error) {
+	r := csv.NewReader(strings.NewReader(stdout))
+	if delimiter != "" {
+		r.Comma = []rune(delimiter)[0]
+	}
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+	r.TrimLeadingSpace = true
+
+	header, err := r.Read()
+	if err == io.EOF {
+		return []interface{}{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	rows := []interface{}{}
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		row := newObject()
+		for i, name := range header {
+			if i < len(record) {
+				row.set(name, record[i])
+			} else {
+				row.set(name, "")
+			}
+		}
+		rows = append(rows, row)
+	}
+}
+
+func decodeRegex(stdout, pattern string) (interface{}, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	rows := []interface{}{}
+	for _, m := range re.FindAllStringSubmatch(stdout, -1) {
+		row := newObject()
+		for i, name := range re.SubexpNames() {
+			if name != "" {
+				row.set(name, m[i])
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// present fills the table or fields of res from node.
+func present(res *Result, node interface{}, columns []string) {
+	switch v := node.(type) {
+	case []interface{}:
+		table(res, v, columns)
+	case *object:
+		keys := columns
+		if len(keys) == 0 {
+			keys = v.keys
+		}
+		for _, key := range keys {
+			value, _ := lookup(v, key)
+			res.Fields = append(res.Fields, Field{Key: key, Value: text(value), Type: orString(kind(value))})
+		}
+	default:
+		res.Fields = []Field{{Key: "value", Value: text(v), Type: orString(kind(v))}}
+	}
+}
+
+// table lays out a list as rows. Objects contribute a cell per column, in
+// the order keys first appear; scalars form a single "value" column.
+func table(res *Result, items []interface{}, columns []string) {
+	if len(items) > maxRows {
+		items = items[:maxRows]
+		res.Truncated = true
+	}
+	if len(columns) == 0 {
+		seen := map[string]bool{}
+		for _, item := range items {
+			obj, ok := item.(*object)
+			if !ok {
+				if !seen["value"] {
+					seen["value"] = true
+					columns = append(columns, "value")
+				}
+				continue
+			}
+			for _, key := range obj.keys {
+				if !seen[key] {
+					seen[key] = true
+					columns = append(columns, key)
+				}
+			}
+		}
+	}
+
+	kinds := make([]string, len(columns))
+	res.Rows = make([][]string, 0, len(items))
+	for _, item := range items {
+		row := make([]string, len(columns))
+		for i, col := range columns {
+			var v interface{}
+			if obj, ok := item.(*object); ok {
+				v, _ = lookup(obj, col)
+			} else if col == "value" {
+				v = item
+			}
+			row[i] = text(v)
+			switch k := kind(v); {
+			case k == "":
+			case kinds[i] == "":
+				kinds[i] = k
+			case kinds[i] != k:
+				kinds[i] = TypeString
+			}
+		}
+		res.Rows = append(res.Rows, row)
+	}
+	res.Columns = make([]Column, len(columns))
+	for i, col := range columns {
+		res.Columns[i] = Column{Name: col, Type: orString(kinds[i])}
+	}
+}
+
+func orString(k string) string {
+	if k == "" {
+		return TypeString
+	}
+	return k
+}
@@ -0,0 +1,126 @@
+package output
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"repo/shared-go-lib/models"
+)
+
+const ffprobe = `{
+  "streams": [
+    {"index": 0, "codec_name": "h264", "width": 1920, "tags": {"language": "und"}},
+    {"index": 1, "codec_name": "aac", "channels": 2, "default": true, "tags": {"language": "eng"}}
+  ],
+  "format": {"duration": "12.500000", "size": "1048576", "format_name": "mov,mp4"}
+}`
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		spec models.OutputSpec
+		out  string
+		want Result
+	}{
+		{"json rows with columns",
+			models.OutputSpec{Format: models.OutputJSON, Rows: "streams", Columns: []string{"codec_name", "tags.language", "width"}},
+			ffprobe,
+			Result{Format: "json",
+				Columns: []Column{{"codec_name", TypeString}, {"tags.language", TypeString}, {"width", TypeNumber}},
+				Rows:    [][]string{{"h264", "und", "1920"}, {"aac", "eng", ""}}}},
+		{"json columns in order of appearance",
+			models.OutputSpec{Format: models.OutputJSON, Rows: "streams"},
+			ffprobe,
+			Result{Format: "json",
+				Columns: []Column{{"index", TypeNumber}, {"codec_name", TypeString}, {"width", TypeNumber}, {"tags", TypeJSON}, {"channels", TypeNumber}, {"default", TypeBoolean}},
+				Rows: [][]string{
+					{"0", "h264", "1920", `{"language":"und"}`, "", ""},
+					{"1", "aac", "", `{"language":"eng"}`, "2", "true"},
+				}}},
+		{"json object as fields",
+			models.OutputSpec{Format: models.OutputJSON, Rows: "format"},
+			ffprobe,
+			Result{Format: "json", Fields: []Field{
+				{"duration", "12.500000", TypeNumber}, {"size", "1048576", TypeNumber}, {"format_name", "mov,mp4", TypeString},
+			}}},
+		{"json scalar",
+			models.OutputSpec{Format: models.OutputJSON, Rows: "format.duration"},
+			ffprobe,
+			Result{Format: "json", Fields: []Field{{"value", "12.500000", TypeNumber}}}},
+		{"json list of scalars",
+			models.OutputSpec{Format: models.OutputJSON},
+			`["a", 1]`,
+			Result{Format: "json", Columns: []Column{{"value", TypeString}}, Rows: [][]string{{"a"}, {"1"}}}},
+		{"extract",
+			models.OutputSpec{Format: models.OutputJSON, Rows: "format", Columns: []string{"size"},
+				Extract: map[string]string{"duration": "format.duration", "codec": "streams.0.codec_name"}},
+			ffprobe,
+			Result{Format: "json", Fields: []Field{{"size", "1048576", TypeNumber}},
+				Values: map[string]string{"duration": "12.500000", "codec": "h264"}}},
+		{"csv",
+			models.OutputSpec{Format: models.OutputCSV},
+			"name, size\na.mov, 10\n\"b, c.mov\",\n",
+			Result{Format: "csv", Columns: []Column{{"name", TypeString}, {"size", TypeNumber}},
+				Rows: [][]string{{"a.mov", "10"}, {"b, c.mov", ""}}}},
+		{"csv delimiter and extract",
+			models.OutputSpec{Format: models.OutputCSV, Delimiter: ";", Extract: map[string]string{"first": "0.name"}},
+			"name;size\na;1\n",
+			Result{Format: "csv", Columns: []Column{{"name", TypeString}, {"size", TypeNumber}},
+				Rows: [][]string{{"a", "1"}}, Values: map[string]string{"first": "a"}}},
+		{"empty csv",
+			models.OutputSpec{Format: models.OutputCSV},
+			"",
+			Result{Format: "csv", Rows: [][]string{}, Columns: []Column{}}},
+		{"regex",
+			models.OutputSpec{Format: models.OutputRegex, Pattern: `(?m)^(?P<file>\S+)\s+(?P<size>\d+)$`},
+			"a.mov 10\nnoise\nb.mov 20\n",
+			Result{Format: "regex", Columns: []Column{{"file", TypeString}, {"size", TypeNumber}},
+				Rows: [][]string{{"a.mov", "10"}, {"b.mov", "20"}}}},
+		{"invalid json",
+			models.OutputSpec{Format: models.OutputJSON},
+			"not json",
+			Result{Format: "json", Error: "解析输出失败"}},
+		{"missing rows",
+			models.OutputSpec{Format: models.OutputJSON, Rows: "chapters"},
+			ffprobe,
+			Result{Format: "json", Error: "输出中没有 'chapters'"}},
+		{"missing extract paths",
+			models.OutputSpec{Format: models.OutputJSON, Rows: "format.size", Extract: map[string]string{"a": "x.y", "b": "streams.5"}},
+			ffprobe,
+			Result{Format: "json", Fields: []Field{{"value", "1048576", TypeNumber}}, Error: "输出中没有 streams.5, x.y"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Parse(tt.spec, tt.out)
+			if tt.want.Error != "" && strings.Contains(got.Error, tt.want.Error) {
+				got.Error = tt.want.Error
+			}
+			if !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("Parse =\n%+v\nwant\n%+v", *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTruncatesRows(t *testing.T) {
+	var sb strings.Builder
+	sb.WriteString("n\n")
+	for i := 0; i < maxRows+10; i++ {
+		fmt.Fprintf(&sb, "%d\n", i)
+	}
+	got := Parse(models.OutputSpec{Format: models.OutputCSV}, sb.String())
+	if len(got.Rows) != maxRows || !got.Truncated || got.Rows[maxRows-1][0] != fmt.Sprint(maxRows-1) {
+		t.Errorf("Parse kept %d rows, truncated = %v", len(got.Rows), got.Truncated)
+	}
+}
+
+// Keys keep the order of the JSON document rather than being sorted.
+func TestParseKeepsKeyOrder(t *testing.T) {
+	got := Parse(models.OutputSpec{Format: models.OutputJSON}, `{"z": 1, "a": {"y": 2, "b": 3}}`)
+	want := []Field{{"z", "1", TypeNumber}, {"a", `{"y":2,"b":3}`, TypeJSON}}
+	if !reflect.DeepEqual(got.Fields, want) {
+		t.Errorf("Fields = %+v, want %+v", got.Fields, want)
+	}
+}
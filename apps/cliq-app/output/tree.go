@@ -0,0 +1,177 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// object is a JSON object that remembers the order of its keys, so tables
+// show columns in the order the tool printed them.
+type object struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func newObject() *object {
+	return &object{values: map[string]interface{}{}}
+}
+
+func (o *object) set(key string, v interface{}) {
+	if _, ok := o.values[key]; !ok {
+		o.keys = append(o.keys, key)
+	}
+	o.values[key] = v
+}
+
+// MarshalJSON encodes the object with its keys in their original order.
+func (o *object) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		k, _ := json.Marshal(key)
+		v, err := json.Marshal(o.values[key])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(k)
+		buf.WriteByte(':')
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// decodeJSON parses a single JSON value. Objects become *object and numbers
+// json.Number, so nothing is lost or reordered.
+func decodeJSON(text string) (interface{}, error) {
+	dec := json.NewDecoder(strings.NewReader(text))
+	dec.UseNumber()
+	v, err := decodeValue(dec)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, fmt.Errorf("JSON 之后还有多余的内容")
+	}
+	return v, nil
+}
+
+func decodeValue(dec *json.Decoder) (interface{}, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch t := tok.(type) {
+	case json.Delim:
+		switch t {
+		case '{':
+			obj := newObject()
+			for dec.More() {
+				keyTok, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+				v, err := decodeValue(dec)
+				if err != nil {
+					return nil, err
+				}
+				obj.set(keyTok.(string), v)
+			}
+			_, err := dec.Token() // '}'
+			return obj, err
+		case '[':
+			list := []interface{}{}
+			for dec.More() {
+				v, err := decodeValue(dec)
+				if err != nil {
+					return nil, err
+				}
+				list = append(list, v)
+			}
+			_, err := dec.Token() // ']'
+			return list, err
+		}
+		return nil, fmt.Errorf("意外的 %v", t)
+	default:
+		return tok, nil
+	}
+}
+
+// lookup follows a dotted path such as "streams.0.codec_name" through
+// objects and arrays. An empty path returns v itself.
+func lookup(v interface{}, path string) (interface{}, bool) {
+	if path == "" {
+		return v, true
+	}
+	for _, part := range strings.Split(path, ".") {
+		switch node := v.(type) {
+		case *object:
+			next, ok := node.values[part]
+			if !ok {
+				return nil, false
+			}
+			v = next
+		case []interface{}:
+			i, err := strconv.Atoi(part)
+			if err != nil || i < 0 || i >= len(node) {
+				return nil, false
+			}
+			v = node[i]
+		default:
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// text formats a value for display: scalars as is, objects and arrays as
+// compact JSON.
+func text(v interface{}) string {
+	switch x := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return x
+	case json.Number:
+		return x.String()
+	case bool:
+		return strconv.FormatBool(x)
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+// kind classifies a value for column typing. Strings that look like numbers
+// count as numbers: CSV and regex cells are always strings, and tools such
+// as ffprobe quote numbers in their JSON.
+func kind(v interface{}) string {
+	switch x := v.(type) {
+	case nil:
+		return ""
+	case json.Number:
+		return TypeNumber
+	case bool:
+		return TypeBoolean
+	case string:
+		if x == "" {
+			return ""
+		}
+		if _, err := strconv.ParseFloat(x, 64); err == nil {
+			return TypeNumber
+		}
+		return TypeString
+	case *object, []interface{}:
+		return TypeJSON
+	}
+	return TypeString
+}
@@ -0,0 +1,161 @@
+// Package presets persists the presets users save for the commands of a
+// template under the cliq config directory, one JSON file per template.
+package presets
+
+import (
+	"crypto/md5"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"repo/shared-go-lib/models"
+)
+
+// Command holds the user's presets of one command.
+type Command struct {
+	Presets []models.Preset `json:"presets"`
+	// Default is the preset filled in when the command is opened. nil falls
+	// back to the template's default preset; an empty string means none.
+	Default *string `json:"default,omitempty"`
+}
+
+// file is the content of a template's presets file.
+type file struct {
+	TemplateName string              `json:"template_name"`
+	Commands     map[string]*Command `json:"commands"`
+}
+
+// Store reads and writes preset files in a directory.
+type Store struct {
+	mu  sync.Mutex
+	dir string
+}
+
+// DefaultDir returns the presets directory next to fav_templates.
+func DefaultDir() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("获取用户配置目录失败: %w", err)
+	}
+	return filepath.Join(configDir, "cliq", "presets"), nil
+}
+
+// NewStore creates a store that keeps its files in dir.
+func NewStore(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+// Get returns the user's presets of a command.
+func (s *Store) Get(templateName, commandID string) (Command, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := s.read(templateName)
+	if err != nil {
+		return Command{}, err
+	}
+	if c := f.Commands[commandID]; c != nil {
+		return *c, nil
+	}
+	return Command{}, nil
+}
+
+// Update loads the presets of a command, lets fn change them and writes
+// the result back.
+func (s *Store) Update(templateName, commandID string, fn func(c *Command) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := s.read(templateName)
+	if err != nil {
+		return err
+	}
+	c := f.Commands[commandID]
+	if c == nil {
+		c = &Command{}
+		f.Commands[commandID] = c
+	}
+	if err := fn(c); err != nil {
+		return err
+	}
+	if len(c.Presets) == 0 && c.Default == nil {
+		delete(f.Commands, commandID)
+	}
+	return s.write(f)
+}
+
+// Rename moves the presets of a template to its new name, replacing any
+// presets stored under that name.
+func (s *Store) Rename(oldName, newName string) error {
+	if oldName == newName {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := s.read(oldName)
+	if err != nil || len(f.Commands) == 0 {
+		return err
+	}
+	f.TemplateName = newName
+	if err := s.write(f); err != nil {
+		return err
+	}
+	return s.remove(oldName)
+}
+
+// Remove deletes all presets of a template.
+func (s *Store) Remove(templateName string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.remove(templateName)
+}
+
+// path 与收藏模板一样使用模板名称的哈希作为文件名
+func (s *Store) path(templateName string) string {
+	return filepath.Join(s.dir, fmt.Sprintf("%x.json", md5.Sum([]byte(templateName))))
+}
+
+func (s *Store) read(templateName string) (*file, error) {
+	f := &file{TemplateName: templateName, Commands: map[string]*Command{}}
+	data, err := os.ReadFile(s.path(templateName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return f, nil
+		}
+		return nil, fmt.Errorf("读取预设失败: %w", err)
+	}
+	if err := json.Unmarshal(data, f); err != nil {
+		return nil, fmt.Errorf("解析预设失败 (%s): %w", templateName, err)
+	}
+	if f.Commands == nil {
+		f.Commands = map[string]*Command{}
+	}
+	return f, nil
+}
+
+func (s *Store) write(f *file) error {
+	if len(f.Commands) == 0 {
+		return s.remove(f.TemplateName)
+	}
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return fmt.Errorf("创建预设目录失败: %w", err)
+	}
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化预设失败: %w", err)
+	}
+	if err := os.WriteFile(s.path(f.TemplateName), data, 0644); err != nil {
+		return fmt.Errorf("写入预设失败: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) remove(templateName string) error {
+	if err := os.Remove(s.path(templateName)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("删除预设失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package presets
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"repo/shared-go-lib/models"
+)
+
+func add(name string, values map[string]interface{}) func(c *Command) error {
+	return func(c *Command) error {
+		c.Presets = append(c.Presets, models.Preset{Name: name, Values: values})
+		return nil
+	}
+}
+
+func TestStoreUpdateAndGet(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "presets")
+	s := NewStore(dir)
+	if got, err := s.Get("ffmpeg", "encode"); err != nil || !reflect.DeepEqual(got, Command{}) {
+		t.Fatalf("Get of a missing file = %+v, %v", got, err)
+	}
+
+	if err := s.Update("ffmpeg", "encode", add("web", map[string]interface{}{"crf": 28.0})); err != nil {
+		t.Fatal(err)
+	}
+	none := ""
+	if err := s.Update("ffmpeg", "encode", func(c *Command) error {
+		c.Default = &none
+		return add("archive", map[string]interface{}{"crf": 18.0})(c)
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Update("ffmpeg", "probe", add("quick", nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	// a new store reads what an earlier one wrote
+	s = NewStore(dir)
+	got, err := s.Get("ffmpeg", "encode")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Command{Presets: []models.Preset{
+		{Name: "web", Values: map[string]interface{}{"crf": 28.0}},
+		{Name: "archive", Values: map[string]interface{}{"crf": 18.0}},
+	}, Default: &none}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Get = %+v, want %+v", got, want)
+	}
+	if got, _ := s.Get("other", "encode"); len(got.Presets) != 0 {
+		t.Errorf("presets leaked to another template: %+v", got)
+	}
+}
+
+func TestStoreUpdateError(t *testing.T) {
+	s := NewStore(t.TempDir())
+	if err := s.Update("ffmpeg", "encode", add("web", nil)); err != nil {
+		t.Fatal(err)
+	}
+	boom := errors.New("boom")
+	err := s.Update("ffmpeg", "encode", func(c *Command) error {
+		c.Presets = nil
+		return boom
+	})
+	if err != boom {
+		t.Errorf("Update error = %v, want %v", err, boom)
+	}
+	if got, _ := s.Get("ffmpeg", "encode"); len(got.Presets) != 1 {
+		t.Errorf("a failed Update was written: %+v", got)
+	}
+}
+
+// Commands without presets are dropped, and so is the file of a template
+// without any.
+func TestStoreRemovesEmptyFiles(t *testing.T) {
+	dir := t.TempDir()
+	s := NewStore(dir)
+	if err := s.Update("ffmpeg", "encode", add("web", nil)); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Update("ffmpeg", "encode", func(c *Command) error {
+		c.Presets = nil
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if files, _ := os.ReadDir(dir); len(files) != 0 {
+		t.Errorf("%d files left after the last preset was removed", len(files))
+	}
+}
+
+func TestStoreRenameAndRemove(t *testing.T) {
+	s := NewStore(t.TempDir())
+	for _, name := range []string{"old", "new"} {
+		if err := s.Update(name, "encode", add(name+" preset", nil)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Rename("old", "new"); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := s.Get("new", "encode")
+	if len(got.Presets) != 1 || got.Presets[0].Name != "old preset" {
+		t.Errorf("presets after Rename = %+v", got)
+	}
+	if got, _ := s.Get("old", "encode"); len(got.Presets) != 0 {
+		t.Errorf("old presets kept after Rename = %+v", got)
+	}
+	if err := s.Rename("missing", "new"); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := s.Get("new", "encode"); len(got.Presets) != 1 {
+		t.Errorf("Rename of a template without presets replaced them: %+v", got)
+	}
+
+	if err := s.Remove("new"); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := s.Get("new", "encode"); len(got.Presets) != 0 {
+		t.Errorf("presets after Remove = %+v", got)
+	}
+	if err := s.Remove("new"); err != nil {
+		t.Errorf("second Remove = %v", err)
+	}
+}
+
+func TestStoreCorruptFile(t *testing.T) {
+	s := NewStore(t.TempDir())
+	if err := os.WriteFile(s.path("ffmpeg"), []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Get("ffmpeg", "encode"); err == nil || !strings.Contains(err.Error(), "解析预设失败 (ffmpeg)") {
+		t.Errorf("Get error = %v", err)
+	}
+}
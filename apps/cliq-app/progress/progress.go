@@ -0,0 +1,138 @@
+// Package progress derives the progress of a running command from its
+// output lines, according to the command's `progress` spec.
+package progress
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"repo/shared-go-lib/models"
+)
+
+// Update is the progress read from one output line.
+type Update struct {
+	Percent float64
+	// Current and Total are set when the spec reports amounts rather than
+	// a percentage, e.g. seconds of media processed out of its duration.
+	Current float64
+	Total   float64
+}
+
+// Tracker reads progress from the output lines of one job. It keeps the
+// total across lines, so it must not be shared between jobs or used
+// concurrently.
+type Tracker struct {
+	pattern      *regexp.Regexp
+	totalPattern *regexp.Regexp
+	total        float64
+}
+
+// NewTracker compiles the patterns of spec.
+func NewTracker(spec models.ProgressSpec) (*Tracker, error) {
+	pattern, err := regexp.Compile(spec.Pattern)
+	if err != nil {
+		return nil, fmt.Errorf("无效的进度正则: %w", err)
+	}
+	t := &Tracker{pattern: pattern}
+	if spec.TotalPattern != "" {
+		if t.totalPattern, err = regexp.Compile(spec.TotalPattern); err != nil {
+			return nil, fmt.Errorf("无效的总量正则: %w", err)
+		}
+	}
+	return t, nil
+}
+
+// Feed inspects one output line and reports whether it carried progress.
+func (t *Tracker) Feed(line string) (Update, bool) {
+	if t.totalPattern != nil {
+		if v, ok := group(t.totalPattern, line, "total"); ok {
+			if total, ok := ParseAmount(v); ok && total > 0 {
+				t.total = total
+			}
+		}
+	}
+
+	m := t.pattern.FindStringSubmatch(line)
+	if m == nil {
+		return Update{}, false
+	}
+	named := map[string]string{}
+	for i, name := range t.pattern.SubexpNames() {
+		if name != "" && m[i] != "" {
+			named[name] = m[i]
+		}
+	}
+
+	if v, ok := named["percent"]; ok {
+		percent, ok := ParseAmount(v)
+		return Update{Percent: clamp(percent)}, ok
+	}
+	if v, ok := named["current"]; ok {
+		current, ok := ParseAmount(v)
+		if !ok {
+			return Update{}, false
+		}
+		total := t.total
+		if v, found := named["total"]; found {
+			if n, ok := ParseAmount(v); ok && n > 0 {
+				total = n
+			}
+		}
+		if total <= 0 {
+			return Update{}, false // 总量未知时无法计算百分比
+		}
+		return Update{Percent: clamp(current / total * 100), Current: current, Total: total}, true
+	}
+	if len(m) > 1 {
+		percent, ok := ParseAmount(m[1])
+		return Update{Percent: clamp(percent)}, ok
+	}
+	return Update{}, false
+}
+
+// group returns the named group of the first match, or the first group when
+// the pattern has no group of that name.
+func group(re *regexp.Regexp, line, name string) (string, bool) {
+	m := re.FindStringSubmatch(line)
+	if m == nil {
+		return "", false
+	}
+	if i := re.SubexpIndex(name); i > 0 {
+		return m[i], m[i] != ""
+	}
+	if len(m) > 1 {
+		return m[1], m[1] != ""
+	}
+	return "", false
+}
+
+// ParseAmount parses a number ("42", "42.5%") or a clock time
+// ("01:02:03.45", "02:03") as seconds.
+func ParseAmount(s string) (float64, bool) {
+	s = strings.TrimSuffix(strings.TrimSpace(s), "%")
+	if !strings.Contains(s, ":") {
+		n, err := strconv.ParseFloat(s, 64)
+		return n, err == nil
+	}
+	var seconds float64
+	for _, part := range strings.Split(s, ":") {
+		n, err := strconv.ParseFloat(part, 64)
+		if err != nil || n < 0 {
+			return 0, false
+		}
+		seconds = seconds*60 + n
+	}
+	return seconds, true
+}
+
+func clamp(percent float64) float64 {
+	switch {
+	case percent < 0:
+		return 0
+	case percent > 100:
+		return 100
+	}
+	return percent
+}
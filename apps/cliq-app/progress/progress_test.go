@@ -0,0 +1,104 @@
+package progress
+
+import (
+	"strings"
+	"testing"
+
+	"repo/shared-go-lib/models"
+)
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		s    string
+		want float64
+		ok   bool
+	}{
+		{"42", 42, true},
+		{" 42.5% ", 42.5, true},
+		{"01:02:03.50", 3723.5, true},
+		{"02:03", 123, true},
+		{"N/A", 0, false},
+		{"00:-1:00", 0, false},
+		{"1:x", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := ParseAmount(tt.s)
+		if ok != tt.ok || ok && got != tt.want {
+			t.Errorf("ParseAmount(%q) = %v, %v, want %v, %v", tt.s, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+type step struct {
+	line string
+	ok   bool
+	want Update
+}
+
+func TestTracker(t *testing.T) {
+	tests := []struct {
+		name  string
+		spec  models.ProgressSpec
+		steps []step
+	}{
+		{"named percent", models.ProgressSpec{Pattern: `(?P<percent>[\d.]+)%`}, []step{
+			{"downloading 12.5% of 3MB", true, Update{Percent: 12.5}},
+			{"no progress here", false, Update{}},
+			{"150%", true, Update{Percent: 100}},
+		}},
+		{"first group is the percent", models.ProgressSpec{Pattern: `^\[(\d+)/100\]`}, []step{
+			{"[40/100] compiling", true, Update{Percent: 40}},
+			{"[x/100]", false, Update{}},
+		}},
+		{"current and total on one line", models.ProgressSpec{Pattern: `(?P<current>\d+)/(?P<total>\d+) files`}, []step{
+			{"copied 3/12 files", true, Update{Percent: 25, Current: 3, Total: 12}},
+			{"copied 3/0 files", false, Update{}},
+		}},
+		{"ffmpeg duration and time", models.ProgressSpec{
+			Pattern:      `time=(?P<current>\d+:\d+:[\d.]+)`,
+			TotalPattern: `Duration: (?P<total>[^,]+),`,
+		}, []step{
+			{"frame=1 time=00:00:01.00", false, Update{}}, // the total is not known yet
+			{"  Duration: 00:01:40.00, start: 0.000000", false, Update{}},
+			{"frame=250 fps=50 time=00:00:25.00 bitrate=1k", true, Update{Percent: 25, Current: 25, Total: 100}},
+			{"frame=500 fps=50 time=00:01:40.00 bitrate=1k", true, Update{Percent: 100, Current: 100, Total: 100}},
+		}},
+		{"total from its first group", models.ProgressSpec{Pattern: `done (?P<current>\d+)`, TotalPattern: `of (\d+)`}, []step{
+			{"processing of 8 items", false, Update{}},
+			{"done 2", true, Update{Percent: 25, Current: 2, Total: 8}},
+			{"of 0", false, Update{}}, // a zero total is ignored
+			{"done 4", true, Update{Percent: 50, Current: 4, Total: 8}},
+		}},
+		{"no groups", models.ProgressSpec{Pattern: `working`}, []step{
+			{"working", false, Update{}},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker, err := NewTracker(tt.spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, s := range tt.steps {
+				got, ok := tracker.Feed(s.line)
+				if ok != s.ok || got != s.want {
+					t.Errorf("Feed(%q) = %+v, %v, want %+v, %v", s.line, got, ok, s.want, s.ok)
+				}
+			}
+		})
+	}
+}
+
+func TestNewTrackerErrors(t *testing.T) {
+	for _, tt := range []struct {
+		spec models.ProgressSpec
+		want string
+	}{
+		{models.ProgressSpec{Pattern: `(`}, "无效的进度正则"},
+		{models.ProgressSpec{Pattern: `x`, TotalPattern: `[`}, "无效的总量正则"},
+	} {
+		if _, err := NewTracker(tt.spec); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("NewTracker(%+v) error = %v, want it to contain %q", tt.spec, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+// Package recent remembers the values entered for the variables of a
+// command, so the form can suggest them again. Values are kept under the
+// cliq config directory, one JSON file per template.
+package recent
+
+import (
+	"crypto/md5"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// maxValues is the number of values kept per variable; the lowest
+	// ranked are dropped.
+	maxValues = 20
+	// MaxValueLength is the longest value that is remembered.
+	MaxValueLength = 1024
+	// halfLife is the age at which a use counts half as much as a new one.
+	halfLife = 7 * 24 * time.Hour
+)
+
+// Entry is a remembered value of a variable.
+type Entry struct {
+	Value    string    `json:"value"`
+	Count    int       `json:"count"`
+	LastUsed time.Time `json:"last_used"`
+}
+
+// score ranks entries by how often and how recently they were used: every
+// use counts, but older uses count less.
+func (e Entry) score(now time.Time) float64 {
+	age := now.Sub(e.LastUsed)
+	if age < 0 {
+		age = 0
+	}
+	return float64(e.Count) * math.Pow(0.5, float64(age)/float64(halfLife))
+}
+
+// file is the content of a template's values file: command ID ->
+// variable name -> entries.
+type file struct {
+	TemplateName string                        `json:"template_name"`
+	Commands     map[string]map[string][]Entry `json:"commands"`
+}
+
+// Store reads and writes remembered values in a directory.
+type Store struct {
+	mu  sync.Mutex
+	dir string
+	now func() time.Time
+}
+
+// DefaultDir returns the directory for remembered values next to
+// fav_templates.
+func DefaultDir() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("获取用户配置目录失败: %w", err)
+	}
+	return filepath.Join(configDir, "cliq", "recent_values"), nil
+}
+
+// NewStore creates a store that keeps its files in dir.
+func NewStore(dir string) *Store {
+	return &Store{dir: dir, now: time.Now}
+}
+
+// Record remembers the values entered for a command, keyed by variable
+// name. Empty values and values longer than MaxValueLength are ignored.
+func (s *Store) Record(templateName, commandID string, values map[string]string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := s.read(templateName)
+	if err != nil {
+		return err
+	}
+	vars := f.Commands[commandID]
+	if vars == nil {
+		vars = map[string][]Entry{}
+		f.Commands[commandID] = vars
+	}
+	now := s.now()
+	changed := false
+	for name, value := range values {
+		if strings.TrimSpace(value) == "" || len(value) > MaxValueLength {
+			continue
+		}
+		vars[name] = add(vars[name], value, now)
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+	return s.write(f)
+}
+
+// Suggest returns the remembered values of a variable that contain query
+// (case-insensitively), best ranked first. limit <= 0 returns all of them.
+func (s *Store) Suggest(templateName, commandID, variable, query string, limit int) ([]Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := s.read(templateName)
+	if err != nil {
+		return nil, err
+	}
+	query = strings.ToLower(query)
+	out := []Entry{}
+	for _, e := range f.Commands[commandID][variable] {
+		if strings.Contains(strings.ToLower(e.Value), query) {
+			out = append(out, e)
+		}
+	}
+	rank(out, s.now())
+	if limit > 0 && len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+// Clear forgets all remembered values.
+func (s *Store) Clear() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.RemoveAll(s.dir); err != nil {
+		return fmt.Errorf("清除输入记录失败: %w", err)
+	}
+	return nil
+}
+
+// add counts a use of value and keeps the maxValues best ranked entries.
+func add(entries []Entry, value string, now time.Time) []Entry {
+	found := false
+	for i := range entries {
+		if entries[i].Value == value {
+			entries[i].Count++
+			entries[i].LastUsed = now
+			found = true
+		}
+	}
+	if !found {
+		entries = append(entries, Entry{Value: value, Count: 1, LastUsed: now})
+	}
+	rank(entries, now)
+	if len(entries) > maxValues {
+		entries = entries[:maxValues]
+	}
+	return entries
+}
+
+func rank(entries []Entry, now time.Time) {
+	sort.SliceStable(entries, func(a, b int) bool {
+		sa, sb := entries[a].score(now), entries[b].score(now)
+		if sa != sb {
+			return sa > sb
+		}
+		return entries[a].LastUsed.After(entries[b].LastUsed)
+	})
+}
+
+// path 与收藏模板一样使用模板名称的哈希作为文件名
+func (s *Store) path(templateName string) string {
+	return filepath.Join(s.dir, fmt.Sprintf("%x.json", md5.Sum([]byte(templateName))))
+}
+
+func (s *Store) read(templateName string) (*file, error) {
+	f := &file{TemplateName: templateName, Commands: map[string]map[string][]Entry{}}
+	data, err := os.ReadFile(s.path(templateName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return f, nil
+		}
+		return nil, fmt.Errorf("读取输入记录失败: %w", err)
+	}
+	if err := json.Unmarshal(data, f); err != nil {
+		return nil, fmt.Errorf("解析输入记录失败 (%s): %w", templateName, err)
+	}
+	if f.Commands == nil {
+		f.Commands = map[string]map[string][]Entry{}
+	}
+	return f, nil
+}
+
+func (s *Store) write(f *file) error {
+	if err := os.MkdirAll(s.dir, 0755); err != nil {
+		return fmt.Errorf("创建输入记录目录失败: %w", err)
+	}
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化输入记录失败: %w", err)
+	}
+	if err := os.WriteFile(s.path(f.TemplateName), data, 0644); err != nil {
+		return fmt.Errorf("写入输入记录失败: %w", err)
+	}
+	return nil
+}
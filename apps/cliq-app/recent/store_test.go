@@ -0,0 +1,148 @@
+package recent
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var base = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+// clockStore returns a store whose clock is set with the returned function.
+func clockStore(dir string) (*Store, func(time.Duration)) {
+	s := NewStore(dir)
+	now := base
+	s.now = func() time.Time { return now }
+	return s, func(d time.Duration) { now = base.Add(d) }
+}
+
+func values(entries []Entry) []string {
+	var out []string
+	for _, e := range entries {
+		out = append(out, e.Value)
+	}
+	return out
+}
+
+func TestRecordAndSuggest(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "recent")
+	s, at := clockStore(dir)
+	record := func(value string, d time.Duration) {
+		t.Helper()
+		at(d)
+		if err := s.Record("ffmpeg", "encode", map[string]string{"input": value, "empty": " "}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	record("old.mov", 0)
+	record("old.mov", time.Hour)
+	record("old.mov", 2*time.Hour)
+	record("Clip.mov", 20*24*time.Hour)
+	record("new.mp4", 21*24*time.Hour)
+	record("new.mp4", 21*24*time.Hour+time.Minute)
+
+	// three uses three weeks ago rank below two uses now
+	tests := []struct {
+		query string
+		limit int
+		want  []string
+	}{
+		{"", 0, []string{"new.mp4", "Clip.mov", "old.mov"}},
+		{"", 2, []string{"new.mp4", "Clip.mov"}},
+		{"MOV", 0, []string{"Clip.mov", "old.mov"}},
+		{"clip", 0, []string{"Clip.mov"}},
+		{"x", 0, nil},
+	}
+	for _, tt := range tests {
+		got, err := s.Suggest("ffmpeg", "encode", "input", tt.query, tt.limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(values(got), tt.want) {
+			t.Errorf("Suggest(%q, %d) = %q, want %q", tt.query, tt.limit, values(got), tt.want)
+		}
+	}
+
+	// a new store reads what an earlier one wrote
+	s2, _ := clockStore(dir)
+	got, err := s2.Suggest("ffmpeg", "encode", "input", "old", 0)
+	if err != nil || len(got) != 1 || got[0].Count != 3 || !got[0].LastUsed.Equal(base.Add(2*time.Hour)) {
+		t.Errorf("Suggest from a new store = %+v, %v", got, err)
+	}
+	if got, _ := s2.Suggest("ffmpeg", "encode", "empty", "", 0); len(got) != 0 {
+		t.Errorf("blank values were remembered: %+v", got)
+	}
+	if got, _ := s2.Suggest("ffmpeg", "probe", "input", "", 0); len(got) != 0 {
+		t.Errorf("values leaked to another command: %+v", got)
+	}
+}
+
+func TestRecordKeepsBestValues(t *testing.T) {
+	s, at := clockStore(t.TempDir())
+	if err := s.Record("t", "c", map[string]string{"v": "favourite"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Record("t", "c", map[string]string{"v": "favourite"}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < maxValues+5; i++ {
+		at(time.Duration(i) * time.Minute)
+		if err := s.Record("t", "c", map[string]string{"v": fmt.Sprintf("v%02d", i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err := s.Suggest("t", "c", "v", "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != maxValues || got[0].Value != "favourite" || got[1].Value != fmt.Sprintf("v%02d", maxValues+4) {
+		t.Errorf("Suggest = %q", values(got))
+	}
+	for _, e := range got {
+		if e.Value == "v00" {
+			t.Errorf("the oldest value was kept")
+		}
+	}
+
+	long := strings.Repeat("x", MaxValueLength+1)
+	if err := s.Record("t", "c", map[string]string{"v": long}); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := s.Suggest("t", "c", "v", "xx", 0); len(got) != 0 {
+		t.Errorf("a value over MaxValueLength was remembered")
+	}
+}
+
+func TestClear(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "recent")
+	s := NewStore(dir)
+	if err := s.Record("t", "c", map[string]string{"v": "a"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Clear(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("directory kept after Clear: %v", err)
+	}
+	if got, err := s.Suggest("t", "c", "v", "", 0); err != nil || len(got) != 0 {
+		t.Errorf("Suggest after Clear = %+v, %v", got, err)
+	}
+}
+
+func TestCorruptFile(t *testing.T) {
+	s := NewStore(t.TempDir())
+	if err := os.WriteFile(s.path("t"), []byte("["), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Suggest("t", "c", "v", "", 0); err == nil || !strings.Contains(err.Error(), "解析输入记录失败 (t)") {
+		t.Errorf("Suggest error = %v", err)
+	}
+	if err := s.Record("t", "c", map[string]string{"v": "a"}); err == nil {
+		t.Error("Record overwrote a corrupt file")
+	}
+}
@@ -43,7 +43,9 @@ func (a *App) ImportTemplate() (*models.TemplateFile, error) {
 	}
 
 	// 设置应用的模板
-	a.setTemplate(template)
+	if err := a.setTemplate(template); err != nil {
+		return nil, err
+	}
 
 	return template, nil
 }
@@ -81,7 +83,9 @@ func (a *App) ImportTemplateFromURL(url string) (*models.TemplateFile, error) {
 	}
 
 	// 设置应用的模板
-	a.setTemplate(template)
+	if err := a.setTemplate(template); err != nil {
+		return nil, err
+	}
 
 	return template, nil
 }
@@ -116,7 +120,13 @@ func (a *App) parseAndValidateTemplateFromData(data []byte) (*models.TemplateFil
 	return &template, nil
 }
 
-// setTemplate 设置应用的当前模板
-func (a *App) setTemplate(template *models.TemplateFile) {
-	a.template = template
+// setTemplate 设置应用的当前模板. 执行命令使用展开了 extends / include 的副本,
+// 返回给前端的模板保持原样, 以便收藏和编辑
+func (a *App) setTemplate(template *models.TemplateFile) error {
+	resolved, err := a.templateService.ResolveTemplate(template)
+	if err != nil {
+		return err
+	}
+	a.template = resolved
+	return nil
 }
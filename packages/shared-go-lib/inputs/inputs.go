@@ -0,0 +1,290 @@
+// Package inputs checks the values submitted for a command against the
+// constraints of its variable definitions before the command runs.
+package inputs
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"repo/shared-go-lib/models"
+	"repo/shared-go-lib/render"
+)
+
+// Errors maps variable names to the problem with their value. The messages
+// are meant to be shown next to the form field.
+type Errors map[string]string
+
+// Error is returned when submitted values violate their constraints.
+type Error struct {
+	Fields Errors
+	// labels keeps the variables in definition order for the message.
+	labels []string
+}
+
+func (e *Error) Error() string {
+	return "参数校验失败: " + strings.Join(e.labels, "; ")
+}
+
+// Check validates values against the variables of cmd: required fields
+// (including `required_if`), numeric ranges, `pattern`, select membership,
+// dates, key_value pairs and that files and directories exist where their
+// options ask for it. Relative paths are resolved against dir when it is
+// set. Empty optional values are not checked, and neither is membership of
+// selects whose choices come from `options_from`. Variables hidden by their
+// `show_if` are skipped.
+func Check(cmd models.Command, values map[string]interface{}, dir string) Errors {
+	errs := Errors{}
+	// 条件无效时按无条件处理, 模板校验会报告
+	states, _ := render.FieldStates(cmd.Variables, values)
+	for _, def := range cmd.Variables {
+		if st, ok := states[def.Name]; ok {
+			if st.Hidden {
+				continue
+			}
+			def.Required = st.Required
+		}
+		if msg := checkValue(def, values[def.Name], dir); msg != "" {
+			errs[def.Name] = msg
+		}
+	}
+	return errs
+}
+
+// Validate runs Check and returns an *Error when any value is invalid.
+func Validate(cmd models.Command, values map[string]interface{}, dir string) error {
+	errs := Check(cmd, values, dir)
+	if len(errs) == 0 {
+		return nil
+	}
+	e := &Error{Fields: errs}
+	for _, def := range cmd.Variables {
+		if msg, ok := errs[def.Name]; ok {
+			label := def.Label
+			if label == "" {
+				label = def.Name
+			}
+			e.labels = append(e.labels, label+": "+msg)
+		}
+	}
+	return e
+}
+
+func checkValue(def models.VariableDefinition, v interface{}, dir string) string {
+	if def.Type == models.VarTypeBoolean {
+		return "" // 未勾选也是有效的值
+	}
+	if def.Type == models.VarTypeKeyValue && len(render.KeyValuePairs(v)) == 0 && checkPairs(v) == "" {
+		v = nil // 只有空行
+	}
+	if empty(v) {
+		if def.Required {
+			return "必填"
+		}
+		return ""
+	}
+
+	if _, err := models.DecodeOptions(def); err != nil {
+		return fmt.Sprintf("模板中的选项无效: %v", err)
+	}
+	switch def.Type {
+	case models.VarTypeNumber:
+		n, ok := ToNumber(v)
+		if !ok {
+			return "必须是数字"
+		}
+		opts := def.NumberOptions()
+		if opts.Min != nil && n < *opts.Min {
+			return fmt.Sprintf("不能小于 %s", formatNumber(*opts.Min))
+		}
+		if opts.Max != nil && n > *opts.Max {
+			return fmt.Sprintf("不能大于 %s", formatNumber(*opts.Max))
+		}
+	case models.VarTypeSelect:
+		if choices := def.SelectOptions().Values(); len(choices) > 0 && !contains(choices, text(v)) {
+			return fmt.Sprintf("必须是以下选项之一: %s", strings.Join(choices, ", "))
+		}
+	case models.VarTypeMultiSelect:
+		choices := (models.SelectOptions{Options: def.MultiSelectOptions().Options}).Values()
+		for _, item := range list(v) {
+			if len(choices) > 0 && !contains(choices, text(item)) {
+				return fmt.Sprintf("'%s' 不是可选项, 可选: %s", text(item), strings.Join(choices, ", "))
+			}
+		}
+	case models.VarTypeDate, models.VarTypeDatetime:
+		if _, ok := render.ParseDate(text(v)); !ok {
+			return "日期格式不正确"
+		}
+	case models.VarTypeKeyValue:
+		if msg := checkPairs(v); msg != "" {
+			return msg
+		}
+	}
+
+	if pattern := def.Pattern(); pattern != "" {
+		re, err := CompilePattern(pattern)
+		if err != nil {
+			return fmt.Sprintf("模板中的 pattern 无效: %v", err)
+		}
+		if !re.MatchString(text(v)) {
+			return fmt.Sprintf("格式不正确, 应匹配 %s", pattern)
+		}
+	}
+
+	switch def.Type {
+	case models.VarTypeFileInput, models.VarTypeFileOutput:
+		if def.FileOptions().Exists(def.Type) {
+			return checkPath(text(v), dir, false)
+		}
+	case models.VarTypeFileList:
+		if must := def.FileListOptions().MustExist; must == nil || *must {
+			for _, item := range list(v) {
+				if msg := checkPath(text(item), dir, false); msg != "" {
+					return msg
+				}
+			}
+		}
+	case models.VarTypeDirectory:
+		if must := def.DirectoryOptions().MustExist; must == nil || *must {
+			return checkPath(text(v), dir, true)
+		}
+	}
+	return ""
+}
+
+// checkPath reports a missing file or directory. Relative paths are
+// resolved against dir.
+func checkPath(path, dir string, isDir bool) string {
+	path = render.ExpandHome(path)
+	if dir != "" && !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	info, err := os.Stat(path)
+	switch {
+	case isDir && (err != nil || !info.IsDir()):
+		return fmt.Sprintf("目录不存在: %s", path)
+	case err != nil:
+		return fmt.Sprintf("文件不存在: %s", path)
+	}
+	return ""
+}
+
+// checkPairs reports key_value entries that have a value but no key, and
+// keys that appear twice.
+func checkPairs(v interface{}) string {
+	items, ok := v.([]interface{})
+	if !ok {
+		return ""
+	}
+	seen := map[string]bool{}
+	for _, item := range items {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			return "格式不正确, 应为键值对列表"
+		}
+		key := strings.TrimSpace(text(m["key"]))
+		if key == "" {
+			if !empty(m["value"]) {
+				return "键不能为空"
+			}
+			continue
+		}
+		if seen[key] {
+			return fmt.Sprintf("键重复: %s", key)
+		}
+		seen[key] = true
+	}
+	return ""
+}
+
+// list returns the elements of a list value; a single value counts as a
+// list of one.
+func list(v interface{}) []interface{} {
+	switch x := v.(type) {
+	case []interface{}:
+		return x
+	case []string:
+		out := make([]interface{}, len(x))
+		for i, s := range x {
+			out[i] = s
+		}
+		return out
+	}
+	return []interface{}{v}
+}
+
+// CompilePattern compiles a variable's `pattern` option, which must match
+// the whole value.
+func CompilePattern(pattern string) (*regexp.Regexp, error) {
+	return regexp.Compile(`^(?:` + pattern + `)$`)
+}
+
+// ToNumber converts the number representations found in YAML, JSON and
+// form values.
+func ToNumber(v interface{}) (float64, bool) {
+	switch x := v.(type) {
+	case float64:
+		return x, true
+	case float32:
+		return float64(x), true
+	case int:
+		return float64(x), true
+	case int64:
+		return float64(x), true
+	case uint64:
+		return float64(x), true
+	case json.Number:
+		n, err := x.Float64()
+		return n, err == nil
+	case string:
+		n, err := strconv.ParseFloat(strings.TrimSpace(x), 64)
+		return n, err == nil
+	}
+	return 0, false
+}
+
+func formatNumber(n float64) string {
+	return strconv.FormatFloat(n, 'f', -1, 64)
+}
+
+func text(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	if s, ok := v.(string); ok {
+		return s
+	}
+	if n, ok := ToNumber(v); ok {
+		return formatNumber(n)
+	}
+	return fmt.Sprint(v)
+}
+
+func empty(v interface{}) bool {
+	switch x := v.(type) {
+	case nil:
+		return true
+	case string:
+		return strings.TrimSpace(x) == ""
+	case []interface{}:
+		return len(x) == 0
+	case []string:
+		return len(x) == 0
+	case map[string]interface{}:
+		return len(x) == 0
+	}
+	return false
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,163 @@
+package inputs
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"repo/shared-go-lib/models"
+)
+
+func variable(name, typ string, required bool, options map[string]interface{}) models.VariableDefinition {
+	return models.VariableDefinition{Name: name, Type: typ, Required: required, Options: options}
+}
+
+func TestCheck(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "in.mov"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name  string
+		def   models.VariableDefinition
+		value interface{}
+		want  string
+	}{
+		{"required missing", variable("v", models.VarTypeText, true, nil), nil, "必填"},
+		{"required blank", variable("v", models.VarTypeText, true, nil), "  ", "必填"},
+		{"optional empty skips the pattern", variable("v", models.VarTypeText, false, map[string]interface{}{"pattern": `\d+`}), "", ""},
+		{"unchecked boolean", variable("v", models.VarTypeBoolean, true, nil), false, ""},
+		{"pattern matches", variable("v", models.VarTypeText, true, map[string]interface{}{"pattern": `\d+x\d+`}), "1920x1080", ""},
+		{"pattern is anchored", variable("v", models.VarTypeText, true, map[string]interface{}{"pattern": `\d+`}), "12a", `格式不正确, 应匹配 \d+`},
+		{"number", variable("v", models.VarTypeNumber, true, map[string]interface{}{"min": 0, "max": 51}), 23.0, ""},
+		{"number as text", variable("v", models.VarTypeNumber, true, map[string]interface{}{"max": 51}), " 51 ", ""},
+		{"not a number", variable("v", models.VarTypeNumber, true, nil), "fast", "必须是数字"},
+		{"below min", variable("v", models.VarTypeNumber, true, map[string]interface{}{"min": 0.5}), 0, "不能小于 0.5"},
+		{"above max", variable("v", models.VarTypeNumber, true, map[string]interface{}{"max": 51}), 52, "不能大于 51"},
+		{"select", variable("v", models.VarTypeSelect, true, map[string]interface{}{"options": []interface{}{"fast", "slow"}}), "slow", ""},
+		{"select outside the options", variable("v", models.VarTypeSelect, true, map[string]interface{}{"options": []interface{}{"fast", "slow"}}), "medium", "必须是以下选项之一: fast, slow"},
+		{"select without options", variable("v", models.VarTypeSelect, true, nil), "anything", ""},
+		{"input file relative to dir", variable("v", models.VarTypeFileInput, true, nil), "in.mov", ""},
+		{"missing input file", variable("v", models.VarTypeFileInput, true, nil), "gone.mov", "文件不存在: " + filepath.Join(dir, "gone.mov")},
+		{"output file need not exist", variable("v", models.VarTypeFileOutput, true, nil), "out.mp4", ""},
+		{"must_exist on an output file", variable("v", models.VarTypeFileOutput, true, map[string]interface{}{"must_exist": true}), "out.mp4", "文件不存在: " + filepath.Join(dir, "out.mp4")},
+		{"invalid options", variable("v", models.VarTypeNumber, true, map[string]interface{}{"max": "high"}), 1, "模板中的选项无效"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := Check(models.Command{Variables: []models.VariableDefinition{tt.def}}, map[string]interface{}{"v": tt.value}, dir)
+			if got := errs["v"]; (got == "") != (tt.want == "") || !strings.HasPrefix(got, tt.want) {
+				t.Errorf("Check(%v) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckTypes(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.wav", "sub/b.wav"} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	choices := map[string]interface{}{"options": []interface{}{"h264", map[string]interface{}{"value": "hevc", "label": "H.265"}}}
+	pair := func(key, value string) interface{} {
+		return map[string]interface{}{"key": key, "value": value}
+	}
+	tests := []struct {
+		name  string
+		def   models.VariableDefinition
+		value interface{}
+		want  string
+	}{
+		{"multi_select", variable("v", models.VarTypeMultiSelect, true, choices), []interface{}{"h264", "hevc"}, ""},
+		{"multi_select outside the options", variable("v", models.VarTypeMultiSelect, true, choices), []interface{}{"vp9"}, "'vp9' 不是可选项, 可选: h264, hevc"},
+		{"empty multi_select", variable("v", models.VarTypeMultiSelect, true, choices), []interface{}{}, "必填"},
+		{"file_list", variable("v", models.VarTypeFileList, true, nil), []interface{}{"a.wav", "sub/b.wav"}, ""},
+		{"file_list with a missing file", variable("v", models.VarTypeFileList, true, nil), []interface{}{"a.wav", "c.wav"}, "文件不存在: " + filepath.Join(dir, "c.wav")},
+		{"file_list without must_exist", variable("v", models.VarTypeFileList, true, map[string]interface{}{"must_exist": false}), []interface{}{"c.wav"}, ""},
+		{"directory", variable("v", models.VarTypeDirectory, true, nil), "sub", ""},
+		{"file as directory", variable("v", models.VarTypeDirectory, true, nil), "a.wav", "目录不存在: " + filepath.Join(dir, "a.wav")},
+		{"secret pattern", variable("v", models.VarTypeSecret, true, map[string]interface{}{"pattern": `sk-\w+`}), "pk-1", `格式不正确, 应匹配 sk-\w+`},
+		{"date", variable("v", models.VarTypeDate, true, nil), "2024-05-01", ""},
+		{"bad date", variable("v", models.VarTypeDate, true, nil), "2024-13-01", "日期格式不正确"},
+		{"key_value", variable("v", models.VarTypeKeyValue, true, nil), []interface{}{pair("a", "1"), pair("b", "")}, ""},
+		{"key_value with only blank rows", variable("v", models.VarTypeKeyValue, true, nil), []interface{}{pair("", "")}, "必填"},
+		{"key_value without a key", variable("v", models.VarTypeKeyValue, true, nil), []interface{}{pair(" ", "1")}, "键不能为空"},
+		{"key_value with a duplicate key", variable("v", models.VarTypeKeyValue, true, nil), []interface{}{pair("a", "1"), pair("a ", "2")}, "键重复: a"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := Check(models.Command{Variables: []models.VariableDefinition{tt.def}}, map[string]interface{}{"v": tt.value}, dir)
+			if got := errs["v"]; got != tt.want {
+				t.Errorf("Check(%v) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckConditions(t *testing.T) {
+	cmd := models.Command{Variables: []models.VariableDefinition{
+		{Name: "mode", Type: models.VarTypeSelect, Options: map[string]interface{}{"options": []interface{}{"crf", "cbr"}}},
+		{Name: "bitrate", Type: models.VarTypeNumber, Required: true, ShowIf: "mode == cbr"},
+		{Name: "output", Type: models.VarTypeText, RequiredIf: "mode == cbr"},
+	}}
+	tests := []struct {
+		values map[string]interface{}
+		want   Errors
+	}{
+		// hidden variables are neither required nor checked
+		{map[string]interface{}{"mode": "crf", "bitrate": "fast"}, Errors{}},
+		{map[string]interface{}{"mode": "cbr"}, Errors{"bitrate": "必填", "output": "必填"}},
+		{map[string]interface{}{"mode": "cbr", "bitrate": 800, "output": "a.mp4"}, Errors{}},
+	}
+	for _, tt := range tests {
+		if got := Check(cmd, tt.values, ""); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Check(%v) = %q, want %q", tt.values, got, tt.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	cmd := models.Command{Variables: []models.VariableDefinition{
+		{Name: "input", Label: "输入文件", Type: models.VarTypeText, Required: true},
+		{Name: "preset", Type: models.VarTypeSelect, Options: map[string]interface{}{"options": []interface{}{"fast"}}},
+		{Name: "crf", Label: "质量", Type: models.VarTypeNumber, Options: map[string]interface{}{"max": 51}},
+	}}
+	if err := Validate(cmd, map[string]interface{}{"input": "a.mov", "crf": 23}, ""); err != nil {
+		t.Fatalf("Validate of valid values = %v", err)
+	}
+
+	err := Validate(cmd, map[string]interface{}{"crf": 60, "preset": "slow"}, "")
+	e, ok := err.(*Error)
+	if !ok {
+		t.Fatalf("Validate error = %#v, want *Error", err)
+	}
+	want := Errors{"input": "必填", "preset": "必须是以下选项之一: fast", "crf": "不能大于 51"}
+	if !reflect.DeepEqual(e.Fields, want) {
+		t.Errorf("Fields = %q, want %q", e.Fields, want)
+	}
+	// labels in definition order, the name when there is no label
+	if msg := "参数校验失败: 输入文件: 必填; preset: 必须是以下选项之一: fast; 质量: 不能大于 51"; err.Error() != msg {
+		t.Errorf("Error() = %q, want %q", err.Error(), msg)
+	}
+}
+
+func TestToNumber(t *testing.T) {
+	for _, v := range []interface{}{2.5, float32(2.5), " 2.5", "2.5"} {
+		if n, ok := ToNumber(v); !ok || n != 2.5 {
+			t.Errorf("ToNumber(%#v) = %v, %v", v, n, ok)
+		}
+	}
+	for _, v := range []interface{}{nil, "", "2.5s", true} {
+		if _, ok := ToNumber(v); ok {
+			t.Errorf("ToNumber(%#v) succeeded", v)
+		}
+	}
+}
@@ -9,8 +9,17 @@ type TemplateFile struct {
 	Author              string `yaml:"author" json:"author"`
 	CliqTemplateVersion string `yaml:"cliq_template_version" json:"cliq_template_version"`
 
+	// 所有命令共同依赖的 CLI 工具
+	Requires []Requirement `yaml:"requires,omitempty" json:"requires,omitempty"`
+
+	// 模板级共享变量, 命令通过 include 引用
+	Variables []VariableDefinition `yaml:"variables,omitempty" json:"variables,omitempty"`
+
 	// 命令列表
 	Cmds []Command `yaml:"cmds" json:"cmds"`
+
+	// 多步骤工作流, 按顺序执行 cmds 中的命令
+	Pipelines []Pipeline `yaml:"pipelines,omitempty" json:"pipelines,omitempty"`
 }
 
 // Command 表示一个命令模板
@@ -19,7 +28,133 @@ type Command struct {
 	Name        string               `yaml:"name" json:"name"`
 	Description string               `yaml:"description" json:"description"`
 	Command     string               `yaml:"command" json:"command"`
-	Variables   []VariableDefinition `yaml:"variables" json:"variables"` // Changed from map to array
+	Variables   []VariableDefinition `yaml:"variables" json:"variables"`                 // Changed from map to array
+	Timeout     string               `yaml:"timeout,omitempty" json:"timeout,omitempty"` // 执行超时, Go duration 格式, 如 "30s", "10m"
+	Workdir     string               `yaml:"workdir,omitempty" json:"workdir,omitempty"` // 工作目录, 支持 {{变量}}, 为空时继承应用的工作目录
+	// Env 为命令额外设置的环境变量, 值支持 {{变量}}
+	Env map[string]string `yaml:"env,omitempty" json:"env,omitempty"`
+	// Stdin 为作为进程标准输入的变量名. file_input 变量从文件流式读取, string 变量直接写入变量值
+	Stdin string `yaml:"stdin,omitempty" json:"stdin,omitempty"`
+	// Output 声明如何解析标准输出, 解析结果以表格或键值对展示
+	Output *OutputSpec `yaml:"output,omitempty" json:"output,omitempty"`
+	// Progress 声明如何从输出行中解析执行进度
+	Progress *ProgressSpec `yaml:"progress,omitempty" json:"progress,omitempty"`
+	// Requires 为该命令额外依赖的 CLI 工具, 同名时覆盖模板级的定义
+	Requires []Requirement `yaml:"requires,omitempty" json:"requires,omitempty"`
+	// Presets 为命名的变量值组合, 可一键填入表单
+	Presets []Preset `yaml:"presets,omitempty" json:"presets,omitempty"`
+	// Extends 为另一个命令的 ID, 继承其全部变量; Include 为引用的模板级变量名.
+	// variables 中的同名变量覆盖继承和引用的定义
+	Extends string   `yaml:"extends,omitempty" json:"extends,omitempty"`
+	Include []string `yaml:"include,omitempty" json:"include,omitempty"`
+}
+
+// Preset 为一组命名的变量值, 填入表单时覆盖其中列出的变量
+type Preset struct {
+	Name        string                 `yaml:"name" json:"name"`
+	Description string                 `yaml:"description,omitempty" json:"description,omitempty"`
+	Values      map[string]interface{} `yaml:"values" json:"values"`
+	// Default 为 true 时打开命令时自动填入, 每个命令最多一个
+	Default bool `yaml:"default,omitempty" json:"default,omitempty"`
+}
+
+// Requirement 描述命令依赖的一个 CLI 工具
+type Requirement struct {
+	Name       string `yaml:"name" json:"name"` // 可执行文件名, 如 ffmpeg
+	MinVersion string `yaml:"min_version,omitempty" json:"min_version,omitempty"`
+	// VersionCommand 用于获取版本的命令, 默认为 "<name> --version"
+	VersionCommand string `yaml:"version_command,omitempty" json:"version_command,omitempty"`
+	// VersionPattern 从版本命令输出中提取版本号的正则, 有捕获组时取第一个捕获组
+	VersionPattern string `yaml:"version_pattern,omitempty" json:"version_pattern,omitempty"`
+	// Install 为各平台的安装提示, 键为 darwin / linux / windows
+	Install map[string]string `yaml:"install,omitempty" json:"install,omitempty"`
+	// Installers 为可由 cliQ 直接执行的安装方式, 按顺序选择第一个适用于当前平台的
+	Installers []Installer `yaml:"installers,omitempty" json:"installers,omitempty"`
+}
+
+// Installer 描述一种安装依赖的方式
+type Installer struct {
+	// Method 为安装方式: apt / brew / winget / pip / npm / go / download
+	Method string `yaml:"method" json:"method"`
+	// OS 限定适用平台 (darwin / linux / windows), 为空时按安装方式判断
+	OS string `yaml:"os,omitempty" json:"os,omitempty"`
+	// Package 为包名, go 安装方式为模块路径, 如 golang.org/x/tools/cmd/goimports@latest
+	Package string `yaml:"package,omitempty" json:"package,omitempty"`
+	// URL / SHA256 / Dest 用于 download 安装方式, 下载单个可执行文件并校验 sha256
+	URL    string `yaml:"url,omitempty" json:"url,omitempty"`
+	SHA256 string `yaml:"sha256,omitempty" json:"sha256,omitempty"`
+	Dest   string `yaml:"dest,omitempty" json:"dest,omitempty"` // 默认 ~/.local/bin/<name>
+}
+
+// 安装方式常量
+const (
+	InstallApt      = "apt"
+	InstallBrew     = "brew"
+	InstallWinget   = "winget"
+	InstallPip      = "pip"
+	InstallNpm      = "npm"
+	InstallGo       = "go"
+	InstallDownload = "download"
+)
+
+// OutputSpec 描述命令标准输出的结构
+type OutputSpec struct {
+	// Format 为输出格式: json / csv / regex
+	Format string `yaml:"format" json:"format"`
+	// Rows 为 json 输出中作为表格展示的数组路径, 如 "streams", 为空时使用整个输出
+	Rows string `yaml:"rows,omitempty" json:"rows,omitempty"`
+	// Columns 为要展示的列 (json 对象中的键, 支持 "tags.language" 形式的路径), 为空时展示全部
+	Columns []string `yaml:"columns,omitempty" json:"columns,omitempty"`
+	// Delimiter 为 csv 的分隔符, 默认为逗号. 第一行为表头
+	Delimiter string `yaml:"delimiter,omitempty" json:"delimiter,omitempty"`
+	// Pattern 为 regex 格式使用的正则, 每个匹配是一行, 命名捕获组是列
+	Pattern string `yaml:"pattern,omitempty" json:"pattern,omitempty"`
+	// Extract 从解析结果中提取命名值, 值为路径, 如 "format.duration" 或 "0.size"
+	Extract map[string]string `yaml:"extract,omitempty" json:"extract,omitempty"`
+}
+
+// ProgressSpec 描述如何从 stdout / stderr 的输出行中解析进度.
+// 数值可以是数字或 hh:mm:ss.xx 形式的时间
+type ProgressSpec struct {
+	// Pattern 匹配进度行. 命名捕获组 percent 为百分比; 或 current 为当前值, total 为总量.
+	// 没有命名组时第一个捕获组为百分比
+	Pattern string `yaml:"pattern" json:"pattern"`
+	// TotalPattern 匹配总量的正则, 使用命名组 total 或第一个捕获组, 如 ffmpeg 的 "Duration: (?P<total>\S+),"
+	TotalPattern string `yaml:"total_pattern,omitempty" json:"total_pattern,omitempty"`
+}
+
+// 输出格式常量
+const (
+	OutputJSON  = "json"
+	OutputCSV   = "csv"
+	OutputRegex = "regex"
+)
+
+// Pipeline 表示一个多步骤工作流
+type Pipeline struct {
+	ID          string         `yaml:"id" json:"id"`
+	Name        string         `yaml:"name" json:"name"`
+	Description string         `yaml:"description" json:"description"`
+	Steps       []PipelineStep `yaml:"steps" json:"steps"`
+}
+
+// PipelineStep 表示工作流中的一步, 运行 cmds 中的一个命令
+type PipelineStep struct {
+	ID      string `yaml:"id,omitempty" json:"id,omitempty"` // 为空时使用 Command
+	Name    string `yaml:"name,omitempty" json:"name,omitempty"`
+	Command string `yaml:"command" json:"command"` // 命令 ID
+	// With 为该步骤的变量赋值, 可以引用工作流变量 {{name}} 以及前面步骤的结果
+	// {{steps.<id>.output}} (输出文件) / {{steps.<id>.stdout}} (标准输出)
+	With            map[string]string `yaml:"with,omitempty" json:"with,omitempty"`
+	ContinueOnError bool              `yaml:"continue_on_error,omitempty" json:"continue_on_error,omitempty"`
+}
+
+// StepID 返回步骤的标识, 未设置 id 时使用命令 ID
+func (s PipelineStep) StepID() string {
+	if s.ID != "" {
+		return s.ID
+	}
+	return s.Command
 }
 
 // VariableDefinition 表示命令中的一个变量定义（扁平化结构）
@@ -30,6 +165,10 @@ type VariableDefinition struct {
 	Label       string                 `yaml:"label" json:"label"`
 	Description string                 `yaml:"description" json:"description"`
 	Required    bool                   `yaml:"required" json:"required"`
+	Env         string                 `yaml:"env,omitempty" json:"env,omitempty"`                 // 设置后作为该名称的环境变量传入, 而不是命令参数
+	Computed    string                 `yaml:"computed,omitempty" json:"computed,omitempty"`       // 由其他变量推导的值, 如 "{{input|stem}}.mp3", 用户仍可修改
+	ShowIf      string                 `yaml:"show_if,omitempty" json:"show_if,omitempty"`         // 条件为真时才显示, 如 "mode == cbr". 隐藏的变量既不必填也不渲染
+	RequiredIf  string                 `yaml:"required_if,omitempty" json:"required_if,omitempty"` // 条件为真时必填
 	Options     map[string]interface{} `yaml:"options,omitempty" json:"options,omitempty"`
 }
 
@@ -41,4 +180,17 @@ const (
 	VarTypeBoolean    = "boolean"
 	VarTypeNumber     = "number"
 	VarTypeSelect     = "select"
+	// 多选, 值为字符串列表
+	VarTypeMultiSelect = "multi_select"
+	// 多个输入文件, 值为路径列表
+	VarTypeFileList  = "file_list"
+	VarTypeDirectory = "directory"
+	// 密钥: 输入时隐藏, 不写入执行历史和任务输出
+	VarTypeSecret   = "secret"
+	VarTypeTextarea = "textarea"
+	// 日期和时间, 按 options.format 格式化
+	VarTypeDate     = "date"
+	VarTypeDatetime = "datetime"
+	// 键值对列表, 值为 [{key, value}]
+	VarTypeKeyValue = "key_value"
 )
@@ -0,0 +1,598 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// The option structs below are typed views of VariableDefinition.Options.
+// Options stays a plain map so templates keep their YAML layout; the views
+// are decoded from it with DecodeOptions, which rejects unknown keys and
+// values of the wrong type.
+
+// TextOptions are the options of `string` variables.
+type TextOptions struct {
+	Default     string `yaml:"default"`
+	Placeholder string `yaml:"placeholder"`
+	// Pattern is a regular expression the whole value must match.
+	Pattern string `yaml:"pattern"`
+}
+
+// NumberOptions are the options of `number` variables. Unset values are nil.
+type NumberOptions struct {
+	Default *float64 `yaml:"default"`
+	Min     *float64 `yaml:"min"`
+	Max     *float64 `yaml:"max"`
+	Step    *float64 `yaml:"step"`
+}
+
+// SelectOptions are the options of `select` variables.
+type SelectOptions struct {
+	Options []Choice `yaml:"options"`
+	Default string   `yaml:"default"`
+	Pattern string   `yaml:"pattern"`
+	// OptionsFrom produces the choices by running a command instead.
+	OptionsFrom *OptionSource `yaml:"options_from"`
+}
+
+// Values returns the values of the choices.
+func (o SelectOptions) Values() []string {
+	values := make([]string, len(o.Options))
+	for i, c := range o.Options {
+		values[i] = c.Value
+	}
+	return values
+}
+
+// Choice is one entry of a select. In YAML it is either the plain value or
+// a mapping with `value` and an optional display `label`.
+type Choice struct {
+	Value string `yaml:"value" json:"value"`
+	Label string `yaml:"label,omitempty" json:"label,omitempty"`
+}
+
+// UnmarshalYAML accepts both "- libx264" and "- {value: libx264, label: H.264}".
+func (c *Choice) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		c.Value = node.Value
+		return nil
+	}
+	type plain Choice
+	var p plain
+	if err := decodeNode(node, &p); err != nil {
+		return err
+	}
+	if p.Value == "" {
+		return fmt.Errorf("choice without value")
+	}
+	*c = Choice(p)
+	return nil
+}
+
+// Formats of an OptionSource's output.
+const (
+	SourceLines = "lines"
+	SourceJSON  = "json"
+)
+
+// OptionSource produces the choices of a select or multi_select from the
+// output of a command, such as `git branch --format=%(refname:short)`. In
+// YAML it is either the command itself or a mapping.
+type OptionSource struct {
+	// Command is a command template. Its placeholders refer to the other
+	// variables of the command, so the choices follow their current values.
+	Command string `yaml:"command" json:"command"`
+	// Format is "lines" (every non-empty line is a choice) or "json". It
+	// is "json" when JSONPath is set and "lines" otherwise.
+	Format string `yaml:"format,omitempty" json:"format,omitempty"`
+	// JSONPath is the dotted path of the choices in the JSON output, such
+	// as "items"; empty means the whole output.
+	JSONPath string `yaml:"json_path,omitempty" json:"json_path,omitempty"`
+	// Value and Label are dotted paths inside each JSON element, such as
+	// "metadata.name". Empty Value uses the element itself; empty Label
+	// shows the value.
+	Value string `yaml:"value,omitempty" json:"value,omitempty"`
+	Label string `yaml:"label,omitempty" json:"label,omitempty"`
+	// Cache is how long the choices are reused, e.g. "5m". "0" runs the
+	// command every time; unset means DefaultSourceCache.
+	Cache string `yaml:"cache,omitempty" json:"cache,omitempty"`
+	// Timeout bounds the command, DefaultSourceTimeout when unset.
+	Timeout string `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+}
+
+// Defaults of an OptionSource.
+const (
+	DefaultSourceCache   = time.Minute
+	DefaultSourceTimeout = 10 * time.Second
+)
+
+// UnmarshalYAML accepts both `options_from: "git branch"` and a mapping.
+func (s *OptionSource) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		s.Command = node.Value
+		return nil
+	}
+	if node.Kind == yaml.MappingNode {
+		names := yamlNames(reflect.TypeOf(*s))
+		for i := 0; i < len(node.Content); i += 2 {
+			key := node.Content[i].Value
+			if !containsName(names, key) {
+				return unknownKey(fmt.Sprintf("unknown key '%s'", key), key, names)
+			}
+		}
+	}
+	type plain OptionSource
+	var p plain
+	if err := decodeNode(node, &p); err != nil {
+		return err
+	}
+	*s = OptionSource(p)
+	return nil
+}
+
+// OutputFormat returns the format of the command's output, see Format.
+func (s OptionSource) OutputFormat() string {
+	if s.Format != "" {
+		return s.Format
+	}
+	if s.JSONPath != "" || s.Value != "" {
+		return SourceJSON
+	}
+	return SourceLines
+}
+
+// CacheDuration returns how long choices are reused. Invalid values, which
+// ValidateTemplate reports, disable the cache.
+func (s OptionSource) CacheDuration() time.Duration {
+	if s.Cache == "" {
+		return DefaultSourceCache
+	}
+	d, err := time.ParseDuration(s.Cache)
+	if err != nil || d < 0 {
+		return 0
+	}
+	return d
+}
+
+// TimeoutDuration returns the timeout of the command.
+func (s OptionSource) TimeoutDuration() time.Duration {
+	if d, err := time.ParseDuration(s.Timeout); err == nil && d > 0 {
+		return d
+	}
+	return DefaultSourceTimeout
+}
+
+// FileOptions are the options of `file_input` and `file_output` variables.
+type FileOptions struct {
+	Default   string   `yaml:"default"`
+	FileTypes []string `yaml:"file_types"`
+	Pattern   string   `yaml:"pattern"`
+	// MustExist defaults to true for file_input and false for file_output.
+	MustExist *bool `yaml:"must_exist"`
+}
+
+// Exists reports whether the file of a variable of type varType must exist
+// when the command runs.
+func (o FileOptions) Exists(varType string) bool {
+	if o.MustExist != nil {
+		return *o.MustExist
+	}
+	return varType == VarTypeFileInput
+}
+
+// BooleanOptions are the options of `boolean` variables.
+type BooleanOptions struct {
+	Default bool `yaml:"default"`
+	// TrueValue and FalseValue replace the flag rendered for a checked
+	// and an unchecked box, e.g. "--color" / "--no-color".
+	TrueValue  string `yaml:"true_value"`
+	FalseValue string `yaml:"false_value"`
+}
+
+// MultiSelectOptions are the options of `multi_select` variables.
+type MultiSelectOptions struct {
+	Options []Choice `yaml:"options"`
+	Default []string `yaml:"default"`
+	// Separator joins the selected values into one argument, e.g. ","; by
+	// default every value is a separate argument.
+	Separator   string        `yaml:"separator"`
+	OptionsFrom *OptionSource `yaml:"options_from"`
+}
+
+// FileListOptions are the options of `file_list` variables.
+type FileListOptions struct {
+	Default   []string `yaml:"default"`
+	FileTypes []string `yaml:"file_types"`
+	// MustExist defaults to true.
+	MustExist *bool  `yaml:"must_exist"`
+	Separator string `yaml:"separator"`
+}
+
+// DirectoryOptions are the options of `directory` variables.
+type DirectoryOptions struct {
+	Default string `yaml:"default"`
+	Pattern string `yaml:"pattern"`
+	// MustExist defaults to true.
+	MustExist *bool `yaml:"must_exist"`
+}
+
+// SecretOptions are the options of `secret` variables. There is no default:
+// secrets do not belong in templates.
+type SecretOptions struct {
+	Placeholder string `yaml:"placeholder"`
+	Pattern     string `yaml:"pattern"`
+}
+
+// TextareaOptions are the options of `textarea` variables.
+type TextareaOptions struct {
+	Default     string `yaml:"default"`
+	Placeholder string `yaml:"placeholder"`
+	Pattern     string `yaml:"pattern"`
+	// Rows is the height of the input in lines.
+	Rows int `yaml:"rows"`
+}
+
+// DateOptions are the options of `date` and `datetime` variables.
+type DateOptions struct {
+	Default string `yaml:"default"`
+	// Format is a strftime-style layout such as "%Y%m%d"; see
+	// DefaultDateFormat.
+	Format string `yaml:"format"`
+}
+
+// DefaultDateFormat returns the format used when a date or datetime
+// variable sets none.
+func DefaultDateFormat(varType string) string {
+	if varType == VarTypeDatetime {
+		return "%Y-%m-%d %H:%M:%S"
+	}
+	return "%Y-%m-%d"
+}
+
+// KeyValueOptions are the options of `key_value` variables.
+type KeyValueOptions struct {
+	Default map[string]string `yaml:"default"`
+	// Separator goes between key and value, "=" by default.
+	Separator string `yaml:"separator"`
+}
+
+// OptionsFor returns a pointer to the empty option struct of a variable
+// type, or nil for an unknown type.
+func OptionsFor(varType string) interface{} {
+	switch varType {
+	case VarTypeText:
+		return &TextOptions{}
+	case VarTypeNumber:
+		return &NumberOptions{}
+	case VarTypeSelect:
+		return &SelectOptions{}
+	case VarTypeFileInput, VarTypeFileOutput:
+		return &FileOptions{}
+	case VarTypeBoolean:
+		return &BooleanOptions{}
+	case VarTypeMultiSelect:
+		return &MultiSelectOptions{}
+	case VarTypeFileList:
+		return &FileListOptions{}
+	case VarTypeDirectory:
+		return &DirectoryOptions{}
+	case VarTypeSecret:
+		return &SecretOptions{}
+	case VarTypeTextarea:
+		return &TextareaOptions{}
+	case VarTypeDate, VarTypeDatetime:
+		return &DateOptions{}
+	case VarTypeKeyValue:
+		return &KeyValueOptions{}
+	}
+	return nil
+}
+
+// DecodeOptions decodes the options of v into the option struct of its
+// type, see OptionsFor. It fails on keys the type does not support and on
+// values of the wrong type.
+func DecodeOptions(v VariableDefinition) (interface{}, error) {
+	out := OptionsFor(v.Type)
+	if out == nil {
+		return nil, fmt.Errorf("unsupported type '%s'", v.Type)
+	}
+	rv := reflect.ValueOf(out).Elem()
+	names := yamlNames(rv.Type())
+	fields := map[string]int{}
+	for i, name := range names {
+		fields[name] = i
+	}
+
+	keys := make([]string, 0, len(v.Options))
+	for key := range v.Options {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		i, ok := fields[key]
+		if !ok {
+			return nil, unknownKey(fmt.Sprintf("unknown option '%s' for type %s", key, v.Type), key, names)
+		}
+		field := rv.Field(i)
+		if err := decodeValue(v.Options[key], field.Addr().Interface()); err != nil {
+			var typeErr *yaml.TypeError
+			if !errors.As(err, &typeErr) {
+				// 自定义解码 (如 options_from) 的错误更具体
+				return nil, fmt.Errorf("option '%s': %v", key, err)
+			}
+			return nil, fmt.Errorf("option '%s' must be %s", key, describe(field.Type()))
+		}
+	}
+	return out, nil
+}
+
+// The accessors below decode the options of v as one type. Options that
+// fail to decode yield the zero value; ValidateTemplate reports them.
+
+// TextOptions returns the options of a string variable.
+func (v VariableDefinition) TextOptions() TextOptions {
+	var o TextOptions
+	decodeInto(v, &o)
+	return o
+}
+
+// NumberOptions returns the options of a number variable.
+func (v VariableDefinition) NumberOptions() NumberOptions {
+	var o NumberOptions
+	decodeInto(v, &o)
+	return o
+}
+
+// SelectOptions returns the options of a select variable.
+func (v VariableDefinition) SelectOptions() SelectOptions {
+	var o SelectOptions
+	decodeInto(v, &o)
+	return o
+}
+
+// FileOptions returns the options of a file_input or file_output variable.
+func (v VariableDefinition) FileOptions() FileOptions {
+	var o FileOptions
+	decodeInto(v, &o)
+	return o
+}
+
+// BooleanOptions returns the options of a boolean variable.
+func (v VariableDefinition) BooleanOptions() BooleanOptions {
+	var o BooleanOptions
+	decodeInto(v, &o)
+	return o
+}
+
+// MultiSelectOptions returns the options of a multi_select variable.
+func (v VariableDefinition) MultiSelectOptions() MultiSelectOptions {
+	var o MultiSelectOptions
+	decodeInto(v, &o)
+	return o
+}
+
+// FileListOptions returns the options of a file_list variable.
+func (v VariableDefinition) FileListOptions() FileListOptions {
+	var o FileListOptions
+	decodeInto(v, &o)
+	return o
+}
+
+// DirectoryOptions returns the options of a directory variable.
+func (v VariableDefinition) DirectoryOptions() DirectoryOptions {
+	var o DirectoryOptions
+	decodeInto(v, &o)
+	return o
+}
+
+// DateOptions returns the options of a date or datetime variable, with the
+// default format filled in.
+func (v VariableDefinition) DateOptions() DateOptions {
+	var o DateOptions
+	decodeInto(v, &o)
+	if o.Format == "" {
+		o.Format = DefaultDateFormat(v.Type)
+	}
+	return o
+}
+
+// KeyValueOptions returns the options of a key_value variable, with the
+// default separator filled in.
+func (v VariableDefinition) KeyValueOptions() KeyValueOptions {
+	var o KeyValueOptions
+	decodeInto(v, &o)
+	if o.Separator == "" {
+		o.Separator = "="
+	}
+	return o
+}
+
+// Pattern returns the `pattern` option of the text-like, select and path
+// variables.
+func (v VariableDefinition) Pattern() string {
+	decoded, err := DecodeOptions(v)
+	if err != nil {
+		return ""
+	}
+	switch o := decoded.(type) {
+	case *TextOptions:
+		return o.Pattern
+	case *SelectOptions:
+		return o.Pattern
+	case *FileOptions:
+		return o.Pattern
+	case *DirectoryOptions:
+		return o.Pattern
+	case *SecretOptions:
+		return o.Pattern
+	case *TextareaOptions:
+		return o.Pattern
+	}
+	return ""
+}
+
+// OptionSource returns the `options_from` of a select or multi_select
+// variable, nil when its choices are fixed.
+func (v VariableDefinition) OptionSource() *OptionSource {
+	switch v.Type {
+	case VarTypeSelect:
+		return v.SelectOptions().OptionsFrom
+	case VarTypeMultiSelect:
+		return v.MultiSelectOptions().OptionsFrom
+	}
+	return nil
+}
+
+// Separator returns the separator that joins the values of a multi_select
+// or file_list variable into one argument, empty when they stay separate.
+func (v VariableDefinition) Separator() string {
+	switch v.Type {
+	case VarTypeMultiSelect:
+		return v.MultiSelectOptions().Separator
+	case VarTypeFileList:
+		return v.FileListOptions().Separator
+	}
+	return ""
+}
+
+// IsList reports whether values of the variable type are lists.
+func IsList(varType string) bool {
+	switch varType {
+	case VarTypeMultiSelect, VarTypeFileList, VarTypeKeyValue:
+		return true
+	}
+	return false
+}
+
+// IsPath reports whether values of the variable type are file system paths,
+// whose leading "~" is expanded.
+func IsPath(varType string) bool {
+	switch varType {
+	case VarTypeFileInput, VarTypeFileOutput, VarTypeFileList, VarTypeDirectory:
+		return true
+	}
+	return false
+}
+
+func decodeInto(v VariableDefinition, out interface{}) {
+	decoded, err := DecodeOptions(v)
+	if err != nil {
+		return
+	}
+	if reflect.TypeOf(decoded) == reflect.TypeOf(out) {
+		reflect.ValueOf(out).Elem().Set(reflect.ValueOf(decoded).Elem())
+	}
+}
+
+// decodeValue converts a value decoded from YAML or JSON into out by way of
+// YAML, so both sources follow the same rules.
+func decodeValue(v interface{}, out interface{}) error {
+	b, err := yaml.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return yaml.Unmarshal(b, out)
+}
+
+func decodeNode(node *yaml.Node, out interface{}) error {
+	b, err := yaml.Marshal(node)
+	if err != nil {
+		return err
+	}
+	dec := yaml.NewDecoder(strings.NewReader(string(b)))
+	dec.KnownFields(true)
+	return dec.Decode(out)
+}
+
+// describe names the expected kind of value for error messages.
+func describe(t reflect.Type) string {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Float64:
+		return "a number"
+	case reflect.Int:
+		return "a whole number"
+	case reflect.Map:
+		return "a mapping of strings"
+	case reflect.Bool:
+		return "true or false"
+	case reflect.String:
+		return "a string"
+	case reflect.Slice:
+		if t.Elem() == reflect.TypeOf(Choice{}) {
+			return "a list of values or {value, label} entries"
+		}
+		return "a list of strings"
+	case reflect.Struct:
+		if t == reflect.TypeOf(OptionSource{}) {
+			return "a command or a mapping with command, format, json_path, value, label, cache and timeout"
+		}
+	}
+	return t.String()
+}
+
+// yamlNames returns the yaml keys of the fields of struct type t, in order.
+func yamlNames(t reflect.Type) []string {
+	names := make([]string, t.NumField())
+	for i := range names {
+		names[i] = strings.Split(t.Field(i).Tag.Get("yaml"), ",")[0]
+	}
+	return names
+}
+
+func containsName(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// unknownKey completes the message about an unknown key with the closest
+// supported key or the list of supported keys.
+func unknownKey(msg, key string, names []string) error {
+	if near := closest(key, names); near != "" {
+		return fmt.Errorf("%s, did you mean '%s'?", msg, near)
+	}
+	return fmt.Errorf("%s (supported: %s)", msg, strings.Join(names, ", "))
+}
+
+// closest returns the candidate within two edits of key, if any.
+func closest(key string, candidates []string) string {
+	best, bestDist := "", 3
+	for _, c := range candidates {
+		if d := editDistance(key, c); d < bestDist {
+			best, bestDist = c, d
+		}
+	}
+	return best
+}
+
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
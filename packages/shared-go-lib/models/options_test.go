@@ -0,0 +1,168 @@
+package models
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// variable decodes a variable definition from YAML, the way templates load.
+func variable(t *testing.T, src string) VariableDefinition {
+	t.Helper()
+	var v VariableDefinition
+	if err := yaml.Unmarshal([]byte(src), &v); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func number(f float64) *float64 { return &f }
+
+func TestDecodeOptions(t *testing.T) {
+	no := false
+	tests := []struct {
+		name string
+		src  string
+		want interface{}
+	}{
+		{"text", "{type: string, options: {default: out, pattern: '\\w+'}}",
+			&TextOptions{Default: "out", Pattern: `\w+`}},
+		{"number from ints", "{type: number, options: {default: 23, min: 0, max: 51}}",
+			&NumberOptions{Default: number(23), Min: number(0), Max: number(51)}},
+		{"select with plain and labelled choices", "{type: select, options: {options: [fast, {value: slow, label: Slow}], default: fast}}",
+			&SelectOptions{Options: []Choice{{Value: "fast"}, {Value: "slow", Label: "Slow"}}, Default: "fast"}},
+		{"file", "{type: file_output, options: {file_types: [.mp4], must_exist: false}}",
+			&FileOptions{FileTypes: []string{".mp4"}, MustExist: &no}},
+		{"boolean", "{type: boolean, options: {default: true, true_value: --color, false_value: --no-color}}",
+			&BooleanOptions{Default: true, TrueValue: "--color", FalseValue: "--no-color"}},
+		{"no options", "{type: number}", &NumberOptions{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecodeOptions(variable(t, tt.src))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DecodeOptions = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeOptionsErrors(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{"{type: number, options: {maxx: 5}}", "unknown option 'maxx' for type number, did you mean 'max'?"},
+		{"{type: boolean, options: {placeholder: x}}", "unknown option 'placeholder' for type boolean (supported: default, true_value, false_value)"},
+		{"{type: number, options: {max: high}}", "option 'max' must be a number"},
+		{"{type: string, options: {default: [a, b]}}", "option 'default' must be a string"},
+		{"{type: select, options: {options: a}}", "option 'options' must be a list of values or {value, label} entries"},
+		{"{type: select, options: {options: [{label: Slow}]}}", "option 'options': choice without value"},
+		{"{type: color}", "unsupported type 'color'"},
+	}
+	for _, tt := range tests {
+		_, err := DecodeOptions(variable(t, tt.src))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("DecodeOptions(%s) error = %v, want %q", tt.src, err, tt.want)
+		}
+	}
+}
+
+// The accessors fall back to the zero value for options that fail to
+// decode; ValidateTemplate reports those.
+func TestOptionAccessors(t *testing.T) {
+	v := variable(t, "{type: number, options: {max: 51}}")
+	if max := v.NumberOptions().Max; max == nil || *max != 51 {
+		t.Errorf("NumberOptions().Max = %v", max)
+	}
+	if got := v.SelectOptions(); !reflect.DeepEqual(got, SelectOptions{}) {
+		t.Errorf("SelectOptions of a number = %+v", got)
+	}
+	if got := variable(t, "{type: number, options: {max: high}}").NumberOptions(); got.Max != nil {
+		t.Errorf("NumberOptions of invalid options = %+v", got)
+	}
+	if got := variable(t, "{type: file_input, options: {pattern: '.*\\.mov'}}").Pattern(); got != `.*\.mov` {
+		t.Errorf("Pattern = %q", got)
+	}
+	for typ, want := range map[string]bool{VarTypeFileInput: true, VarTypeFileOutput: false} {
+		if got := (FileOptions{}).Exists(typ); got != want {
+			t.Errorf("Exists(%s) = %v, want %v", typ, got, want)
+		}
+	}
+}
+
+func TestListTypeOptions(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want interface{}
+	}{
+		{"multi_select", "{type: multi_select, options: {options: [a, b], default: [a], separator: ','}}",
+			&MultiSelectOptions{Options: []Choice{{Value: "a"}, {Value: "b"}}, Default: []string{"a"}, Separator: ","}},
+		{"file_list", "{type: file_list, options: {file_types: [.wav]}}", &FileListOptions{FileTypes: []string{".wav"}}},
+		{"key_value", "{type: key_value, options: {default: {A: '1'}}}", &KeyValueOptions{Default: map[string]string{"A": "1"}}},
+		{"date", "{type: datetime, options: {format: '%Y'}}", &DateOptions{Format: "%Y"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecodeOptions(variable(t, tt.src))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DecodeOptions = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	if got := variable(t, "{type: key_value}").KeyValueOptions().Separator; got != "=" {
+		t.Errorf("default key_value separator = %q", got)
+	}
+	if got := variable(t, "{type: datetime}").DateOptions().Format; got != "%Y-%m-%d %H:%M:%S" {
+		t.Errorf("default datetime format = %q", got)
+	}
+	if got := variable(t, "{type: file_list, options: {separator: ' '}}").Separator(); got != " " {
+		t.Errorf("file_list Separator = %q", got)
+	}
+}
+
+func TestOptionSource(t *testing.T) {
+	tests := []struct {
+		src     string
+		want    OptionSource
+		format  string
+		cache   time.Duration
+		timeout time.Duration
+	}{
+		{"{type: select, options: {options_from: git branch}}",
+			OptionSource{Command: "git branch"}, SourceLines, DefaultSourceCache, DefaultSourceTimeout},
+		{"{type: multi_select, options: {options_from: {command: kubectl get ns -o json, json_path: items, value: metadata.name, cache: '0', timeout: 2s}}}",
+			OptionSource{Command: "kubectl get ns -o json", JSONPath: "items", Value: "metadata.name", Cache: "0", Timeout: "2s"}, SourceJSON, 0, 2 * time.Second},
+		{"{type: select, options: {options_from: {command: ls, cache: soon}}}",
+			OptionSource{Command: "ls", Cache: "soon"}, SourceLines, 0, DefaultSourceTimeout},
+	}
+	for _, tt := range tests {
+		src := variable(t, tt.src).OptionSource()
+		if src == nil {
+			t.Errorf("OptionSource(%s) = nil", tt.src)
+			continue
+		}
+		if *src != tt.want || src.OutputFormat() != tt.format || src.CacheDuration() != tt.cache || src.TimeoutDuration() != tt.timeout {
+			t.Errorf("OptionSource(%s) = %+v, format %s, cache %v, timeout %v", tt.src, *src, src.OutputFormat(), src.CacheDuration(), src.TimeoutDuration())
+		}
+	}
+
+	if src := variable(t, "{type: select, options: {options: [a]}}").OptionSource(); src != nil {
+		t.Errorf("OptionSource of fixed choices = %+v", src)
+	}
+	_, err := DecodeOptions(variable(t, "{type: select, options: {options_from: {command: ls, jsonpath: items}}}"))
+	if err == nil || !strings.Contains(err.Error(), "unknown key 'jsonpath', did you mean 'json_path'?") {
+		t.Errorf("DecodeOptions of an unknown options_from key error = %v", err)
+	}
+}
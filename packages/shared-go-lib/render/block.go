@@ -0,0 +1,176 @@
+package render
+
+import (
+	"fmt"
+
+	"repo/shared-go-lib/models"
+)
+
+// node is an element of a parsed command template: a plain token, or an
+// {{#if}} / {{#each}} block with its body.
+type node struct {
+	tok  token
+	body []node
+	// alt is the {{else}} branch of an {{#if}} block.
+	alt []node
+}
+
+// parseBlocks nests the tokens between block start and end markers.
+func parseBlocks(tokens []token) ([]node, error) {
+	nodes, rest, err := parseNodes(tokens, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) > 0 {
+		return nil, fmt.Errorf("unexpected {{/%s}} at offset %d", rest[0].text, rest[0].pos)
+	}
+	return nodes, nil
+}
+
+// parseNodes parses tokens until the end of the enclosing block and returns
+// the remaining tokens, starting with the closing {{/...}}. open is the
+// enclosing block start, nil at the top level; eachDepth counts the
+// {{#each}} blocks around the tokens, where {{.}} is allowed.
+func parseNodes(tokens []token, open *token, eachDepth int) ([]node, []token, error) {
+	var nodes []node
+	for i := 0; i < len(tokens); i++ {
+		tok := tokens[i]
+		switch tok.kind {
+		case tokEnd:
+			return nodes, tokens[i:], nil
+		case tokElse:
+			if open == nil || open.kind != tokIf {
+				return nil, nil, fmt.Errorf("{{else}} outside of an {{#if}} block at offset %d", tok.pos)
+			}
+			nodes = append(nodes, node{tok: tok}) // split off by the enclosing block
+		case tokVar:
+			if tok.text == "." && eachDepth == 0 {
+				return nil, nil, fmt.Errorf("{{.}} outside of an {{#each}} block at offset %d", tok.pos)
+			}
+			nodes = append(nodes, node{tok: tok})
+		case tokIf, tokEach:
+			depth := eachDepth
+			if tok.kind == tokEach {
+				depth++
+			}
+			n := node{tok: tok}
+			body, rest, err := parseNodes(tokens[i+1:], &tok, depth)
+			if err != nil {
+				return nil, nil, err
+			}
+			n.body = body
+			// {{else}} ends the body of an {{#if}} without closing it
+			for j, t := range body {
+				if t.tok.kind != tokElse {
+					continue
+				}
+				if n.alt != nil {
+					return nil, nil, fmt.Errorf("second {{else}} in {{#if %s}} at offset %d", tok.text, t.tok.pos)
+				}
+				n.body, n.alt = body[:j], body[j+1:]
+			}
+			if len(rest) == 0 {
+				return nil, nil, fmt.Errorf("unclosed {{%s %s}} block at offset %d", blockName(tok.kind), tok.text, tok.pos)
+			}
+			if want := blockName(tok.kind)[1:]; rest[0].text != want {
+				return nil, nil, fmt.Errorf("{{/%s}} at offset %d closes {{%s %s}}", rest[0].text, rest[0].pos, blockName(tok.kind), tok.text)
+			}
+			nodes = append(nodes, n)
+			i = len(tokens) - len(rest) // skip the body and the closing token
+		default:
+			nodes = append(nodes, node{tok: tok})
+		}
+	}
+	return nodes, nil, nil
+}
+
+func blockName(kind tokenKind) string {
+	if kind == tokEach {
+		return "#each"
+	}
+	return "#if"
+}
+
+// walk calls fn for every token of nodes, including block start tokens and
+// the tokens of all branches.
+func walk(nodes []node, fn func(token)) {
+	for _, n := range nodes {
+		fn(n.tok)
+		walk(n.body, fn)
+		walk(n.alt, fn)
+	}
+}
+
+// expand evaluates the blocks of nodes against values and returns the flat
+// token list of the chosen branches. The {{.}} tokens inside an {{#each}}
+// body are bound to the current element.
+//
+// Repetitions of an {{#each}} body outside of quotes are separated like
+// words, so "{{#each files}}-i {{.}}{{/each}}" yields "-i a -i b"; inside
+// quotes they are concatenated.
+func expand(nodes []node, values map[string]interface{}, byName map[string]*models.VariableDefinition, item *token) []token {
+	var out []token
+	for _, n := range nodes {
+		switch n.tok.kind {
+		case tokIf:
+			if truthy(n.tok.text, values, byName) {
+				out = append(out, expand(n.body, values, byName, item)...)
+			} else {
+				out = append(out, expand(n.alt, values, byName, item)...)
+			}
+		case tokEach:
+			for i, v := range items(values[n.tok.text]) {
+				if i > 0 && !n.tok.quoted {
+					out = append(out, token{kind: tokSep, pos: n.tok.pos})
+				}
+				bound := &token{kind: tokVar, text: ".", item: v, list: n.tok.text, bound: true}
+				out = append(out, expand(n.body, values, byName, bound)...)
+			}
+		case tokVar:
+			if n.tok.text == "." && item != nil {
+				tok := *item
+				tok.quoted, tok.pos, tok.filters = n.tok.quoted, n.tok.pos, n.tok.filters
+				out = append(out, tok)
+				continue
+			}
+			out = append(out, n.tok)
+		default:
+			out = append(out, n.tok)
+		}
+	}
+	return out
+}
+
+// truthy decides an {{#if name}} condition: a boolean variable must be true,
+// any other variable must have a non-empty value.
+func truthy(name string, values map[string]interface{}, byName map[string]*models.VariableDefinition) bool {
+	v, found := values[name]
+	if !found {
+		return false
+	}
+	if def := byName[name]; def != nil && def.Type == models.VarTypeBoolean {
+		return isTruthy(v)
+	}
+	if b, ok := v.(bool); ok {
+		return b
+	}
+	return !isEmpty(v)
+}
+
+// items returns the elements an {{#each}} block iterates over; a single
+// non-empty value counts as a list of one.
+func items(v interface{}) []interface{} {
+	if list, ok := toList(v); ok {
+		var out []interface{}
+		for _, item := range list {
+			if !isEmpty(item) {
+				out = append(out, item)
+			}
+		}
+		return out
+	}
+	if isEmpty(v) {
+		return nil
+	}
+	return []interface{}{v}
+}
@@ -0,0 +1,123 @@
+package render
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"repo/shared-go-lib/models"
+)
+
+func TestBlocks(t *testing.T) {
+	defs := []models.VariableDefinition{
+		{Name: "fast", Type: models.VarTypeBoolean},
+		{Name: "crf", Type: models.VarTypeNumber},
+		{Name: "files", Type: models.VarTypeFileList},
+		{Name: "out", Type: models.VarTypeText},
+	}
+	tests := []struct {
+		name    string
+		command string
+		values  map[string]interface{}
+		want    []string
+	}{
+		{"if set", "ffmpeg {{#if crf}}-crf {{crf}}{{/if}} out.mp4",
+			map[string]interface{}{"crf": 23.0}, []string{"ffmpeg", "-crf", "23", "out.mp4"}},
+		{"if empty", "ffmpeg {{#if crf}}-crf {{crf}}{{/if}} out.mp4",
+			map[string]interface{}{"crf": ""}, []string{"ffmpeg", "out.mp4"}},
+		{"if missing", "ffmpeg {{#if crf}}-crf {{crf}}{{/if}} out.mp4",
+			nil, []string{"ffmpeg", "out.mp4"}},
+		{"if boolean false", "x {{#if fast}}--fast{{/if}}",
+			map[string]interface{}{"fast": false}, []string{"x"}},
+		{"if boolean string", "x {{#if fast}}--fast{{/if}}",
+			map[string]interface{}{"fast": "true"}, []string{"x", "--fast"}},
+		{"else branch", "x {{#if fast}}--fast{{else}}--slow{{/if}}",
+			map[string]interface{}{"fast": false}, []string{"x", "--slow"}},
+		{"each repeats words", "ffmpeg {{#each files}}-i {{.}} {{/each}}-y",
+			map[string]interface{}{"files": []interface{}{"a b.mov", "c.mov"}},
+			[]string{"ffmpeg", "-i", "a b.mov", "-i", "c.mov", "-y"}},
+		{"each skips empty items", "cat {{#each files}}{{.}}{{/each}}",
+			map[string]interface{}{"files": []interface{}{"a", "", "b"}}, []string{"cat", "a", "b"}},
+		{"each over nothing", "cat {{#each files}}-i {{.}}{{/each}}",
+			map[string]interface{}{"files": []interface{}{}}, []string{"cat"}},
+		{"each in quotes concatenates", `echo "{{#each files}}{{.}};{{/each}}"`,
+			map[string]interface{}{"files": []interface{}{"a", "b"}}, []string{"echo", "a;b;"}},
+		{"each with filters", "x {{#each files}}{{.|stem}}{{/each}}",
+			map[string]interface{}{"files": []interface{}{"/d/a.mov", "/d/b.mp4"}}, []string{"x", "a", "b"}},
+		{"nested blocks", "x {{#each files}}{{#if out}}-o {{out}}/{{.}}{{/if}}{{/each}}",
+			map[string]interface{}{"files": []interface{}{"a", "b"}, "out": "dir"},
+			[]string{"x", "-o", "dir/a", "-o", "dir/b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := Parse(tt.command)
+			if err != nil {
+				t.Fatalf("Parse(%q) error: %v", tt.command, err)
+			}
+			got, err := tmpl.Render(tt.values, defs)
+			if err != nil {
+				t.Fatalf("Render error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Render(%q) = %q, want %q", tt.command, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBlockErrors(t *testing.T) {
+	tests := []struct {
+		command string
+		want    string
+	}{
+		{"x {{#if a}}y", "unclosed {{#if a}} block at offset 2"},
+		{"x {{#each a}}{{.}}", "unclosed {{#each a}} block at offset 2"},
+		{"x {{/if}}", "unexpected {{/if}} at offset 2"},
+		{"x {{#if a}}y{{/each}}", "{{/each}} at offset 12 closes {{#if a}}"},
+		{"x {{else}}", "{{else}} outside of an {{#if}} block"},
+		{"x {{#each a}}{{else}}{{/each}}", "{{else}} outside of an {{#if}} block"},
+		{"x {{#if a}}1{{else}}2{{else}}3{{/if}}", "second {{else}} in {{#if a}}"},
+		{"x {{.}}", "{{.}} outside of an {{#each}} block at offset 2"},
+		{"x {{#unless a}}y{{/unless}}", "unknown block {{#unless a}}"},
+		{"x {{#if}}y{{/if}}", "invalid block {{#if}}"},
+		{"x {{#if a b}}y{{/if}}", "invalid block {{#if a b}}"},
+		{"x {{/for}}", "unknown block end {{/for}}"},
+	}
+	for _, tt := range tests {
+		_, err := Parse(tt.command)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Parse(%q) error = %v, want it to contain %q", tt.command, err, tt.want)
+		}
+	}
+}
+
+func TestPlaceholderNames(t *testing.T) {
+	valid := []string{"{{a}}", "{{input_file}}", "{{_x1}}", "{{ crf }}", "{{#if crf}}x{{/if}}",
+		"{{max-size}}", "{{2pass}}", "{{a.b}}", "{{输入}}", "{{#if my-var}}x{{/if}}", "{{#each 2x}}{{.}}{{/each}}"}
+	for _, command := range valid {
+		if _, err := Parse("x " + command); err != nil {
+			t.Errorf("Parse(%q) error: %v", command, err)
+		}
+	}
+	invalid := []string{"{{my var}}", "{{ my var }}", "{{.1}}", "{{a{b}}", "{{#if .x}}x{{/if}}", "{{#each /x}}{{.}}{{/each}}"}
+	for _, command := range invalid {
+		_, err := Parse("x " + command)
+		if err == nil || !strings.Contains(err.Error(), "invalid variable name") {
+			t.Errorf("Parse(%q) error = %v, want an invalid variable name error", command, err)
+		}
+		if _, err := Placeholders(command); err == nil && !strings.HasPrefix(command, "{{#") {
+			t.Errorf("Placeholders(%q) accepted an invalid name", command)
+		}
+	}
+}
+
+func TestVariables(t *testing.T) {
+	tmpl, err := Parse("x {{#if crf}}-crf {{crf}}{{/if}} {{#each files}}-i {{.}}{{/each}} {{out|stem}} {{files}}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"crf", "files", "out"}
+	if got := tmpl.Variables(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Variables() = %q, want %q", got, want)
+	}
+}
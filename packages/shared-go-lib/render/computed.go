@@ -0,0 +1,127 @@
+package render
+
+import (
+	"fmt"
+	"strings"
+
+	"repo/shared-go-lib/models"
+)
+
+// Expression returns the expression a variable's value is derived from: its
+// `computed` field, or a string default that contains placeholders such as
+// "{{input|stem}}_out.mp4". It is empty for ordinary variables.
+func Expression(def models.VariableDefinition) string {
+	if def.Computed != "" {
+		return def.Computed
+	}
+	if s, ok := def.Options["default"].(string); ok && strings.Contains(s, "{{") {
+		return s
+	}
+	return ""
+}
+
+// ExpressionOrder returns the variables that have an expression, ordered so
+// that every variable comes after the variables its expression refers to.
+// It fails when the expressions refer to each other in a cycle.
+func ExpressionOrder(defs []models.VariableDefinition) ([]string, error) {
+	deps := map[string][]string{}
+	var names []string
+	for _, def := range defs {
+		expr := Expression(def)
+		if expr == "" {
+			continue
+		}
+		refs, err := Placeholders(expr)
+		if err != nil {
+			return nil, fmt.Errorf("variable '%s': %v", def.Name, err)
+		}
+		deps[def.Name] = refs
+		names = append(names, def.Name)
+	}
+
+	const (
+		visiting = 1
+		done     = 2
+	)
+	state := map[string]int{}
+	var order []string
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		switch state[name] {
+		case done:
+			return nil
+		case visiting:
+			return fmt.Errorf("computed variables form a cycle: %s", strings.Join(append(path, name), " -> "))
+		}
+		state[name] = visiting
+		for _, ref := range deps[name] {
+			if _, ok := deps[ref]; !ok {
+				continue // 普通变量, 不依赖其他变量
+			}
+			if err := visit(ref, append(path, name)); err != nil {
+				return err
+			}
+		}
+		state[name] = done
+		order = append(order, name)
+		return nil
+	}
+	for _, name := range names {
+		if err := visit(name, nil); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+// Computed evaluates the expressions of cmd's variables against values and
+// returns the derived values. A variable that already has a value keeps it,
+// so users can override a derived value; an expression whose placeholders
+// are not all filled in yields no value.
+func Computed(cmd models.Command, values map[string]interface{}) (map[string]interface{}, error) {
+	order, err := ExpressionOrder(cmd.Variables)
+	if err != nil {
+		return nil, err
+	}
+	byName := make(map[string]*models.VariableDefinition, len(cmd.Variables))
+	for i := range cmd.Variables {
+		byName[cmd.Variables[i].Name] = &cmd.Variables[i]
+	}
+	current := make(map[string]interface{}, len(values))
+	for k, v := range values {
+		current[k] = v
+	}
+	derived := map[string]interface{}{}
+	for _, name := range order {
+		if !isEmpty(current[name]) {
+			continue
+		}
+		s, complete, err := interpolate(Expression(*byName[name]), current, byName)
+		if err != nil {
+			return nil, fmt.Errorf("variable '%s': %v", name, err)
+		}
+		if !complete {
+			continue
+		}
+		current[name] = s
+		derived[name] = s
+	}
+	return derived, nil
+}
+
+// ApplyComputed returns a copy of values with the derived values of cmd's
+// variables filled in.
+func ApplyComputed(cmd models.Command, values map[string]interface{}) (map[string]interface{}, error) {
+	derived, err := Computed(cmd, values)
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]interface{}, len(values)+len(derived))
+	for k, v := range values {
+		out[k] = v
+	}
+	for k, v := range derived {
+		out[k] = v
+	}
+	return out, nil
+}
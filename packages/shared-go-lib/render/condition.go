@@ -0,0 +1,418 @@
+package render
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"repo/shared-go-lib/models"
+)
+
+// Condition is a parsed show_if or required_if expression such as
+// "mode == cbr", "!fast" or "format == mp4 && (crf > 20 || preset)".
+//
+// A bare name is true when the variable is set, like {{#if name}}. The
+// comparisons ==, != , <, <=, > and >= compare the variable's value with a
+// word or a quoted string; the ordering operators need numbers on both
+// sides. For list values == and != test membership. Conditions combine
+// with !, && and || and parentheses.
+type Condition struct {
+	source string
+	root   *condNode
+}
+
+type condNode struct {
+	op          string // "||", "&&", "!", a comparison, or "" for a bare name
+	name, value string
+	left, right *condNode
+}
+
+// ParseCondition parses a show_if or required_if expression.
+func ParseCondition(s string) (*Condition, error) {
+	toks, err := condTokens(s)
+	if err != nil {
+		return nil, err
+	}
+	p := &condParser{toks: toks}
+	root, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.toks) {
+		return nil, fmt.Errorf("unexpected '%s' in condition '%s'", p.toks[p.pos].text, s)
+	}
+	return &Condition{source: s, root: root}, nil
+}
+
+// Variables returns the variables the condition refers to, in order of
+// first appearance.
+func (c *Condition) Variables() []string {
+	var names []string
+	seen := map[string]bool{}
+	var visit func(n *condNode)
+	visit = func(n *condNode) {
+		if n == nil {
+			return
+		}
+		if n.name != "" && !seen[n.name] {
+			seen[n.name] = true
+			names = append(names, n.name)
+		}
+		visit(n.left)
+		visit(n.right)
+	}
+	visit(c.root)
+	return names
+}
+
+func (c *Condition) eval(values map[string]interface{}, byName map[string]*models.VariableDefinition) bool {
+	return evalNode(c.root, values, byName)
+}
+
+func evalNode(n *condNode, values map[string]interface{}, byName map[string]*models.VariableDefinition) bool {
+	switch n.op {
+	case "||":
+		return evalNode(n.left, values, byName) || evalNode(n.right, values, byName)
+	case "&&":
+		return evalNode(n.left, values, byName) && evalNode(n.right, values, byName)
+	case "!":
+		return !evalNode(n.left, values, byName)
+	case "":
+		return truthy(n.name, values, byName)
+	}
+
+	var texts []string
+	if v, found := values[n.name]; found {
+		def := byName[n.name]
+		switch list, isList := toList(v); {
+		case def != nil && def.Type == models.VarTypeBoolean:
+			texts = []string{strconv.FormatBool(isTruthy(v))}
+		case isList:
+			for _, item := range list {
+				texts = append(texts, formatScalar(item))
+			}
+		case !isEmpty(v):
+			texts = []string{formatScalar(v)}
+		}
+	}
+
+	switch n.op {
+	case "==", "!=":
+		match := false
+		for _, t := range texts {
+			match = match || t == n.value
+		}
+		if len(texts) == 0 {
+			match = n.value == ""
+		}
+		return match == (n.op == "==")
+	}
+	if len(texts) != 1 {
+		return false
+	}
+	a, errA := strconv.ParseFloat(strings.TrimSpace(texts[0]), 64)
+	b, errB := strconv.ParseFloat(n.value, 64)
+	if errA != nil || errB != nil {
+		return false
+	}
+	switch n.op {
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	case ">":
+		return a > b
+	case ">=":
+		return a >= b
+	}
+	return false
+}
+
+type condToken struct {
+	text   string
+	quoted bool
+}
+
+// condOperators are the operator tokens, longest first.
+var condOperators = []string{"==", "!=", "<=", ">=", "&&", "||", "<", ">", "!", "(", ")"}
+
+func condTokens(s string) ([]condToken, error) {
+	var toks []condToken
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t':
+			i++
+			continue
+		case c == '\'' || c == '"':
+			end := strings.IndexByte(s[i+1:], c)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated %c quote in condition '%s'", c, s)
+			}
+			toks = append(toks, condToken{text: s[i+1 : i+1+end], quoted: true})
+			i += end + 2
+			continue
+		}
+		op := ""
+		for _, o := range condOperators {
+			if strings.HasPrefix(s[i:], o) {
+				op = o
+				break
+			}
+		}
+		if op != "" {
+			toks = append(toks, condToken{text: op})
+			i += len(op)
+			continue
+		}
+		start := i
+		for i < len(s) && isWordByte(s[i]) {
+			i++
+		}
+		if i == start {
+			return nil, fmt.Errorf("unexpected '%c' in condition '%s'", c, s)
+		}
+		toks = append(toks, condToken{text: s[start:i]})
+	}
+	if len(toks) == 0 {
+		return nil, fmt.Errorf("empty condition")
+	}
+	return toks, nil
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || c == '-' || c == '.' || c == '/' || c == ':' || unicode.IsLetter(rune(c)) || unicode.IsDigit(rune(c)) || c >= 0x80
+}
+
+type condParser struct {
+	toks []condToken
+	pos  int
+}
+
+func (p *condParser) peek(op string) bool {
+	return p.pos < len(p.toks) && !p.toks[p.pos].quoted && p.toks[p.pos].text == op
+}
+
+func (p *condParser) or() (*condNode, error) {
+	left, err := p.and()
+	for err == nil && p.peek("||") {
+		p.pos++
+		var right *condNode
+		if right, err = p.and(); err == nil {
+			left = &condNode{op: "||", left: left, right: right}
+		}
+	}
+	return left, err
+}
+
+func (p *condParser) and() (*condNode, error) {
+	left, err := p.not()
+	for err == nil && p.peek("&&") {
+		p.pos++
+		var right *condNode
+		if right, err = p.not(); err == nil {
+			left = &condNode{op: "&&", left: left, right: right}
+		}
+	}
+	return left, err
+}
+
+func (p *condParser) not() (*condNode, error) {
+	if p.peek("!") {
+		p.pos++
+		inner, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		return &condNode{op: "!", left: inner}, nil
+	}
+	if p.peek("(") {
+		p.pos++
+		inner, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if !p.peek(")") {
+			return nil, fmt.Errorf("missing ')' in condition")
+		}
+		p.pos++
+		return inner, nil
+	}
+	if p.pos >= len(p.toks) {
+		return nil, fmt.Errorf("condition ends unexpectedly")
+	}
+	tok := p.toks[p.pos]
+	if tok.quoted || !ValidVariableName(tok.text) {
+		return nil, fmt.Errorf("expected a variable name, found '%s'", tok.text)
+	}
+	p.pos++
+	n := &condNode{name: tok.text}
+	for _, op := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if !p.peek(op) {
+			continue
+		}
+		p.pos++
+		if p.pos >= len(p.toks) || (!p.toks[p.pos].quoted && isCondOperator(p.toks[p.pos].text)) {
+			return nil, fmt.Errorf("missing value after '%s %s'", tok.text, op)
+		}
+		n.op, n.value = op, p.toks[p.pos].text
+		p.pos++
+		break
+	}
+	return n, nil
+}
+
+func isCondOperator(s string) bool {
+	for _, o := range condOperators {
+		if s == o {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidVariableName reports whether name can name a variable: any text
+// without whitespace, braces or "|", not starting with ".", "#" or "/",
+// which mark Go template actions and blocks. "else" is taken by blocks too.
+func ValidVariableName(name string) bool {
+	if name == "" || name == "else" || strings.ContainsAny(name[:1], ".#/") {
+		return false
+	}
+	for _, r := range name {
+		if unicode.IsSpace(r) || strings.ContainsRune("{}|", r) {
+			return false
+		}
+	}
+	return true
+}
+
+// FieldState is the outcome of a variable's show_if and required_if for
+// the current values.
+type FieldState struct {
+	Hidden   bool `json:"hidden"`
+	Required bool `json:"required"`
+}
+
+// FieldStates evaluates the show_if and required_if expressions of defs.
+// A hidden variable counts as unset in the conditions of other variables,
+// so fields that depend on a hidden field are hidden as well. Required is
+// never set for hidden variables.
+func FieldStates(defs []models.VariableDefinition, values map[string]interface{}) (map[string]FieldState, error) {
+	byName := make(map[string]*models.VariableDefinition, len(defs))
+	for i := range defs {
+		byName[defs[i].Name] = &defs[i]
+	}
+	visible, hidden, err := hideValues(values, byName)
+	if err != nil {
+		return nil, err
+	}
+	states := make(map[string]FieldState, len(defs))
+	for _, def := range defs {
+		st := FieldState{Hidden: hidden[def.Name]}
+		if !st.Hidden {
+			st.Required = def.Required
+			if def.RequiredIf != "" && !st.Required {
+				cond, err := ParseCondition(def.RequiredIf)
+				if err != nil {
+					return nil, fmt.Errorf("variable '%s' required_if: %v", def.Name, err)
+				}
+				st.Required = cond.eval(visible, byName)
+			}
+		}
+		states[def.Name] = st
+	}
+	return states, nil
+}
+
+// VisibleValues returns a copy of values without the variables that
+// show_if hides.
+func VisibleValues(cmd models.Command, values map[string]interface{}) (map[string]interface{}, error) {
+	byName := make(map[string]*models.VariableDefinition, len(cmd.Variables))
+	for i := range cmd.Variables {
+		byName[cmd.Variables[i].Name] = &cmd.Variables[i]
+	}
+	visible, _, err := hideValues(values, byName)
+	return visible, err
+}
+
+// ConditionOrder returns the variables with a show_if, ordered so that
+// every variable comes after the variables its condition refers to. It
+// fails when the conditions refer to each other in a cycle.
+func ConditionOrder(defs []models.VariableDefinition) ([]string, error) {
+	byName := make(map[string]*models.VariableDefinition, len(defs))
+	for i := range defs {
+		byName[defs[i].Name] = &defs[i]
+	}
+	return conditionOrder(byName)
+}
+
+func conditionOrder(byName map[string]*models.VariableDefinition) ([]string, error) {
+	deps := map[string][]string{}
+	var names []string
+	for name, def := range byName {
+		if def.ShowIf == "" {
+			continue
+		}
+		cond, err := ParseCondition(def.ShowIf)
+		if err != nil {
+			return nil, fmt.Errorf("variable '%s' show_if: %v", name, err)
+		}
+		deps[name] = cond.Variables()
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	state := map[string]int{} // 1 访问中, 2 已完成
+	var order []string
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		switch state[name] {
+		case 2:
+			return nil
+		case 1:
+			return fmt.Errorf("show_if conditions form a cycle: %s", strings.Join(append(path, name), " -> "))
+		}
+		state[name] = 1
+		for _, ref := range deps[name] {
+			if _, ok := deps[ref]; !ok {
+				continue
+			}
+			if err := visit(ref, append(path, name)); err != nil {
+				return err
+			}
+		}
+		state[name] = 2
+		order = append(order, name)
+		return nil
+	}
+	for _, name := range names {
+		if err := visit(name, nil); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+// hideValues evaluates the show_if conditions in dependency order and
+// returns the values without the hidden variables, and the hidden set.
+func hideValues(values map[string]interface{}, byName map[string]*models.VariableDefinition) (map[string]interface{}, map[string]bool, error) {
+	order, err := conditionOrder(byName)
+	if err != nil || len(order) == 0 {
+		return values, nil, err
+	}
+	visible := make(map[string]interface{}, len(values))
+	for k, v := range values {
+		visible[k] = v
+	}
+	hidden := map[string]bool{}
+	for _, name := range order {
+		cond, _ := ParseCondition(byName[name].ShowIf) // conditionOrder 已检查
+		if !cond.eval(visible, byName) {
+			hidden[name] = true
+			delete(visible, name)
+		}
+	}
+	return visible, hidden, nil
+}
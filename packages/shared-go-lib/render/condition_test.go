@@ -0,0 +1,209 @@
+package render
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"repo/shared-go-lib/models"
+)
+
+func TestConditionEval(t *testing.T) {
+	defs := []models.VariableDefinition{
+		{Name: "fast", Type: models.VarTypeBoolean},
+		{Name: "crf", Type: models.VarTypeNumber},
+		{Name: "mode", Type: models.VarTypeSelect},
+		{Name: "tags", Type: models.VarTypeMultiSelect},
+		{Name: "title", Type: models.VarTypeText},
+	}
+	byName := map[string]*models.VariableDefinition{}
+	for i := range defs {
+		byName[defs[i].Name] = &defs[i]
+	}
+	values := map[string]interface{}{
+		"fast":  false,
+		"crf":   23.0,
+		"mode":  "cbr",
+		"tags":  []interface{}{"hdr", "4k"},
+		"title": "",
+	}
+	tests := []struct {
+		cond string
+		want bool
+	}{
+		{"mode", true},
+		{"title", false},
+		{"missing", false},
+		{"fast", false},
+		{"!fast", true},
+		{"fast == false", true},
+		{"mode == cbr", true},
+		{"mode == 'cbr'", true},
+		{`mode == "vbr"`, false},
+		{"mode != vbr", true},
+		{"title == ''", true},
+		{"missing != x", true},
+		{"crf == 23", true},
+		{"crf < 30", true},
+		{"crf <= 23", true},
+		{"crf > 23", false},
+		{"crf >= 23.0", true},
+		{"mode > 1", false},
+		{"tags < 1", false},
+		{"tags == 4k", true},
+		{"tags == sdr", false},
+		{"tags != sdr", true},
+		{"mode == vbr || crf > 20", true},
+		{"mode == cbr && fast", false},
+		{"mode == vbr && crf > 20 || tags == hdr", true},
+		{"mode == vbr && (crf > 20 || tags == hdr)", false},
+		{"max-rate", false},
+		{"!(mode == vbr) && !!mode", true},
+	}
+	for _, tt := range tests {
+		cond, err := ParseCondition(tt.cond)
+		if err != nil {
+			t.Errorf("ParseCondition(%q) error: %v", tt.cond, err)
+			continue
+		}
+		if got := cond.eval(values, byName); got != tt.want {
+			t.Errorf("%q = %v, want %v", tt.cond, got, tt.want)
+		}
+	}
+}
+
+func TestConditionVariables(t *testing.T) {
+	cond, err := ParseCondition("format == mp4 && (crf > 20 || preset) && !format")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"format", "crf", "preset"}
+	if got := cond.Variables(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Variables() = %q, want %q", got, want)
+	}
+}
+
+func TestConditionErrors(t *testing.T) {
+	tests := []struct {
+		cond string
+		want string
+	}{
+		{"", "empty condition"},
+		{"   ", "empty condition"},
+		{"mode == 'cbr", "unterminated ' quote"},
+		{"mode == cbr)", "unexpected ')'"},
+		{"(mode", "missing ')'"},
+		{"mode &&", "condition ends unexpectedly"},
+		{"mode ==", "missing value after 'mode =='"},
+		{"mode == && fast", "missing value after 'mode =='"},
+		{"'mode' == cbr", "expected a variable name, found 'mode'"},
+		{".x", "expected a variable name, found '.x'"},
+		{"a b", "unexpected 'b'"},
+		{"mode = cbr", "unexpected '='"},
+	}
+	for _, tt := range tests {
+		_, err := ParseCondition(tt.cond)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("ParseCondition(%q) error = %v, want it to contain %q", tt.cond, err, tt.want)
+		}
+	}
+}
+
+func TestFieldStates(t *testing.T) {
+	defs := []models.VariableDefinition{
+		{Name: "mode", Type: models.VarTypeSelect},
+		{Name: "bitrate", Type: models.VarTypeText, ShowIf: "mode == cbr", Required: true},
+		{Name: "maxrate", Type: models.VarTypeText, ShowIf: "bitrate"},
+		{Name: "crf", Type: models.VarTypeNumber, ShowIf: "mode != cbr"},
+		{Name: "out", Type: models.VarTypeText, RequiredIf: "crf > 20"},
+	}
+	tests := []struct {
+		name   string
+		values map[string]interface{}
+		want   map[string]FieldState
+	}{
+		{"cbr", map[string]interface{}{"mode": "cbr", "bitrate": "2M", "crf": 28.0},
+			map[string]FieldState{
+				"mode":    {},
+				"bitrate": {Required: true},
+				"maxrate": {},
+				"crf":     {Hidden: true},
+				"out":     {},
+			}},
+		{"hidden dependency hides its dependents", map[string]interface{}{"mode": "crf", "bitrate": "2M", "crf": 28.0},
+			map[string]FieldState{
+				"mode":    {},
+				"bitrate": {Hidden: true},
+				"maxrate": {Hidden: true},
+				"crf":     {},
+				"out":     {Required: true},
+			}},
+		{"required_if false", map[string]interface{}{"mode": "crf", "crf": 18.0},
+			map[string]FieldState{
+				"mode":    {},
+				"bitrate": {Hidden: true},
+				"maxrate": {Hidden: true},
+				"crf":     {},
+				"out":     {},
+			}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := FieldStates(defs, tt.values)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FieldStates = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVisibleValues(t *testing.T) {
+	cmd := models.Command{Variables: []models.VariableDefinition{
+		{Name: "mode", Type: models.VarTypeSelect},
+		{Name: "bitrate", Type: models.VarTypeText, ShowIf: "mode == cbr"},
+		{Name: "maxrate", Type: models.VarTypeText, ShowIf: "bitrate"},
+	}}
+	values := map[string]interface{}{"mode": "crf", "bitrate": "2M", "maxrate": "4M"}
+	got, err := VisibleValues(cmd, values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{"mode": "crf"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("VisibleValues = %v, want %v", got, want)
+	}
+	if len(values) != 3 {
+		t.Errorf("VisibleValues changed its input: %v", values)
+	}
+}
+
+func TestConditionOrder(t *testing.T) {
+	order, err := ConditionOrder([]models.VariableDefinition{
+		{Name: "c", ShowIf: "b"},
+		{Name: "a"},
+		{Name: "b", ShowIf: "a == x"},
+		{Name: "d", ShowIf: "c && b"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"b", "c", "d"}; !reflect.DeepEqual(order, want) {
+		t.Errorf("ConditionOrder = %q, want %q", order, want)
+	}
+
+	_, err = ConditionOrder([]models.VariableDefinition{
+		{Name: "a", ShowIf: "b"},
+		{Name: "b", ShowIf: "!a"},
+	})
+	if err == nil || !strings.Contains(err.Error(), "show_if conditions form a cycle: a -> b -> a") {
+		t.Errorf("ConditionOrder error = %v, want a cycle error", err)
+	}
+
+	_, err = ConditionOrder([]models.VariableDefinition{{Name: "a", ShowIf: "a == ("}})
+	if err == nil || !strings.Contains(err.Error(), "variable 'a' show_if") {
+		t.Errorf("ConditionOrder error = %v, want a show_if parse error", err)
+	}
+}
@@ -0,0 +1,97 @@
+package render
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// dateLayouts are the forms a date or datetime value may arrive in: ISO 8601
+// from the form's date picker, with or without time and zone.
+var dateLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02",
+}
+
+// ParseDate parses a date or datetime value. Values with a zone are
+// converted to local time.
+func ParseDate(s string) (time.Time, bool) {
+	s = strings.TrimSpace(s)
+	for _, layout := range dateLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t.Local(), true
+		}
+	}
+	return time.Time{}, false
+}
+
+// strftime maps the supported % directives to Go layouts.
+var strftime = map[byte]string{
+	'Y': "2006",
+	'y': "06",
+	'm': "01",
+	'd': "02",
+	'H': "15",
+	'I': "03",
+	'M': "04",
+	'S': "05",
+	'p': "PM",
+	'b': "Jan",
+	'B': "January",
+	'a': "Mon",
+	'A': "Monday",
+	'z': "-0700",
+	'Z': "MST",
+}
+
+// FormatDate formats t with a strftime-style format. Besides the
+// directives in strftime it supports %j (day of the year), %s (Unix
+// seconds) and %%.
+func FormatDate(t time.Time, format string) string {
+	var sb strings.Builder
+	for i := 0; i < len(format); i++ {
+		c := format[i]
+		if c != '%' || i+1 == len(format) {
+			sb.WriteByte(c)
+			continue
+		}
+		i++
+		switch d := format[i]; d {
+		case '%':
+			sb.WriteByte('%')
+		case 'j':
+			fmt.Fprintf(&sb, "%03d", t.YearDay())
+		case 's':
+			fmt.Fprintf(&sb, "%d", t.Unix())
+		default:
+			if layout, ok := strftime[d]; ok {
+				sb.WriteString(t.Format(layout))
+			} else {
+				sb.WriteByte('%')
+				sb.WriteByte(d)
+			}
+		}
+	}
+	return sb.String()
+}
+
+// ValidDateFormat reports whether format only uses supported directives.
+func ValidDateFormat(format string) error {
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			continue
+		}
+		if i+1 == len(format) {
+			return fmt.Errorf("format ends with a lone %%")
+		}
+		i++
+		if _, ok := strftime[format[i]]; !ok && !strings.ContainsRune("%js", rune(format[i])) {
+			return fmt.Errorf("unsupported directive %%%c", format[i])
+		}
+	}
+	return nil
+}
@@ -0,0 +1,218 @@
+package render
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"repo/shared-go-lib/models"
+)
+
+// envNamePattern matches the names accepted for environment variables.
+var envNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ValidEnvName reports whether name can be used as an environment variable.
+func ValidEnvName(name string) bool {
+	return envNamePattern.MatchString(name)
+}
+
+// Placeholders returns the names of the {{placeholders}} in text, in order
+// of first appearance.
+func Placeholders(text string) ([]string, error) {
+	var names []string
+	seen := map[string]struct{}{}
+	err := scanPlaceholders(text, func(lit string) {}, func(name string, _ []filter) {
+		if _, ok := seen[name]; !ok {
+			seen[name] = struct{}{}
+			names = append(names, name)
+		}
+	})
+	return names, err
+}
+
+// Interpolate substitutes values into plain text such as a working
+// directory or an environment value. Unlike Render no quoting rules apply
+// and the result is a single string; placeholders without a value render as
+// the empty string.
+func Interpolate(text string, values map[string]interface{}, defs []models.VariableDefinition) (string, error) {
+	byName := make(map[string]*models.VariableDefinition, len(defs))
+	for i := range defs {
+		byName[defs[i].Name] = &defs[i]
+	}
+	s, _, err := interpolate(text, values, byName)
+	return s, err
+}
+
+// interpolate implements Interpolate and also reports whether every
+// placeholder had a non-empty value.
+func interpolate(text string, values map[string]interface{}, byName map[string]*models.VariableDefinition) (string, bool, error) {
+	values, _, err := hideValues(values, byName)
+	if err != nil {
+		return "", false, err
+	}
+	values = normalizeValues(values, byName)
+	var sb strings.Builder
+	complete := true
+	err = scanPlaceholders(text, func(lit string) { sb.WriteString(lit) }, func(name string, filters []filter) {
+		v, ok := values[name]
+		v, ok = filterValue(byName[name], v, ok, filters)
+		if !ok || isEmpty(v) {
+			complete = false
+			return
+		}
+		sb.WriteString(formatValue(byName[name], v))
+	})
+	if err != nil {
+		return "", false, err
+	}
+	return sb.String(), complete, nil
+}
+
+// Workdir renders cmd.Workdir. A leading "~" is expanded; an empty result
+// means the working directory is inherited.
+func Workdir(cmd models.Command, values map[string]interface{}) (string, error) {
+	if cmd.Workdir == "" {
+		return "", nil
+	}
+	dir, err := Interpolate(cmd.Workdir, values, cmd.Variables)
+	if err != nil {
+		return "", fmt.Errorf("workdir: %w", err)
+	}
+	return ExpandHome(strings.TrimSpace(dir)), nil
+}
+
+// Env renders the environment a command adds to the inherited one: the
+// interpolated cmd.Env entries plus every variable with an env name. Empty
+// values of env variables are left out so the inherited value, if any,
+// still applies.
+func Env(cmd models.Command, values map[string]interface{}) (map[string]string, error) {
+	values, err := VisibleValues(cmd, values)
+	if err != nil {
+		return nil, err
+	}
+	env := make(map[string]string, len(cmd.Env))
+	for name, text := range cmd.Env {
+		value, err := Interpolate(text, values, cmd.Variables)
+		if err != nil {
+			return nil, fmt.Errorf("env %s: %w", name, err)
+		}
+		env[name] = value
+	}
+	for i := range cmd.Variables {
+		def := &cmd.Variables[i]
+		if def.Env == "" {
+			continue
+		}
+		v, found := values[def.Name]
+		if !found || isEmpty(v) {
+			continue
+		}
+		env[def.Env] = formatValue(def, v)
+	}
+	return env, nil
+}
+
+// Stdin resolves the variable named by cmd.Stdin. For a file_input variable
+// it returns the path of the file to stream, otherwise the text to write.
+// Both are empty when the command has no stdin or the value is empty.
+func Stdin(cmd models.Command, values map[string]interface{}) (file, text string) {
+	if cmd.Stdin == "" {
+		return "", ""
+	}
+	values, err := VisibleValues(cmd, values)
+	if err != nil {
+		return "", ""
+	}
+	for i := range cmd.Variables {
+		def := &cmd.Variables[i]
+		if def.Name != cmd.Stdin {
+			continue
+		}
+		v, found := values[def.Name]
+		if !found || isEmpty(v) {
+			return "", ""
+		}
+		if def.Type == models.VarTypeFileInput {
+			return ExpandHome(formatValue(def, v)), ""
+		}
+		return "", formatValue(def, v)
+	}
+	return "", ""
+}
+
+// StdinRedirect formats the stdin of a command for a command line preview:
+// "< file" for a file, otherwise a quoted heredoc holding text. It returns
+// the empty string when there is no stdin.
+func StdinRedirect(file, text string) string {
+	if file != "" {
+		return "< " + Quote(file)
+	}
+	if text == "" {
+		return ""
+	}
+	// 选择一个不会出现在内容中的结束标记
+	delim := "EOF"
+	for i := 1; containsLine(text, delim); i++ {
+		delim = fmt.Sprintf("EOF_%d", i)
+	}
+	return "<<'" + delim + "'\n" + strings.TrimSuffix(text, "\n") + "\n" + delim
+}
+
+func containsLine(text, line string) bool {
+	for _, l := range strings.Split(text, "\n") {
+		if strings.TrimRight(l, "\r") == line {
+			return true
+		}
+	}
+	return false
+}
+
+// EnvPrefix formats env as shell assignments, sorted by name, for a
+// command line preview such as "LANG=C tool ...".
+func EnvPrefix(env map[string]string) string {
+	names := make([]string, 0, len(env))
+	for name := range env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = name + "=" + Quote(env[name])
+	}
+	return strings.Join(parts, " ")
+}
+
+// scanPlaceholders calls lit for literal text and ph for every placeholder
+// name, with its filters, in text.
+func scanPlaceholders(text string, lit func(string), ph func(string, []filter)) error {
+	for i := 0; i < len(text); {
+		start := strings.Index(text[i:], "{{")
+		if start < 0 {
+			lit(text[i:])
+			return nil
+		}
+		start += i
+		lit(text[i:start])
+		end := strings.Index(text[start+2:], "}}")
+		if end < 0 {
+			return fmt.Errorf("unclosed placeholder at offset %d", start)
+		}
+		body := strings.TrimSpace(text[start+2 : start+2+end])
+		if goAction(body) {
+			lit(text[start : start+2+end+2])
+			i = start + 2 + end + 2
+			continue
+		}
+		if body == "" {
+			return fmt.Errorf("empty placeholder at offset %d", start)
+		}
+		name, filters, err := parsePlaceholderBody(body)
+		if err != nil {
+			return fmt.Errorf("%v at offset %d", err, start)
+		}
+		ph(name, filters)
+		i = start + 2 + end + 2
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package render
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"repo/shared-go-lib/models"
+)
+
+var envVars = []models.VariableDefinition{
+	{Name: "project", Type: models.VarTypeText},
+	{Name: "threads", Type: models.VarTypeNumber, Env: "OMP_NUM_THREADS"},
+	{Name: "lang", Type: models.VarTypeText, Env: "LANG"},
+}
+
+func TestWorkdir(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skip(err)
+	}
+	tests := []struct {
+		workdir string
+		values  map[string]interface{}
+		want    string
+	}{
+		{"", map[string]interface{}{"project": "a"}, ""},
+		{"/src/{{project}}", map[string]interface{}{"project": "my app"}, "/src/my app"},
+		{" ~/{{project}} ", map[string]interface{}{"project": "a"}, filepath.Join(home, "a")},
+		{"{{project}}", nil, ""},
+	}
+	for _, tt := range tests {
+		got, err := Workdir(models.Command{Workdir: tt.workdir, Variables: envVars}, tt.values)
+		if err != nil || got != tt.want {
+			t.Errorf("Workdir(%q) = %q, %v, want %q", tt.workdir, got, err, tt.want)
+		}
+	}
+	if _, err := Workdir(models.Command{Workdir: "/src/{{project"}, nil); err == nil || !strings.HasPrefix(err.Error(), "workdir: ") {
+		t.Errorf("Workdir of an unclosed placeholder error = %v", err)
+	}
+}
+
+func TestEnv(t *testing.T) {
+	cmd := models.Command{
+		Env:       map[string]string{"APP_HOME": "/src/{{project}}", "EMPTY": "{{missing}}", "LANG": "C"},
+		Variables: envVars,
+	}
+	tests := []struct {
+		name   string
+		values map[string]interface{}
+		want   map[string]string
+	}{
+		{"variables add to env", map[string]interface{}{"project": "a", "threads": 4.0},
+			map[string]string{"APP_HOME": "/src/a", "EMPTY": "", "LANG": "C", "OMP_NUM_THREADS": "4"}},
+		{"a variable overrides env", map[string]interface{}{"lang": "zh_CN.UTF-8"},
+			map[string]string{"APP_HOME": "/src/", "EMPTY": "", "LANG": "zh_CN.UTF-8"}},
+		{"empty variables are left out", map[string]interface{}{"lang": "", "threads": nil},
+			map[string]string{"APP_HOME": "/src/", "EMPTY": "", "LANG": "C"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Env(cmd, tt.values)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Env = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	cmd.Env = map[string]string{"BAD": "{{project"}
+	if _, err := Env(cmd, nil); err == nil || !strings.HasPrefix(err.Error(), "env BAD: ") {
+		t.Errorf("Env of an unclosed placeholder error = %v", err)
+	}
+}
+
+func TestEnvPrefix(t *testing.T) {
+	got := EnvPrefix(map[string]string{"LANG": "C", "APP_HOME": "/my app", "EMPTY": ""})
+	if want := "APP_HOME='/my app' EMPTY='' LANG=C"; got != want {
+		t.Errorf("EnvPrefix = %q, want %q", got, want)
+	}
+	if got := EnvPrefix(nil); got != "" {
+		t.Errorf("EnvPrefix(nil) = %q", got)
+	}
+}
+
+func TestValidEnvName(t *testing.T) {
+	for name, want := range map[string]bool{
+		"LANG": true, "_x1": true, "omp_num_threads": true,
+		"": false, "1X": false, "A-B": false, "A B": false, "A=B": false,
+	} {
+		if got := ValidEnvName(name); got != want {
+			t.Errorf("ValidEnvName(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestPlaceholders(t *testing.T) {
+	got, err := Placeholders("{{b}}/x/{{ a }}-{{b}}")
+	if err != nil || !reflect.DeepEqual(got, []string{"b", "a"}) {
+		t.Errorf("Placeholders = %q, %v", got, err)
+	}
+	if _, err := Placeholders("a {{ }}"); err == nil || !strings.Contains(err.Error(), "empty placeholder") {
+		t.Errorf("Placeholders of an empty placeholder error = %v", err)
+	}
+}
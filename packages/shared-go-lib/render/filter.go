@@ -0,0 +1,129 @@
+package render
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"repo/shared-go-lib/models"
+)
+
+// filter is one "|name" or "|name:arg" step of a placeholder such as
+// {{input|stem}} or {{crf|default:23}}.
+type filter struct {
+	name string
+	arg  string
+}
+
+// filterFuncs are the supported filters. They work on the formatted value,
+// so file paths have "~" expanded already.
+var filterFuncs = map[string]func(value, arg string) string{
+	// stem is the file name without directory and extension
+	"stem": pathFilter(func(v string) string {
+		base := filepath.Base(v)
+		return strings.TrimSuffix(base, filepath.Ext(base))
+	}),
+	"base": pathFilter(filepath.Base),
+	"dir":  pathFilter(filepath.Dir),
+	// ext includes the leading dot, so "{{f|stem}}{{f|ext}}" is the file name
+	"ext":   pathFilter(filepath.Ext),
+	"lower": func(v, _ string) string { return strings.ToLower(v) },
+	"upper": func(v, _ string) string { return strings.ToUpper(v) },
+	"trim":  func(v, _ string) string { return strings.TrimSpace(v) },
+	"default": func(v, arg string) string {
+		if strings.TrimSpace(v) == "" {
+			return arg
+		}
+		return v
+	},
+}
+
+// pathFilter wraps a path function so that an empty value stays empty
+// instead of becoming ".".
+func pathFilter(fn func(string) string) func(value, arg string) string {
+	return func(v, _ string) string {
+		if v == "" {
+			return ""
+		}
+		return fn(v)
+	}
+}
+
+// filtersWithArg are the filters that take an argument.
+var filtersWithArg = map[string]bool{"default": true}
+
+// FilterNames lists the supported filters, for error messages and docs.
+func FilterNames() []string {
+	return []string{"stem", "base", "dir", "ext", "lower", "upper", "trim", "default:<value>"}
+}
+
+// parsePlaceholderBody splits "name|filter|filter:arg" into the variable
+// name and its filters. The name must be a valid variable name, or "." for
+// the element of an {{#each}} block.
+func parsePlaceholderBody(body string) (string, []filter, error) {
+	parts := strings.Split(body, "|")
+	name := strings.TrimSpace(parts[0])
+	if name == "" {
+		return "", nil, fmt.Errorf("missing variable name in {{%s}}", body)
+	}
+	if name != "." && !ValidVariableName(name) {
+		return "", nil, invalidName(name, body)
+	}
+	var filters []filter
+	for _, part := range parts[1:] {
+		fname, arg, hasArg := strings.Cut(strings.TrimSpace(part), ":")
+		fname = strings.TrimSpace(fname)
+		if _, ok := filterFuncs[fname]; !ok {
+			return "", nil, fmt.Errorf("unknown filter '%s' in {{%s}}, supported: %s", fname, body, strings.Join(FilterNames(), ", "))
+		}
+		if hasArg != filtersWithArg[fname] {
+			if hasArg {
+				return "", nil, fmt.Errorf("filter '%s' in {{%s}} takes no argument", fname, body)
+			}
+			return "", nil, fmt.Errorf("filter '%s' in {{%s}} needs an argument, e.g. %s:value", fname, body, fname)
+		}
+		filters = append(filters, filter{name: fname, arg: arg})
+	}
+	return name, filters, nil
+}
+
+// VariableNameRule describes the names ValidVariableName accepts, for
+// error messages.
+const VariableNameRule = "names cannot contain spaces, braces or '|', or start with '.', '#' or '/'"
+
+// invalidName reports a placeholder whose variable name breaks the naming
+// rules, such as {{my var}}.
+func invalidName(name, body string) error {
+	return fmt.Errorf("invalid variable name '%s' in {{%s}}: %s", name, body, VariableNameRule)
+}
+
+// filterValue applies the filters of a placeholder to a variable value;
+// every element of a list is filtered on its own. The result is a string or
+// a list of strings, and counts as found when it is not empty, so that
+// {{n|default:23}} has a value even when n has none.
+func filterValue(def *models.VariableDefinition, v interface{}, found bool, filters []filter) (interface{}, bool) {
+	if len(filters) == 0 {
+		return v, found
+	}
+	if list, ok := toList(v); ok {
+		out := make([]interface{}, 0, len(list))
+		for _, item := range list {
+			out = append(out, applyFilters(formatTyped(def, item), filters))
+		}
+		return out, found || len(out) > 0
+	}
+	s := ""
+	if found {
+		s = formatTyped(def, v)
+	}
+	s = applyFilters(s, filters)
+	return s, found || s != ""
+}
+
+// applyFilters runs value through filters in order.
+func applyFilters(value string, filters []filter) string {
+	for _, f := range filters {
+		value = filterFuncs[f.name](value, f.arg)
+	}
+	return value
+}
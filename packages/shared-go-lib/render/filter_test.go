@@ -0,0 +1,64 @@
+package render
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFilters(t *testing.T) {
+	tests := []struct {
+		command string
+		values  map[string]interface{}
+		want    []string
+	}{
+		{"x {{f|stem}}", map[string]interface{}{"f": "/a/b/clip.final.mov"}, []string{"x", "clip.final"}},
+		{"x {{f|base}}", map[string]interface{}{"f": "/a/b/clip.mov"}, []string{"x", "clip.mov"}},
+		{"x {{f|dir}}", map[string]interface{}{"f": "/a/b/clip.mov"}, []string{"x", "/a/b"}},
+		{"x {{f|ext}}", map[string]interface{}{"f": "/a/b/clip.mov"}, []string{"x", ".mov"}},
+		{"x {{f|ext}}", map[string]interface{}{"f": "/a/b/Makefile"}, []string{"x"}},
+		{"x {{f|stem}}{{f|ext}}", map[string]interface{}{"f": "/a/clip.mov"}, []string{"x", "clip.mov"}},
+		{"x {{f|dir}}/{{f|stem}}_small.mp4", map[string]interface{}{"f": "/a/My Videos/clip.mov"},
+			[]string{"x", "/a/My Videos/clip_small.mp4"}},
+		{`x "{{f|dir}}"`, map[string]interface{}{"f": ""}, []string{"x", ""}},
+		{"x {{s|lower}}", map[string]interface{}{"s": "MiXeD"}, []string{"x", "mixed"}},
+		{"x {{s|upper}}", map[string]interface{}{"s": "MiXeD"}, []string{"x", "MIXED"}},
+		{"x {{s|trim}}", map[string]interface{}{"s": "  v  "}, []string{"x", "v"}},
+		{"x {{s | trim | upper}}", map[string]interface{}{"s": " v "}, []string{"x", "V"}},
+		{"x {{n|default:23}}", map[string]interface{}{"n": ""}, []string{"x", "23"}},
+		{"x {{n|default:23}}", map[string]interface{}{"n": "  "}, []string{"x", "23"}},
+		{"x {{n|default:23}}", map[string]interface{}{"n": "18"}, []string{"x", "18"}},
+		{"x {{n|default:23}}", nil, []string{"x", "23"}},
+		{"x {{n|default:a:b}}", nil, []string{"x", "a:b"}},
+		{"x {{fs|stem}}", map[string]interface{}{"fs": []interface{}{"/a/one.mov", "/b/two.mp4"}},
+			[]string{"x", "one", "two"}},
+	}
+	for _, tt := range tests {
+		got, err := Argv(tt.command, tt.values)
+		if err != nil {
+			t.Errorf("Argv(%q) error: %v", tt.command, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Argv(%q, %v) = %q, want %q", tt.command, tt.values, got, tt.want)
+		}
+	}
+}
+
+func TestFilterErrors(t *testing.T) {
+	tests := []struct {
+		command string
+		want    string
+	}{
+		{"x {{f|basename}}", "unknown filter 'basename' in {{f|basename}}, supported: stem, base"},
+		{"x {{f|stem:1}}", "filter 'stem' in {{f|stem:1}} takes no argument"},
+		{"x {{f|default}}", "filter 'default' in {{f|default}} needs an argument"},
+		{"x {{|stem}}", "missing variable name in {{|stem}}"},
+	}
+	for _, tt := range tests {
+		_, err := Parse(tt.command)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Parse(%q) error = %v, want it to contain %q", tt.command, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,297 @@
+package render
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"repo/shared-go-lib/models"
+)
+
+// renderVar renders a placeholder that forms a whole word on its own, which
+// is where a variable's type and arg_name take effect:
+//
+//   - boolean: true renders the flag (arg_name, or --name), false renders
+//     nothing; the true_value / false_value options replace either
+//   - arg_name set: a value renders as "arg_name value", or as one element
+//     "arg_name=value" when arg_name ends with "="
+//   - an empty value renders nothing, together with its flag
+//
+// The arg_name pairing and dropping apply to required variables as well as
+// optional ones, so a required "-i" input renders as "-i value", unless the
+// template already writes the flag, see Template.Render. Required
+// variables are checked to be set before a command runs, so in practice only
+// optional ones are dropped; a required variable missing from values keeps
+// its placeholder, as in a preview of an unfinished form.
+//
+// List values repeat the flag for every element. handled is false when the
+// placeholder should be left as literal text.
+func renderVar(def *models.VariableDefinition, v interface{}, found bool) (args []string, handled bool) {
+	if !found && def.Required {
+		return nil, false
+	}
+
+	if def.Type == models.VarTypeBoolean {
+		opts := def.BooleanOptions()
+		switch {
+		case isTruthy(v) && opts.TrueValue != "":
+			return []string{opts.TrueValue}, true
+		case isTruthy(v):
+			return []string{flagName(def)}, true
+		case opts.FalseValue != "":
+			return []string{opts.FalseValue}, true
+		}
+		return nil, true
+	}
+
+	var items []interface{}
+	if list, ok := toList(v); ok {
+		items = list
+	} else {
+		items = []interface{}{v}
+	}
+	// multi_select / file_list with a separator form a single argument
+	if sep := def.Separator(); sep != "" && len(items) > 0 {
+		joined := joinList(def, items, sep)
+		items = []interface{}{joined}
+	}
+
+	for _, item := range items {
+		if isEmpty(item) {
+			continue
+		}
+		value := formatTyped(def, item)
+		switch {
+		case def.ArgName == "":
+			args = append(args, value)
+		case strings.HasSuffix(def.ArgName, "="):
+			args = append(args, flagName(def)+value)
+		default:
+			args = append(args, flagName(def), value)
+		}
+	}
+	return args, true
+}
+
+// flagName returns the command-line flag of a variable. arg_name is used
+// verbatim when it already starts with "-"; otherwise a single letter gets
+// "-" and anything longer gets "--". Without arg_name the variable name is
+// used.
+func flagName(def *models.VariableDefinition) string {
+	name := def.ArgName
+	if name == "" {
+		name = def.Name
+	}
+	if strings.HasPrefix(name, "-") {
+		return name
+	}
+	if len(strings.TrimSuffix(name, "=")) == 1 {
+		return "-" + name
+	}
+	return "--" + name
+}
+
+// formatValue converts a variable value into the text substituted into a
+// larger word. Lists are joined with the variable's separator, single
+// spaces by default.
+func formatValue(def *models.VariableDefinition, v interface{}) string {
+	if list, ok := toList(v); ok {
+		sep := " "
+		if def != nil && def.Separator() != "" {
+			sep = def.Separator()
+		}
+		return joinList(def, list, sep)
+	}
+	return formatTyped(def, v)
+}
+
+// joinList formats the non-empty elements of list and joins them with sep.
+func joinList(def *models.VariableDefinition, list []interface{}, sep string) string {
+	parts := make([]string, 0, len(list))
+	for _, item := range list {
+		if !isEmpty(item) {
+			parts = append(parts, formatTyped(def, item))
+		}
+	}
+	return strings.Join(parts, sep)
+}
+
+// formatTyped formats a single value according to the variable type.
+func formatTyped(def *models.VariableDefinition, v interface{}) string {
+	if def == nil {
+		return formatScalar(v)
+	}
+	switch def.Type {
+	case models.VarTypeBoolean:
+		opts := def.BooleanOptions()
+		if isTruthy(v) && opts.TrueValue != "" {
+			return opts.TrueValue
+		}
+		if !isTruthy(v) && opts.FalseValue != "" {
+			return opts.FalseValue
+		}
+		return strconv.FormatBool(isTruthy(v))
+	case models.VarTypeNumber:
+		return formatNumber(v)
+	case models.VarTypeDate, models.VarTypeDatetime:
+		s := formatScalar(v)
+		if t, ok := ParseDate(s); ok {
+			return FormatDate(t, def.DateOptions().Format)
+		}
+		return s
+	}
+	if models.IsPath(def.Type) {
+		return ExpandHome(formatScalar(v))
+	}
+	return formatScalar(v)
+}
+
+// normalizeValues brings list values into the form the renderer expects:
+// key_value pairs, given as a list of {key, value} objects or as a mapping,
+// become "key=value" strings in a list, dropping pairs without a key.
+func normalizeValues(values map[string]interface{}, byName map[string]*models.VariableDefinition) map[string]interface{} {
+	var out map[string]interface{}
+	for name, def := range byName {
+		v, ok := values[name]
+		if !ok || def.Type != models.VarTypeKeyValue {
+			continue
+		}
+		if out == nil {
+			out = make(map[string]interface{}, len(values))
+			for k, v := range values {
+				out[k] = v
+			}
+		}
+		sep := def.KeyValueOptions().Separator
+		pairs := []interface{}{}
+		for _, p := range KeyValuePairs(v) {
+			pairs = append(pairs, p[0]+sep+p[1])
+		}
+		out[name] = pairs
+	}
+	if out == nil {
+		return values
+	}
+	return out
+}
+
+// KeyValuePairs returns the pairs of a key_value value in order; a mapping
+// is sorted by key. Pairs without a key are dropped.
+func KeyValuePairs(v interface{}) [][2]string {
+	var pairs [][2]string
+	add := func(key string, value interface{}) {
+		if key = strings.TrimSpace(key); key != "" {
+			pairs = append(pairs, [2]string{key, formatScalar(value)})
+		}
+	}
+	switch x := v.(type) {
+	case []interface{}:
+		for _, item := range x {
+			if m, ok := item.(map[string]interface{}); ok {
+				add(formatScalar(m["key"]), m["value"])
+			}
+		}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(x))
+		for k := range x {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			add(k, x[k])
+		}
+	case map[string]string:
+		keys := make([]string, 0, len(x))
+		for k := range x {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			add(k, x[k])
+		}
+	}
+	return pairs
+}
+
+func formatScalar(v interface{}) string {
+	switch x := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return x
+	case float64, float32:
+		return formatNumber(x)
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+// formatNumber renders numbers without exponent or trailing zeros, so the
+// float64 values decoded from JSON print as 23 and 0.5 rather than 2.3e+01.
+func formatNumber(v interface{}) string {
+	switch x := v.(type) {
+	case float64:
+		if x == math.Trunc(x) && math.Abs(x) < 1e15 {
+			return strconv.FormatInt(int64(x), 10)
+		}
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	case float32:
+		return formatNumber(float64(x))
+	case string:
+		s := strings.TrimSpace(x)
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return formatNumber(f)
+		}
+		return s
+	}
+	return formatScalar(v)
+}
+
+// ExpandHome replaces a leading "~" with the user's home directory, as a
+// shell would have done.
+func ExpandHome(path string) string {
+	if path != "~" && !strings.HasPrefix(path, "~/") && !strings.HasPrefix(path, `~\`) {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[1:])
+}
+
+// isTruthy interprets the values a boolean field may arrive with.
+func isTruthy(v interface{}) bool {
+	switch x := v.(type) {
+	case bool:
+		return x
+	case string:
+		switch strings.ToLower(strings.TrimSpace(x)) {
+		case "true", "1", "yes", "on":
+			return true
+		}
+		return false
+	case float64:
+		return x != 0
+	case int:
+		return x != 0
+	}
+	return false
+}
+
+// isEmpty reports whether v counts as "not set".
+func isEmpty(v interface{}) bool {
+	switch x := v.(type) {
+	case nil:
+		return true
+	case string:
+		return strings.TrimSpace(x) == ""
+	}
+	if list, ok := toList(v); ok {
+		return len(list) == 0
+	}
+	return false
+}
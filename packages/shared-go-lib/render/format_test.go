@@ -0,0 +1,110 @@
+package render
+
+import (
+	"reflect"
+	"testing"
+
+	"repo/shared-go-lib/models"
+)
+
+type renderCase struct {
+	name    string
+	command string
+	vars    []models.VariableDefinition
+	values  map[string]interface{}
+	want    []string
+}
+
+func runRenderCases(t *testing.T, tests []renderCase) {
+	t.Helper()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RenderCommand(models.Command{Command: tt.command, Variables: tt.vars}, tt.values)
+			if err != nil {
+				t.Fatalf("RenderCommand(%q) error: %v", tt.command, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("RenderCommand(%q, %v) = %q, want %q", tt.command, tt.values, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderTypesAndArgName(t *testing.T) {
+	flag := models.VariableDefinition{Name: "verbose", Type: models.VarTypeBoolean}
+	short := models.VariableDefinition{Name: "force", Type: models.VarTypeBoolean, ArgName: "f"}
+	color := models.VariableDefinition{Name: "color", Type: models.VarTypeBoolean,
+		Options: map[string]interface{}{"true_value": "--color", "false_value": "--no-color"}}
+	crf := models.VariableDefinition{Name: "crf", Type: models.VarTypeNumber, ArgName: "-crf"}
+	speed := models.VariableDefinition{Name: "speed", Type: models.VarTypeNumber, ArgName: "speed="}
+	preset := models.VariableDefinition{Name: "preset", Type: models.VarTypeSelect, ArgName: "preset"}
+	input := models.VariableDefinition{Name: "input", Type: models.VarTypeFileInput, ArgName: "-i", Required: true}
+	n := models.VariableDefinition{Name: "n", Type: models.VarTypeNumber}
+
+	runRenderCases(t, []renderCase{
+		{"boolean true renders the flag", "ls {{verbose}}", []models.VariableDefinition{flag},
+			map[string]interface{}{"verbose": true}, []string{"ls", "--verbose"}},
+		{"boolean false renders nothing", "ls {{verbose}} .", []models.VariableDefinition{flag},
+			map[string]interface{}{"verbose": false}, []string{"ls", "."}},
+		{"boolean from a string", "ls {{verbose}}", []models.VariableDefinition{flag},
+			map[string]interface{}{"verbose": "true"}, []string{"ls", "--verbose"}},
+		{"boolean with a single letter arg_name", "rm {{force}} x", []models.VariableDefinition{short},
+			map[string]interface{}{"force": true}, []string{"rm", "-f", "x"}},
+		{"boolean true_value", "ls {{color}}", []models.VariableDefinition{color},
+			map[string]interface{}{"color": true}, []string{"ls", "--color"}},
+		{"boolean false_value", "ls {{color}}", []models.VariableDefinition{color},
+			map[string]interface{}{"color": false}, []string{"ls", "--no-color"}},
+		{"boolean inside a word", "x --verbose={{verbose}}", []models.VariableDefinition{flag},
+			map[string]interface{}{"verbose": false}, []string{"x", "--verbose=false"}},
+		{"arg_name pairs with the value", "ffmpeg {{crf}}", []models.VariableDefinition{crf},
+			map[string]interface{}{"crf": 23.0}, []string{"ffmpeg", "-crf", "23"}},
+		{"empty optional value drops the flag", "ffmpeg {{crf}} out", []models.VariableDefinition{crf},
+			map[string]interface{}{"crf": ""}, []string{"ffmpeg", "out"}},
+		{"missing optional value drops the flag", "ffmpeg {{crf}} out", []models.VariableDefinition{crf},
+			nil, []string{"ffmpeg", "out"}},
+		{"arg_name with =", "x {{speed}}", []models.VariableDefinition{speed},
+			map[string]interface{}{"speed": 3.0}, []string{"x", "--speed=3"}},
+		{"long arg_name gets --", "x {{preset}}", []models.VariableDefinition{preset},
+			map[string]interface{}{"preset": "slow"}, []string{"x", "--preset", "slow"}},
+		{"required variable pairs too", "ffmpeg {{input}}", []models.VariableDefinition{input},
+			map[string]interface{}{"input": "a b.mov"}, []string{"ffmpeg", "-i", "a b.mov"}},
+		{"missing required variable stays a placeholder", "ffmpeg {{input}}", []models.VariableDefinition{input},
+			nil, []string{"ffmpeg", "{{input}}"}},
+		{"arg_name only applies to a whole word", "x -crf={{crf}}", []models.VariableDefinition{crf},
+			map[string]interface{}{"crf": 18.0}, []string{"x", "-crf=18"}},
+		{"numbers without exponent", "x {{n}}", []models.VariableDefinition{n},
+			map[string]interface{}{"n": 1e7}, []string{"x", "10000000"}},
+		{"fractions", "x {{n}}", []models.VariableDefinition{n},
+			map[string]interface{}{"n": 0.5}, []string{"x", "0.5"}},
+		{"numbers given as text", "x {{n}}", []models.VariableDefinition{n},
+			map[string]interface{}{"n": " 23.0 "}, []string{"x", "23"}},
+	})
+}
+
+// Templates written before arg_name took effect spell out the flag
+// themselves and must not get it twice.
+func TestRenderArgNameSpelledOut(t *testing.T) {
+	out := models.VariableDefinition{Name: "o", Type: models.VarTypeFileOutput, ArgName: "o"}
+	crf := models.VariableDefinition{Name: "crf", Type: models.VarTypeNumber, ArgName: "-crf"}
+	tags := models.VariableDefinition{Name: "tags", Type: models.VarTypeMultiSelect, ArgName: "-t"}
+	mode := models.VariableDefinition{Name: "mode", Type: models.VarTypeSelect}
+	hidden := crf
+	hidden.ShowIf = "mode == crf"
+
+	runRenderCases(t, []renderCase{
+		{"flag written in the template", "gcc main.c -o {{o}}", []models.VariableDefinition{out},
+			map[string]interface{}{"o": "main"}, []string{"gcc", "main.c", "-o", "main"}},
+		{"flag written with its dash", "ffmpeg -crf {{crf}} out", []models.VariableDefinition{crf},
+			map[string]interface{}{"crf": 23.0}, []string{"ffmpeg", "-crf", "23", "out"}},
+		{"empty value drops the written flag", "ffmpeg -crf {{crf}} out", []models.VariableDefinition{crf},
+			map[string]interface{}{"crf": ""}, []string{"ffmpeg", "out"}},
+		{"list after the written flag", "x -t {{tags}}", []models.VariableDefinition{tags},
+			map[string]interface{}{"tags": []interface{}{"a", "b"}}, []string{"x", "-t", "a", "b"}},
+		{"hidden variable drops the written flag", "ffmpeg -crf {{crf}} {{mode}}", []models.VariableDefinition{mode, hidden},
+			map[string]interface{}{"crf": 23.0, "mode": "cbr"}, []string{"ffmpeg", "cbr"}},
+		{"another word before the placeholder", "x -v {{crf}}", []models.VariableDefinition{crf},
+			map[string]interface{}{"crf": 23.0}, []string{"x", "-v", "-crf", "23"}},
+		{"quoted value after the flag is not bare", `x -crf "{{crf}}"`, []models.VariableDefinition{crf},
+			map[string]interface{}{"crf": 23.0}, []string{"x", "-crf", "23"}},
+	})
+}
@@ -0,0 +1,200 @@
+package render
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// tokenKind identifies the kind of a lexical token in a command template.
+type tokenKind int
+
+const (
+	tokText tokenKind = iota // literal text
+	tokSep                   // unquoted whitespace separating two words
+	tokVar                   // {{placeholder}}
+	tokIf                    // {{#if name}}
+	tokEach                  // {{#each name}}
+	tokElse                  // {{else}}
+	tokEnd                   // {{/if}} or {{/each}}
+)
+
+// token is a single lexical element of a command template. Quoted marks text
+// and placeholders that appeared inside single or double quotes. For block
+// tokens text is the variable name ({{#if name}}) or the block kind
+// ({{/if}}).
+type token struct {
+	kind   tokenKind
+	text   string
+	quoted bool
+	pos    int
+	// item is the current element of an {{#each}} block, bound to {{.}}
+	// when the block is expanded; list is the name of the block's variable.
+	item  interface{}
+	list  string
+	bound bool
+	// filters of a placeholder such as {{input|stem}}
+	filters []filter
+}
+
+// lex splits a command template into tokens following POSIX shell quoting
+// rules. Placeholders are recognised in every quoting context, so
+// "'{{file}}'" still substitutes file but keeps the result as one word.
+func lex(src string) ([]token, error) {
+	var (
+		tokens     []token
+		buf        strings.Builder
+		quote      byte // 0, '\'' or '"'
+		start      = -1
+		quoteStart int
+	)
+
+	flush := func(quoted bool) {
+		if buf.Len() == 0 {
+			return
+		}
+		tokens = append(tokens, token{kind: tokText, text: buf.String(), quoted: quoted, pos: start})
+		buf.Reset()
+		start = -1
+	}
+	write := func(pos int, s string) {
+		if start < 0 {
+			start = pos
+		}
+		buf.WriteString(s)
+	}
+
+	for i := 0; i < len(src); {
+		c := src[i]
+
+		if strings.HasPrefix(src[i:], "{{") {
+			end := strings.Index(src[i+2:], "}}")
+			if end < 0 {
+				return nil, fmt.Errorf("unclosed placeholder at offset %d", i)
+			}
+			body := strings.TrimSpace(src[i+2 : i+2+end])
+			if goAction(body) {
+				write(i, src[i:i+end+4])
+				i += end + 4
+				continue
+			}
+			flush(quote != 0)
+			if body == "" {
+				return nil, fmt.Errorf("empty placeholder at offset %d", i)
+			}
+			tok, err := placeholder(body, i)
+			if err != nil {
+				return nil, err
+			}
+			tok.quoted = quote != 0
+			tokens = append(tokens, tok)
+			i += end + 4
+			continue
+		}
+
+		switch quote {
+		case '\'':
+			if c == '\'' {
+				flush(true)
+				quote = 0
+				i++
+				continue
+			}
+			write(i, string(c))
+			i++
+		case '"':
+			switch {
+			case c == '"':
+				flush(true)
+				quote = 0
+				i++
+			case c == '\\' && i+1 < len(src) && strings.IndexByte("\"\\$`\n", src[i+1]) >= 0:
+				if src[i+1] != '\n' {
+					write(i, string(src[i+1]))
+				}
+				i += 2
+			default:
+				write(i, string(c))
+				i++
+			}
+		default:
+			switch {
+			case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+				flush(false)
+				if n := len(tokens); n > 0 && tokens[n-1].kind != tokSep {
+					tokens = append(tokens, token{kind: tokSep, pos: i})
+				}
+				i++
+			case c == '\'' || c == '"':
+				flush(false)
+				quote = c
+				quoteStart = i
+				// An empty quoted string such as '' still forms a word.
+				tokens = append(tokens, token{kind: tokText, quoted: true, pos: i})
+				i++
+			case c == '\\':
+				if i+1 < len(src) {
+					if src[i+1] != '\n' {
+						flush(false)
+						write(i, string(src[i+1]))
+						flush(true)
+					}
+					i += 2
+				} else {
+					write(i, "\\")
+					i++
+				}
+			default:
+				write(i, string(c))
+				i++
+			}
+		}
+	}
+
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote at offset %d", quote, quoteStart)
+	}
+	flush(false)
+	return tokens, nil
+}
+
+// goAction reports whether the body of a {{...}} is a Go template action
+// such as ".Repository" or ".State.Status", as used by `docker --format`.
+// These are not placeholders and are kept as literal text.
+func goAction(body string) bool {
+	return len(body) > 1 && body[0] == '.' && (body[1] == '_' || unicode.IsLetter(rune(body[1])))
+}
+
+// placeholder classifies the body of a {{...}} at offset pos.
+func placeholder(body string, pos int) (token, error) {
+	switch {
+	case strings.HasPrefix(body, "#"):
+		fields := strings.Fields(body[1:])
+		if len(fields) != 2 {
+			return token{}, fmt.Errorf("invalid block {{%s}} at offset %d", body, pos)
+		}
+		if !ValidVariableName(fields[1]) {
+			return token{}, fmt.Errorf("%v at offset %d", invalidName(fields[1], body), pos)
+		}
+		switch fields[0] {
+		case "if":
+			return token{kind: tokIf, text: fields[1], pos: pos}, nil
+		case "each":
+			return token{kind: tokEach, text: fields[1], pos: pos}, nil
+		}
+		return token{}, fmt.Errorf("unknown block {{%s}} at offset %d, expected #if or #each", body, pos)
+	case strings.HasPrefix(body, "/"):
+		kind := strings.TrimSpace(body[1:])
+		if kind != "if" && kind != "each" {
+			return token{}, fmt.Errorf("unknown block end {{%s}} at offset %d", body, pos)
+		}
+		return token{kind: tokEnd, text: kind, pos: pos}, nil
+	case body == "else":
+		return token{kind: tokElse, pos: pos}, nil
+	}
+	name, filters, err := parsePlaceholderBody(body)
+	if err != nil {
+		return token{}, fmt.Errorf("%v at offset %d", err, pos)
+	}
+	return token{kind: tokVar, text: name, filters: filters, pos: pos}, nil
+}
@@ -0,0 +1,156 @@
+package render
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"repo/shared-go-lib/models"
+)
+
+func TestArgvQuoting(t *testing.T) {
+	tests := []struct {
+		name    string
+		command string
+		values  map[string]interface{}
+		want    []string
+	}{
+		{"plain words", "ls -la /tmp", nil, []string{"ls", "-la", "/tmp"}},
+		{"extra whitespace", "  ls \t -la\n/tmp  ", nil, []string{"ls", "-la", "/tmp"}},
+		{"single quotes", "echo 'a  b' c", nil, []string{"echo", "a  b", "c"}},
+		{"double quotes", `echo "a  b" c`, nil, []string{"echo", "a  b", "c"}},
+		{"adjacent segments", `echo pre'mid dle'"post"`, nil, []string{"echo", "premid dlepost"}},
+		{"empty quotes keep an argument", `printf '' ""`, nil, []string{"printf", "", ""}},
+		{"backslash escapes a space", `echo a\ b`, nil, []string{"echo", "a b"}},
+		{"backslash newline continues", "echo a \\\nb", nil, []string{"echo", "a", "b"}},
+		{"escapes in double quotes", `echo "a\"b\\c\$d\e"`, nil, []string{"echo", `a"b\c$d\e`}},
+		{"no escapes in single quotes", `echo 'a\"b'`, nil, []string{"echo", `a\"b`}},
+		{"shell syntax is literal", "cat a | grep b > c", nil, []string{"cat", "a", "|", "grep", "b", ">", "c"}},
+		{"value with spaces stays one argument", "cp {{src}} {{dst}}",
+			map[string]interface{}{"src": "My Videos/a.mov", "dst": "out dir/"},
+			[]string{"cp", "My Videos/a.mov", "out dir/"}},
+		{"value with quotes is not re-parsed", "echo {{msg}}",
+			map[string]interface{}{"msg": `it's "quoted" $HOME`},
+			[]string{"echo", `it's "quoted" $HOME`}},
+		{"placeholder inside a word", "ffmpeg -i {{in}} out_{{n}}.mp4",
+			map[string]interface{}{"in": "a b.mov", "n": "1"},
+			[]string{"ffmpeg", "-i", "a b.mov", "out_1.mp4"}},
+		{"placeholder inside quotes", `echo '{{a}} and {{b}}'`,
+			map[string]interface{}{"a": "x", "b": "y z"},
+			[]string{"echo", "x and y z"}},
+		{"spaces inside the braces", "echo {{ name }}", map[string]interface{}{"name": "v"}, []string{"echo", "v"}},
+		{"bare list value splits", "rm {{files}}",
+			map[string]interface{}{"files": []interface{}{"a b", "c"}},
+			[]string{"rm", "a b", "c"}},
+		{"list value inside a word joins", "echo x{{files}}",
+			map[string]interface{}{"files": []interface{}{"a", "b"}},
+			[]string{"echo", "xa b"}},
+		{"empty unquoted value is dropped", "echo {{a}} b", map[string]interface{}{"a": ""}, []string{"echo", "b"}},
+		{"empty quoted value is kept", `echo "{{a}}" b`, map[string]interface{}{"a": ""}, []string{"echo", "", "b"}},
+		{"missing value stays literal", "echo {{a}}", nil, []string{"echo", "{{a}}"}},
+		{"go template actions pass through", `docker ps --format '{{.Names}} {{ .Status }}'`, nil,
+			[]string{"docker", "ps", "--format", "{{.Names}} {{ .Status }}"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Argv(tt.command, tt.values)
+			if err != nil {
+				t.Fatalf("Argv(%q) error: %v", tt.command, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Argv(%q) = %q, want %q", tt.command, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		command string
+		want    string
+	}{
+		{`echo 'abc`, "unterminated ' quote at offset 5"},
+		{`echo "abc`, `unterminated " quote at offset 5`},
+		{`echo {{abc`, "unclosed placeholder at offset 5"},
+		{`echo {{ }}`, "empty placeholder at offset 5"},
+		{`echo {{a|}}`, "unknown filter ''"},
+	}
+	for _, tt := range tests {
+		_, err := Parse(tt.command)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Parse(%q) error = %v, want it to contain %q", tt.command, err, tt.want)
+		}
+	}
+}
+
+func TestQuote(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"", "''"},
+		{"abc", "abc"},
+		{"/tmp/a-b_c.mp4", "/tmp/a-b_c.mp4"},
+		{"--crf=23", "--crf=23"},
+		{"a b", "'a b'"},
+		{"it's", `'it'\''s'`},
+		{"$HOME", "'$HOME'"},
+		{"a*b", "'a*b'"},
+	}
+	for _, tt := range tests {
+		if got := Quote(tt.in); got != tt.want {
+			t.Errorf("Quote(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+// The command text shown in the app is Join of the argv that runs, so it
+// must split back into exactly that argv.
+func TestJoinRoundTrip(t *testing.T) {
+	argvs := [][]string{
+		{"ffmpeg", "-i", "My Videos/clip.mov", "-vf", "scale=1280:-2", "out.mp4"},
+		{"echo", "", "it's", `"double"`, `back\slash`, "$VAR", "a\nb", "tab\there"},
+		{"printf", "%s\n", "{{literal}}"},
+	}
+	for _, argv := range argvs {
+		line := Join(argv)
+		got, err := Argv(line, nil)
+		if err != nil {
+			t.Fatalf("Argv(Join(%q)) error: %v", argv, err)
+		}
+		if !reflect.DeepEqual(got, argv) {
+			t.Errorf("Argv(%s) = %q, want %q", line, got, argv)
+		}
+	}
+}
+
+// A path with a space in the user's home stays one argument and the
+// command text splits back into the same argv.
+func TestRenderCommandPathWithSpace(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skip("no home directory")
+	}
+	cmd := models.Command{
+		Command: "ffmpeg -i {{input}} {{output}}",
+		Variables: []models.VariableDefinition{
+			{Name: "input", Type: models.VarTypeFileInput, Required: true},
+			{Name: "output", Type: models.VarTypeFileOutput, Required: true},
+		},
+	}
+	values := map[string]interface{}{"input": "~/My Videos/clip.mov", "output": "~/My Videos/clip small.mp4"}
+	argv, err := RenderCommand(cmd, values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"ffmpeg", "-i", filepath.Join(home, "My Videos/clip.mov"), filepath.Join(home, "My Videos/clip small.mp4")}
+	if !reflect.DeepEqual(argv, want) {
+		t.Fatalf("RenderCommand = %q, want %q", argv, want)
+	}
+	back, err := Argv(Join(argv), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(back, argv) {
+		t.Errorf("command text %s splits into %q, want %q", Join(argv), back, argv)
+	}
+}
@@ -0,0 +1,35 @@
+package render
+
+import "strings"
+
+// shellSafe lists the characters that never need quoting in a POSIX shell.
+const shellSafe = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789@%+=:,./-_"
+
+// Quote returns s quoted for a POSIX shell. Strings consisting only of safe
+// characters are returned unchanged.
+func Quote(s string) string {
+	if s == "" {
+		return "''"
+	}
+	safe := true
+	for i := 0; i < len(s); i++ {
+		if strings.IndexByte(shellSafe, s[i]) < 0 {
+			safe = false
+			break
+		}
+	}
+	if safe {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// Join quotes each argument and joins them with spaces, producing a command
+// line that a POSIX shell would split back into the same argv.
+func Join(argv []string) string {
+	quoted := make([]string, len(argv))
+	for i, arg := range argv {
+		quoted[i] = Quote(arg)
+	}
+	return strings.Join(quoted, " ")
+}
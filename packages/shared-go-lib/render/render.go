@@ -0,0 +1,257 @@
+// Package render turns a cliqfile command template into an argv list.
+//
+// The command string is tokenized with POSIX shell quoting rules and every
+// {{placeholder}} is substituted as part of a single argv element, so values
+// containing spaces are never split. No shell is involved: pipes, redirects
+// and globs are passed through literally.
+//
+// {{#if name}}...{{else}}...{{/if}} keeps a segment only when a variable is
+// set, and {{#each name}}...{{/each}} repeats a segment for every element of
+// a list, with {{.}} standing for the element. Go template actions such as
+// {{.Repository}}, used by `docker --format`, are passed through as text.
+package render
+
+import (
+	"strings"
+
+	"repo/shared-go-lib/models"
+)
+
+// Template is a parsed command template.
+type Template struct {
+	source string
+	nodes  []node
+}
+
+// Parse tokenizes a command template and checks that its blocks are
+// balanced.
+func Parse(command string) (*Template, error) {
+	tokens, err := lex(command)
+	if err != nil {
+		return nil, err
+	}
+	nodes, err := parseBlocks(tokens)
+	if err != nil {
+		return nil, err
+	}
+	return &Template{source: command, nodes: nodes}, nil
+}
+
+// Variables returns the variable names referenced by the template, as
+// placeholders or block conditions, in order of first appearance. {{.}} is
+// not included.
+func (t *Template) Variables() []string {
+	var names []string
+	seen := map[string]struct{}{}
+	walk(t.nodes, func(tok token) {
+		if tok.kind != tokVar && tok.kind != tokIf && tok.kind != tokEach {
+			return
+		}
+		if _, ok := seen[tok.text]; ok || tok.text == "." {
+			return
+		}
+		seen[tok.text] = struct{}{}
+		names = append(names, tok.text)
+	})
+	return names
+}
+
+// words splits tokens into the words of the command line.
+func words(tokens []token) [][]token {
+	var (
+		out  [][]token
+		word []token
+	)
+	for _, tok := range tokens {
+		if tok.kind == tokSep {
+			if len(word) > 0 {
+				out = append(out, word)
+				word = nil
+			}
+			continue
+		}
+		word = append(word, tok)
+	}
+	if len(word) > 0 {
+		out = append(out, word)
+	}
+	return out
+}
+
+// Render substitutes values into the template and returns the argv.
+//
+// A placeholder always expands inside the word it appears in. The only way to
+// produce several argv elements from one placeholder is a list value used as
+// a bare, unquoted word. An unquoted word that expands to nothing is dropped,
+// while a quoted one is kept as an empty argument. Placeholders without a
+// value are left as literal text.
+//
+// When defs is given, values are formatted according to their variable
+// definitions, see renderVar, and variables hidden by their show_if render
+// nothing. A template that already writes the flag of an arg_name, as in
+// "-o {{o}}", does not get it twice; an empty value drops that flag too.
+func (t *Template) Render(values map[string]interface{}, defs []models.VariableDefinition) ([]string, error) {
+	byName := make(map[string]*models.VariableDefinition, len(defs))
+	for i := range defs {
+		byName[defs[i].Name] = &defs[i]
+	}
+	values, hidden, err := hideValues(values, byName)
+	if err != nil {
+		return nil, err
+	}
+	values = normalizeValues(values, byName)
+
+	var (
+		argv []string
+		prev string // 上一个词为纯文本时的内容
+	)
+	for _, word := range words(expand(t.nodes, values, byName, nil)) {
+		literal, isLiteral := literalWord(word)
+		flag := prev
+		prev = ""
+		if isLiteral {
+			prev = literal
+		}
+		if tok, ok := bareVar(word); ok && !tok.bound {
+			if hidden[tok.text] {
+				if spellsFlag(byName[tok.text], flag) {
+					argv = argv[:len(argv)-1]
+				}
+				continue // show_if 为假的变量不渲染
+			}
+			def := byName[tok.text]
+			v, found := filterValue(def, values[tok.text], hasValue(values, tok.text), tok.filters)
+			if def != nil {
+				spelled := spellsFlag(def, flag)
+				if spelled {
+					// 模板已写出 "-o {{o}}" 时不再重复 arg_name
+					plain := *def
+					plain.ArgName = ""
+					def = &plain
+				}
+				if args, handled := renderVar(def, v, found); handled {
+					if spelled && len(args) == 0 {
+						argv = argv[:len(argv)-1] // 值为空时连同模板中的参数名一起省略
+					}
+					argv = append(argv, args...)
+					continue
+				}
+			} else if found {
+				if list, isList := toList(v); isList {
+					for _, item := range list {
+						argv = append(argv, formatScalar(item))
+					}
+					continue
+				}
+			}
+		}
+
+		var (
+			sb   strings.Builder
+			keep bool
+		)
+		for _, tok := range word {
+			if tok.quoted {
+				keep = true
+			}
+			switch tok.kind {
+			case tokText:
+				if tok.text != "" {
+					keep = true
+				}
+				sb.WriteString(tok.text)
+			case tokVar:
+				if tok.bound {
+					// {{.}} in an {{#each}} block, formatted like its list variable
+					sb.WriteString(applyFilters(formatTyped(byName[tok.list], tok.item), tok.filters))
+					continue
+				}
+				if hidden[tok.text] {
+					continue
+				}
+				def := byName[tok.text]
+				v, found := filterValue(def, values[tok.text], hasValue(values, tok.text), tok.filters)
+				if !found && (def == nil || def.Required) {
+					sb.WriteString("{{" + tok.text + "}}")
+					keep = true
+					continue
+				}
+				sb.WriteString(formatValue(def, v))
+			}
+		}
+		if sb.Len() == 0 && !keep {
+			continue
+		}
+		argv = append(argv, sb.String())
+	}
+	return argv, nil
+}
+
+// Argv parses command and renders it with values in one step, without any
+// variable definitions.
+func Argv(command string, values map[string]interface{}) ([]string, error) {
+	t, err := Parse(command)
+	if err != nil {
+		return nil, err
+	}
+	return t.Render(values, nil)
+}
+
+// RenderCommand renders cmd.Command, formatting every value according to
+// the command's variable definitions.
+func RenderCommand(cmd models.Command, values map[string]interface{}) ([]string, error) {
+	t, err := Parse(cmd.Command)
+	if err != nil {
+		return nil, err
+	}
+	return t.Render(values, cmd.Variables)
+}
+
+func hasValue(values map[string]interface{}, name string) bool {
+	_, ok := values[name]
+	return ok
+}
+
+// literalWord returns the text of a word that contains no placeholders.
+func literalWord(word []token) (string, bool) {
+	var sb strings.Builder
+	for _, tok := range word {
+		if tok.kind != tokText {
+			return "", false
+		}
+		sb.WriteString(tok.text)
+	}
+	return sb.String(), true
+}
+
+// spellsFlag reports whether the word before a placeholder, prev, already
+// is the flag of def's arg_name, as in "-o {{o}}" with arg_name "o".
+func spellsFlag(def *models.VariableDefinition, prev string) bool {
+	if def == nil || def.ArgName == "" || def.Type == models.VarTypeBoolean || strings.HasSuffix(def.ArgName, "=") {
+		return false
+	}
+	return prev != "" && prev == flagName(def)
+}
+
+// bareVar reports whether word is a single unquoted placeholder.
+func bareVar(word []token) (token, bool) {
+	if len(word) != 1 || word[0].kind != tokVar || word[0].quoted {
+		return token{}, false
+	}
+	return word[0], true
+}
+
+// toList returns the elements of v when it is a slice value.
+func toList(v interface{}) ([]interface{}, bool) {
+	switch list := v.(type) {
+	case []interface{}:
+		return list, true
+	case []string:
+		out := make([]interface{}, len(list))
+		for i, s := range list {
+			out[i] = s
+		}
+		return out, true
+	}
+	return nil, false
+}
@@ -0,0 +1,83 @@
+package render
+
+import (
+	"sort"
+	"strings"
+
+	"repo/shared-go-lib/models"
+)
+
+// Mask stands in for secret values wherever a command is shown or recorded.
+const Mask = "******"
+
+// Secrets returns the non-empty values of cmd's secret variables, longest
+// first so that Redact never leaves part of a longer secret behind. A
+// secret placeholder with filters, such as {{token|upper}}, renders text
+// that differs from the value, so the filtered forms the command uses are
+// returned as well.
+func Secrets(cmd models.Command, values map[string]interface{}) []string {
+	var secrets []string
+	seen := map[string]bool{}
+	add := func(s string) {
+		if strings.TrimSpace(s) != "" && !seen[s] {
+			seen[s] = true
+			secrets = append(secrets, s)
+		}
+	}
+	byName := map[string]*models.VariableDefinition{}
+	for i, def := range cmd.Variables {
+		if def.Type != models.VarTypeSecret {
+			continue
+		}
+		if s := formatScalar(values[def.Name]); strings.TrimSpace(s) != "" {
+			byName[def.Name] = &cmd.Variables[i]
+			add(s)
+		}
+	}
+	if len(byName) == 0 {
+		return nil
+	}
+
+	filtered := func(name string, filters []filter) {
+		if def := byName[name]; def != nil && len(filters) > 0 {
+			add(applyFilters(formatTyped(def, values[name]), filters))
+		}
+	}
+	commands := []string{cmd.Command}
+	texts := []string{cmd.Workdir}
+	for _, v := range cmd.Env {
+		texts = append(texts, v)
+	}
+	for _, def := range cmd.Variables {
+		if src := def.OptionSource(); src != nil {
+			commands = append(commands, src.Command)
+		}
+		texts = append(texts, Expression(def))
+	}
+	// 无法解析的模板也无法渲染, 忽略即可
+	for _, command := range commands {
+		if tmpl, err := Parse(command); err == nil {
+			walk(tmpl.nodes, func(tok token) {
+				if tok.kind == tokVar {
+					filtered(tok.text, tok.filters)
+				}
+			})
+		}
+	}
+	for _, text := range texts {
+		scanPlaceholders(text, func(string) {}, filtered)
+	}
+
+	sort.Slice(secrets, func(i, j int) bool { return len(secrets[i]) > len(secrets[j]) })
+	return secrets
+}
+
+// Redact replaces every occurrence of the secrets in s with Mask.
+func Redact(s string, secrets []string) string {
+	for _, secret := range secrets {
+		if secret != "" {
+			s = strings.ReplaceAll(s, secret, Mask)
+		}
+	}
+	return s
+}
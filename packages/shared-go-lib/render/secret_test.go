@@ -0,0 +1,75 @@
+package render
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"repo/shared-go-lib/models"
+)
+
+func TestSecrets(t *testing.T) {
+	token := models.VariableDefinition{Name: "token", Type: models.VarTypeSecret}
+	pass := models.VariableDefinition{Name: "pass", Type: models.VarTypeSecret}
+	user := models.VariableDefinition{Name: "user", Type: models.VarTypeText}
+	values := map[string]interface{}{"token": "Abc123", "pass": "pw", "user": "bob"}
+
+	tests := []struct {
+		name string
+		cmd  models.Command
+		want []string
+	}{
+		{"values, longest first", models.Command{Command: "x {{pass}} {{token}} {{user}}",
+			Variables: []models.VariableDefinition{pass, token, user}}, []string{"Abc123", "pw"}},
+		{"unused filters are not listed", models.Command{Command: "x {{token}}",
+			Variables: []models.VariableDefinition{token}}, []string{"Abc123"}},
+		{"filtered placeholders", models.Command{Command: "x {{token|upper}} {{#if token}}{{token|lower|trim}}{{/if}} {{user|upper}}",
+			Variables: []models.VariableDefinition{token, user}}, []string{"Abc123", "ABC123", "abc123"}},
+		{"filters in env, workdir and expressions", models.Command{Command: "x",
+			Workdir: "/d/{{token|lower}}",
+			Env:     map[string]string{"TOKEN": "{{token|upper}}"},
+			Variables: []models.VariableDefinition{token,
+				{Name: "auth", Type: models.VarTypeText, Computed: "{{user}}:{{token|base}}"}}},
+			[]string{"Abc123", "ABC123", "abc123"}},
+		{"filters in options_from", models.Command{Command: "x",
+			Variables: []models.VariableDefinition{token,
+				{Name: "repo", Type: models.VarTypeSelect, Options: map[string]interface{}{"options_from": "gh repo list --token {{token|upper}}"}}}},
+			[]string{"Abc123", "ABC123"}},
+		{"no secrets", models.Command{Command: "x {{user|upper}}",
+			Variables: []models.VariableDefinition{user}}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Secrets(tt.cmd, values)
+			for i := 1; i < len(got); i++ {
+				if len(got[i]) > len(got[i-1]) {
+					t.Errorf("Secrets = %q, want the longest first", got)
+				}
+			}
+			sort.Strings(got)
+			sort.Strings(tt.want)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Secrets = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	empty := models.Command{Command: "x {{token|upper}}", Variables: []models.VariableDefinition{token}}
+	if got := Secrets(empty, map[string]interface{}{"token": "  "}); got != nil {
+		t.Errorf("Secrets of a blank value = %q", got)
+	}
+}
+
+func TestRedactFilteredSecret(t *testing.T) {
+	token := models.VariableDefinition{Name: "token", Type: models.VarTypeSecret}
+	cmd := models.Command{Command: "curl -H 'X-Token: {{token|upper}}' -u {{token}}", Variables: []models.VariableDefinition{token}}
+	values := map[string]interface{}{"token": "s3cret"}
+	argv, err := RenderCommand(cmd, values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := Redact(Join(argv), Secrets(cmd, values))
+	if want := "curl -H 'X-Token: ******' -u ******"; got != want {
+		t.Errorf("redacted command = %q, want %q", got, want)
+	}
+}
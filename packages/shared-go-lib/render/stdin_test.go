@@ -0,0 +1,58 @@
+package render
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"repo/shared-go-lib/models"
+)
+
+func TestStdin(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skip(err)
+	}
+	vars := []models.VariableDefinition{
+		{Name: "body", Type: models.VarTypeText},
+		{Name: "input", Type: models.VarTypeFileInput},
+		{Name: "count", Type: models.VarTypeNumber},
+	}
+	tests := []struct {
+		stdin    string
+		values   map[string]interface{}
+		wantFile string
+		wantText string
+	}{
+		{"", map[string]interface{}{"body": "x"}, "", ""},
+		{"body", map[string]interface{}{"body": "{\"a\": 1}\n"}, "", "{\"a\": 1}\n"},
+		{"body", map[string]interface{}{"body": ""}, "", ""},
+		{"input", map[string]interface{}{"input": "~/in.json"}, filepath.Join(home, "in.json"), ""},
+		{"count", map[string]interface{}{"count": 3.0}, "", "3"},
+		{"missing", map[string]interface{}{"body": "x"}, "", ""},
+	}
+	for _, tt := range tests {
+		file, text := Stdin(models.Command{Stdin: tt.stdin, Variables: vars}, tt.values)
+		if file != tt.wantFile || text != tt.wantText {
+			t.Errorf("Stdin(%q) = %q, %q, want %q, %q", tt.stdin, file, text, tt.wantFile, tt.wantText)
+		}
+	}
+}
+
+func TestStdinRedirect(t *testing.T) {
+	tests := []struct {
+		file, text string
+		want       string
+	}{
+		{"", "", ""},
+		{"/data/my file.json", "", "< '/data/my file.json'"},
+		{"", "a\nb\n", "<<'EOF'\na\nb\nEOF"},
+		{"", "a\nEOF\r\nEOF_1", "<<'EOF_2'\na\nEOF\r\nEOF_1\nEOF_2"},
+		{"", "EOFX", "<<'EOF'\nEOFX\nEOF"},
+	}
+	for _, tt := range tests {
+		if got := StdinRedirect(tt.file, tt.text); got != tt.want {
+			t.Errorf("StdinRedirect(%q, %q) = %q, want %q", tt.file, tt.text, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package render
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Fields of a step result that later pipeline steps can refer to.
+const (
+	StepOutput = "output" // the file written by the step's first file_output variable
+	StepStdout = "stdout" // the step's standard output
+	StepValues = "values" // a value extracted by the step's output spec
+)
+
+// StepRef is a reference to the result of an earlier pipeline step, such as
+// {{steps.encode.output}} or {{steps.probe.values.duration}}.
+type StepRef struct {
+	Step  string
+	Field string // StepOutput, StepStdout or StepValues
+	Value string // name of the extracted value when Field is StepValues
+}
+
+// StepPart is a piece of the `with` expression of a pipeline step: literal
+// text, a pipeline variable {{name}} or a step reference. Text is the
+// literal text or the placeholder as written.
+type StepPart struct {
+	Text string
+	Var  string
+	Ref  *StepRef
+}
+
+var stepPlaceholder = regexp.MustCompile(`\{\{\s*([^{}]+?)\s*\}\}`)
+
+// ParseStepExpr splits the `with` expression of a pipeline step into
+// literal text and references. Every {{steps.…}} placeholder must name one
+// of the supported fields.
+func ParseStepExpr(expr string) ([]StepPart, error) {
+	var parts []StepPart
+	last := 0
+	for _, m := range stepPlaceholder.FindAllStringSubmatchIndex(expr, -1) {
+		if m[0] > last {
+			parts = append(parts, StepPart{Text: expr[last:m[0]]})
+		}
+		last = m[1]
+		text, name := expr[m[0]:m[1]], expr[m[2]:m[3]]
+		if !strings.HasPrefix(name, "steps.") {
+			parts = append(parts, StepPart{Text: text, Var: name})
+			continue
+		}
+		ref, ok := parseStepRef(name)
+		if !ok {
+			return nil, fmt.Errorf("invalid reference '%s', use steps.<id>.%s, steps.<id>.%s or steps.<id>.%s.<name>", text, StepOutput, StepStdout, StepValues)
+		}
+		parts = append(parts, StepPart{Text: text, Ref: &ref})
+	}
+	if last < len(expr) {
+		parts = append(parts, StepPart{Text: expr[last:]})
+	}
+	return parts, nil
+}
+
+// parseStepRef parses "steps.<id>.<field>" or "steps.<id>.values.<name>".
+func parseStepRef(name string) (StepRef, bool) {
+	parts := strings.SplitN(name, ".", 4)
+	if len(parts) < 3 || parts[1] == "" {
+		return StepRef{}, false
+	}
+	ref := StepRef{Step: parts[1], Field: parts[2]}
+	switch {
+	case len(parts) == 3 && (ref.Field == StepOutput || ref.Field == StepStdout):
+		return ref, true
+	case len(parts) == 4 && ref.Field == StepValues && parts[3] != "":
+		ref.Value = parts[3]
+		return ref, true
+	}
+	return StepRef{}, false
+}
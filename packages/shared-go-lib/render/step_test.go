@@ -0,0 +1,45 @@
+package render
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseStepExpr(t *testing.T) {
+	tests := []struct {
+		expr string
+		want []StepPart
+	}{
+		{"", nil},
+		{"plain", []StepPart{{Text: "plain"}}},
+		{"{{ name }}", []StepPart{{Text: "{{ name }}", Var: "name"}}},
+		{"{{steps.enc.output}}", []StepPart{{Text: "{{steps.enc.output}}", Ref: &StepRef{Step: "enc", Field: StepOutput}}}},
+		{"{{ steps.enc.stdout }}", []StepPart{{Text: "{{ steps.enc.stdout }}", Ref: &StepRef{Step: "enc", Field: StepStdout}}}},
+		{"{{steps.probe.values.a.b}}", []StepPart{{Text: "{{steps.probe.values.a.b}}", Ref: &StepRef{Step: "probe", Field: StepValues, Value: "a.b"}}}},
+		{"dir/{{name}}-{{steps.enc.output}}.txt", []StepPart{
+			{Text: "dir/"},
+			{Text: "{{name}}", Var: "name"},
+			{Text: "-"},
+			{Text: "{{steps.enc.output}}", Ref: &StepRef{Step: "enc", Field: StepOutput}},
+			{Text: ".txt"},
+		}},
+		{"{{ unclosed", []StepPart{{Text: "{{ unclosed"}}},
+	}
+	for _, tt := range tests {
+		got, err := ParseStepExpr(tt.expr)
+		if err != nil {
+			t.Errorf("ParseStepExpr(%q) error: %v", tt.expr, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseStepExpr(%q) = %+v, want %+v", tt.expr, got, tt.want)
+		}
+	}
+
+	for _, expr := range []string{"{{steps.enc}}", "{{steps.enc.file}}", "{{steps.enc.output.x}}", "{{steps.enc.values}}", "{{steps.enc.values.}}", "{{steps..output}}"} {
+		if _, err := ParseStepExpr("x " + expr); err == nil || !strings.Contains(err.Error(), "invalid reference '"+expr+"'") {
+			t.Errorf("ParseStepExpr(%q) error = %v, want an invalid reference error", expr, err)
+		}
+	}
+}
@@ -0,0 +1,396 @@
+// Package safety statically inspects a command before it runs and reports
+// operations that can destroy data or compromise the machine, so that the
+// user can confirm them explicitly.
+package safety
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"sort"
+	"strings"
+
+	"repo/shared-go-lib/render"
+)
+
+// Severity ranks a finding.
+type Severity string
+
+const (
+	// SeverityWarning marks operations that are risky depending on the
+	// values involved, such as writing outside the home directory.
+	SeverityWarning Severity = "warning"
+	// SeverityDanger marks operations that can destroy data or run
+	// untrusted code, such as recursive deletes or piping a download into
+	// a shell.
+	SeverityDanger Severity = "danger"
+)
+
+// Rule identifiers.
+const (
+	RuleRecursiveDelete = "recursive_delete"
+	RuleDiskWrite       = "disk_write"
+	RuleRemoteScript    = "remote_script"
+	RuleWorldWritable   = "world_writable"
+	RuleOutsideHome     = "write_outside_home"
+	RuleSystemPath      = "write_system_path"
+	RulePrivileged      = "privileged"
+)
+
+// maxDepth bounds how deeply wrappers such as "sudo" and "sh -c" are
+// unwrapped.
+const maxDepth = 4
+
+// Finding is one risky pattern.
+type Finding struct {
+	Rule     string   `json:"rule"`
+	Severity Severity `json:"severity"`
+	Message  string   `json:"message"`
+	// Arg is the argument or text the finding refers to.
+	Arg string `json:"arg,omitempty"`
+}
+
+// Input is what Analyze inspects.
+type Input struct {
+	// Command is the raw command template.
+	Command string
+	// Argv is the rendered argv.
+	Argv []string
+	// Outputs are paths the command is known to write, such as the values
+	// of file_output variables.
+	Outputs []string
+	// Home is the user's home directory; empty means os.UserHomeDir.
+	Home string
+}
+
+// Report is the result of Analyze.
+type Report struct {
+	Findings []Finding `json:"findings"`
+	// Severity is the highest severity found, empty when there are none.
+	Severity Severity `json:"severity,omitempty"`
+	// RequiresConfirmation is true when the user must confirm before the
+	// command runs.
+	RequiresConfirmation bool `json:"requires_confirmation"`
+}
+
+var (
+	remoteScriptPattern = regexp.MustCompile(`(?i)\b(curl|wget|fetch)\b[^|;&]*\|\s*(sudo\s+)?(sh|bash|zsh|ksh|dash|fish|python[0-9.]*|perl|ruby|node)\b`)
+	psRemotePattern     = regexp.MustCompile(`(?i)\b(iwr|irm|invoke-webrequest|invoke-restmethod|downloadstring)\b.*\|\s*(iex|invoke-expression)\b`)
+	redirectPattern     = regexp.MustCompile(`>>?\s*([^\s;|&<>]+)`)
+	shellSplitPattern   = regexp.MustCompile(`\|\||&&|[;|&\n]`)
+	worldWritableModes  = map[string]bool{"777": true, "0777": true, "a+rwx": true, "ugo+rwx": true, "o+w": true, "a+w": true}
+	diskTools           = map[string]bool{"mkfs": true, "fdisk": true, "sfdisk": true, "parted": true, "wipefs": true, "diskpart": true, "format": true}
+	shells              = map[string]bool{"sh": true, "bash": true, "zsh": true, "ksh": true, "dash": true, "fish": true}
+	wrappers            = map[string]bool{"sudo": true, "doas": true, "env": true, "nohup": true, "time": true, "nice": true, "xargs": true}
+)
+
+// Analyze inspects in and returns every finding, with duplicates removed.
+func Analyze(in Input) Report {
+	a := &analyzer{home: in.Home}
+	if a.home == "" {
+		a.home, _ = os.UserHomeDir()
+	}
+
+	a.script(in.Command)
+	a.argv(in.Argv, 0)
+	for _, out := range in.Outputs {
+		a.write(out)
+	}
+
+	r := Report{Findings: a.findings}
+	for _, f := range a.findings {
+		if f.Severity == SeverityDanger || r.Severity == "" {
+			r.Severity = f.Severity
+		}
+	}
+	r.RequiresConfirmation = len(r.Findings) > 0
+	sort.SliceStable(r.Findings, func(i, j int) bool {
+		return r.Findings[i].Severity == SeverityDanger && r.Findings[j].Severity != SeverityDanger
+	})
+	return r
+}
+
+type analyzer struct {
+	home     string
+	findings []Finding
+	seen     map[string]bool
+}
+
+func (a *analyzer) add(rule string, sev Severity, msg, arg string) {
+	key := rule + "\x00" + arg
+	if a.seen == nil {
+		a.seen = map[string]bool{}
+	}
+	if a.seen[key] {
+		return
+	}
+	a.seen[key] = true
+	a.findings = append(a.findings, Finding{Rule: rule, Severity: sev, Message: msg, Arg: arg})
+}
+
+// script inspects text that a shell would interpret: the raw template and
+// the argument of "sh -c".
+func (a *analyzer) script(text string) {
+	if m := remoteScriptPattern.FindString(text); m != "" {
+		a.add(RuleRemoteScript, SeverityDanger, "将下载的内容直接交给解释器执行", m)
+	}
+	if m := psRemotePattern.FindString(text); m != "" {
+		a.add(RuleRemoteScript, SeverityDanger, "将下载的内容直接交给 PowerShell 执行", m)
+	}
+}
+
+// argv inspects one command, unwrapping privilege wrappers and shells.
+func (a *analyzer) argv(argv []string, depth int) {
+	if len(argv) == 0 || depth > maxDepth {
+		return
+	}
+	prog := programName(argv[0])
+	args := argv[1:]
+
+	switch {
+	case wrappers[prog]:
+		if prog == "sudo" || prog == "doas" {
+			a.add(RulePrivileged, SeverityWarning, "以管理员权限运行", argv[0])
+		}
+		a.argv(skipWrapperArgs(args), depth+1)
+		return
+	case shells[prog]:
+		for i, arg := range args {
+			if strings.HasPrefix(arg, "-") && strings.Contains(arg, "c") && i+1 < len(args) {
+				a.shellScript(args[i+1], depth+1)
+				return
+			}
+		}
+		return
+	case prog == "powershell" || prog == "pwsh" || prog == "cmd":
+		a.script(strings.Join(args, " "))
+		return
+	}
+
+	switch {
+	case prog == "rm":
+		a.rm(args)
+	case prog == "rd" || prog == "rmdir" || prog == "del" || prog == "erase":
+		if hasArg(args, "/s", "/S") {
+			a.add(RuleRecursiveDelete, SeverityDanger, "递归删除", strings.Join(argv, " "))
+		}
+	case prog == "remove-item":
+		if hasArg(args, "-recurse", "-Recurse") {
+			a.add(RuleRecursiveDelete, SeverityDanger, "递归删除", strings.Join(argv, " "))
+		}
+	case prog == "dd":
+		a.dd(args)
+	case diskTools[prog] || strings.HasPrefix(prog, "mkfs."):
+		a.add(RuleDiskWrite, SeverityDanger, "格式化或修改磁盘分区", strings.Join(argv, " "))
+	case prog == "diskutil":
+		for _, arg := range args {
+			lower := strings.ToLower(arg)
+			if strings.HasPrefix(lower, "erase") || strings.HasSuffix(lower, "disk") && lower != "listdisk" {
+				a.add(RuleDiskWrite, SeverityDanger, "格式化或修改磁盘分区", strings.Join(argv, " "))
+				break
+			}
+		}
+	case prog == "chmod":
+		a.chmod(args)
+	case prog == "cp" || prog == "mv" || prog == "install" || prog == "ln":
+		if operands := operands(args); len(operands) > 1 {
+			a.write(operands[len(operands)-1])
+		}
+	case prog == "tee":
+		for _, op := range operands(args) {
+			a.write(op)
+		}
+	}
+}
+
+// shellScript splits a shell script into simple commands and inspects each
+// of them.
+func (a *analyzer) shellScript(script string, depth int) {
+	a.script(script)
+	for _, m := range redirectPattern.FindAllStringSubmatch(script, -1) {
+		if m[1] != "/dev/null" && !strings.HasPrefix(m[1], "&") {
+			a.write(m[1])
+		}
+	}
+	for _, part := range shellSplitPattern.Split(script, -1) {
+		argv, err := render.Argv(redirectPattern.ReplaceAllString(part, ""), nil)
+		if err != nil {
+			argv = strings.Fields(part)
+		}
+		a.argv(argv, depth)
+	}
+}
+
+func (a *analyzer) rm(args []string) {
+	recursive := false
+	for _, arg := range args {
+		if arg == "--" {
+			break
+		}
+		if arg == "--recursive" || (strings.HasPrefix(arg, "-") && !strings.HasPrefix(arg, "--") && strings.ContainsAny(arg, "rR")) {
+			recursive = true
+		}
+	}
+	if !recursive {
+		return
+	}
+	targets := operands(args)
+	for _, t := range targets {
+		if a.critical(t) {
+			a.add(RuleRecursiveDelete, SeverityDanger, "递归删除根目录、主目录或系统目录", t)
+			return
+		}
+	}
+	a.add(RuleRecursiveDelete, SeverityDanger, "递归删除", strings.Join(targets, " "))
+}
+
+func (a *analyzer) dd(args []string) {
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, "of=") {
+			continue
+		}
+		target := strings.TrimPrefix(arg, "of=")
+		if strings.HasPrefix(target, "/dev/") && target != "/dev/null" || strings.HasPrefix(strings.ToLower(target), `\\.\physicaldrive`) {
+			a.add(RuleDiskWrite, SeverityDanger, "直接写入磁盘设备", target)
+		} else {
+			a.write(target)
+		}
+	}
+}
+
+func (a *analyzer) chmod(args []string) {
+	recursive := hasArg(args, "-R", "--recursive")
+	for _, arg := range operands(args) {
+		if !worldWritableModes[arg] {
+			continue
+		}
+		if recursive {
+			a.add(RuleWorldWritable, SeverityDanger, "递归设置所有人可写权限", arg)
+		} else {
+			a.add(RuleWorldWritable, SeverityWarning, "设置所有人可写权限", arg)
+		}
+		return
+	}
+}
+
+// write records a write to path when it lies outside the home directory.
+func (a *analyzer) write(path string) {
+	path = render.ExpandHome(strings.TrimSpace(path))
+	if path == "" || !filepath.IsAbs(path) {
+		return
+	}
+	clean := filepath.Clean(path)
+	switch {
+	case a.home != "" && within(clean, a.home):
+	case within(clean, os.TempDir()) || within(clean, "/tmp") || within(clean, "/private/tmp") || strings.HasPrefix(clean, "/dev/"):
+	case isSystemPath(clean):
+		a.add(RuleSystemPath, SeverityDanger, "写入系统目录", clean)
+	default:
+		a.add(RuleOutsideHome, SeverityWarning, "写入用户主目录以外的位置", clean)
+	}
+}
+
+// critical reports whether deleting path recursively would wipe out the
+// system or the user's data.
+func (a *analyzer) critical(path string) bool {
+	path = strings.TrimSpace(path)
+	switch path {
+	case "/", "/*", "~", "~/", "~/*", "*", ".", "..", "$HOME", "${HOME}":
+		return true
+	}
+	clean := filepath.Clean(render.ExpandHome(path))
+	if a.home != "" && clean == filepath.Clean(a.home) {
+		return true
+	}
+	return isSystemPath(clean) || clean == "/"
+}
+
+// systemDirs are locations a template has no business writing to.
+var systemDirs = []string{"/bin", "/boot", "/dev", "/etc", "/lib", "/lib64", "/sbin", "/usr", "/var", "/System", "/Library", "/Applications"}
+
+func isSystemPath(path string) bool {
+	if runtime.GOOS == "windows" {
+		lower := strings.ToLower(path)
+		return strings.HasPrefix(lower, `c:\windows`) || strings.HasPrefix(lower, `c:\program files`)
+	}
+	if strings.HasPrefix(path, "/dev/") {
+		return false // devices are handled by the dd rule
+	}
+	for _, dir := range systemDirs {
+		if within(path, dir) {
+			return true
+		}
+	}
+	return false
+}
+
+func within(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// programName normalizes argv[0]: base name, lower case, without .exe.
+func programName(arg string) string {
+	name := strings.ToLower(filepath.Base(strings.ReplaceAll(arg, `\`, "/")))
+	return strings.TrimSuffix(name, ".exe")
+}
+
+// skipWrapperArgs drops the options and variable assignments of wrappers
+// such as "sudo -u root" and "env A=1".
+func skipWrapperArgs(args []string) []string {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--":
+			return args[i+1:]
+		case arg == "-u" || arg == "-g" || arg == "-n" && i+1 < len(args) && isNumber(args[i+1]):
+			i++
+		case strings.HasPrefix(arg, "-"), strings.Contains(arg, "=") && !strings.HasPrefix(arg, "/"):
+		default:
+			return args[i:]
+		}
+	}
+	return nil
+}
+
+func isNumber(s string) bool {
+	s = strings.TrimPrefix(s, "-")
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// operands returns the non-option arguments.
+func operands(args []string) []string {
+	var out []string
+	dashdash := false
+	for _, arg := range args {
+		if !dashdash && arg == "--" {
+			dashdash = true
+			continue
+		}
+		if !dashdash && strings.HasPrefix(arg, "-") && arg != "-" {
+			continue
+		}
+		out = append(out, arg)
+	}
+	return out
+}
+
+func hasArg(args []string, names ...string) bool {
+	for _, arg := range args {
+		for _, name := range names {
+			if strings.EqualFold(arg, name) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,149 @@
+package safety
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func summary(r Report) []string {
+	var out []string
+	for _, f := range r.Findings {
+		out = append(out, string(f.Severity)+" "+f.Rule+" "+f.Arg)
+	}
+	return out
+}
+
+func TestAnalyze(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the cases use Unix paths")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil || home == "" {
+		t.Skip("no home directory")
+	}
+	tests := []struct {
+		name    string
+		argv    []string
+		command string
+		outputs []string
+		want    []string
+	}{
+		{"harmless", []string{"ls", "-la", "/etc"}, "", nil, nil},
+
+		// recursive delete
+		{"rm -rf root", []string{"rm", "-rf", "/"}, "", nil, []string{"danger recursive_delete /"}},
+		{"rm -rf home", []string{"rm", "-rf", "~/"}, "", nil, []string{"danger recursive_delete ~/"}},
+		{"rm -rf expanded home", []string{"rm", "-Rf", home}, "", nil, []string{"danger recursive_delete " + home}},
+		{"rm -rf system dir", []string{"rm", "-r", "/usr/lib"}, "", nil, []string{"danger recursive_delete /usr/lib"}},
+		{"rm -rf glob", []string{"rm", "-fr", "*"}, "", nil, []string{"danger recursive_delete *"}},
+		{"rm -r project dir", []string{"rm", "-r", "build", "dist"}, "", nil, []string{"danger recursive_delete build dist"}},
+		{"rm --recursive", []string{"rm", "--recursive", "build"}, "", nil, []string{"danger recursive_delete build"}},
+		{"rm without -r", []string{"rm", "-f", "a.txt"}, "", nil, nil},
+		{"rm -r after --", []string{"rm", "--", "-r"}, "", nil, nil},
+		{"rd /s", []string{"rd", "/s", "/q", "C:\\build"}, "", nil, []string{`danger recursive_delete rd /s /q C:\build`}},
+		{"del /S", []string{"del.exe", "/S", "*.tmp"}, "", nil, []string{"danger recursive_delete del.exe /S *.tmp"}},
+		{"rmdir without /s", []string{"rmdir", "empty"}, "", nil, nil},
+		{"Remove-Item -Recurse", []string{"Remove-Item", "-Recurse", "dir"}, "", nil, []string{"danger recursive_delete Remove-Item -Recurse dir"}},
+
+		// disk writes
+		{"dd to device", []string{"dd", "if=img.iso", "of=/dev/sdb", "bs=4M"}, "", nil, []string{"danger disk_write /dev/sdb"}},
+		{"dd to physical drive", []string{"dd", `of=\\.\PhysicalDrive1`}, "", nil, []string{`danger disk_write \\.\PhysicalDrive1`}},
+		{"dd to /dev/null", []string{"dd", "if=/dev/zero", "of=/dev/null"}, "", nil, nil},
+		{"dd to a file", []string{"dd", "if=/dev/zero", "of=/etc/blob"}, "", nil, []string{"danger write_system_path /etc/blob"}},
+		{"mkfs", []string{"mkfs.ext4", "/dev/sdb1"}, "", nil, []string{"danger disk_write mkfs.ext4 /dev/sdb1"}},
+		{"fdisk", []string{"/sbin/fdisk", "/dev/sda"}, "", nil, []string{"danger disk_write /sbin/fdisk /dev/sda"}},
+		{"wipefs", []string{"wipefs", "-a", "/dev/sdc"}, "", nil, []string{"danger disk_write wipefs -a /dev/sdc"}},
+		{"diskutil erase", []string{"diskutil", "eraseDisk", "APFS", "X", "disk2"}, "", nil, []string{"danger disk_write diskutil eraseDisk APFS X disk2"}},
+		{"diskutil list", []string{"diskutil", "list"}, "", nil, nil},
+
+		// permissions
+		{"chmod 777", []string{"chmod", "777", "f"}, "", nil, []string{"warning world_writable 777"}},
+		{"chmod o+w", []string{"chmod", "o+w", "f"}, "", nil, []string{"warning world_writable o+w"}},
+		{"chmod -R 0777", []string{"chmod", "-R", "0777", "dir"}, "", nil, []string{"danger world_writable 0777"}},
+		{"chmod 755", []string{"chmod", "755", "f"}, "", nil, nil},
+
+		// writes
+		{"cp into system dir", []string{"cp", "a", "/etc/hosts"}, "", nil, []string{"danger write_system_path /etc/hosts"}},
+		{"mv outside home", []string{"mv", "-f", "a", "/opt/app/a"}, "", nil, []string{"warning write_outside_home /opt/app/a"}},
+		{"cp into home", []string{"cp", "/etc/hosts", filepath.Join(home, "hosts")}, "", nil, nil},
+		{"cp into tilde home", []string{"cp", "/etc/hosts", "~/hosts"}, "", nil, nil},
+		{"cp into tmp", []string{"cp", "a", "/tmp/a"}, "", nil, nil},
+		{"cp relative", []string{"cp", "a", "b"}, "", nil, nil},
+		{"ln outside home", []string{"ln", "-s", "a", "/srv/link"}, "", nil, []string{"warning write_outside_home /srv/link"}},
+		{"install", []string{"install", "-m", "755", "tool", "/usr/local/bin/tool"}, "", nil, []string{"danger write_system_path /usr/local/bin/tool"}},
+		{"tee", []string{"tee", "-a", "/etc/motd", "/srv/log"}, "", nil,
+			[]string{"danger write_system_path /etc/motd", "warning write_outside_home /srv/log"}},
+		{"outputs", []string{"ffmpeg", "-i", "a", "b"}, "", []string{"/srv/out.mp4", "/usr/share/x.mp4", "~/out.mp4", ""},
+			[]string{"danger write_system_path /usr/share/x.mp4", "warning write_outside_home /srv/out.mp4"}},
+
+		// wrappers
+		{"sudo", []string{"sudo", "rm", "-rf", "/"}, "", nil,
+			[]string{"danger recursive_delete /", "warning privileged sudo"}},
+		{"sudo -u", []string{"sudo", "-u", "root", "cp", "a", "/opt/b"}, "", nil,
+			[]string{"warning privileged sudo", "warning write_outside_home /opt/b"}},
+		{"doas", []string{"doas", "chmod", "-R", "777", "/srv"}, "", nil,
+			[]string{"danger world_writable 777", "warning privileged doas"}},
+		{"env", []string{"env", "A=1", "B=2", "rm", "-r", "x"}, "", nil, []string{"danger recursive_delete x"}},
+		{"nested wrappers", []string{"nohup", "nice", "-n", "10", "time", "rm", "-r", "x"}, "", nil, []string{"danger recursive_delete x"}},
+		{"wrapper depth is bounded", []string{"env", "env", "env", "env", "env", "env", "rm", "-r", "x"}, "", nil, nil},
+
+		// shells
+		{"sh -c", []string{"sh", "-c", "cd /srv && rm -rf *; echo done > /etc/motd"}, "", nil,
+			[]string{"danger write_system_path /etc/motd", "danger recursive_delete *"}},
+		{"bash -lc", []string{"bash", "-lc", "cp a /opt/b | cat"}, "", nil, []string{"warning write_outside_home /opt/b"}},
+		{"redirect to /dev/null", []string{"sh", "-c", "make >/dev/null 2>&1"}, "", nil, nil},
+		{"append redirect", []string{"sh", "-c", "echo x >> /srv/log"}, "", nil, []string{"warning write_outside_home /srv/log"}},
+		{"sh without -c", []string{"sh", "script.sh"}, "", nil, nil},
+
+		// remote scripts
+		{"curl | sh", []string{"sh", "-c", "curl -fsSL https://x.sh | sh"}, "", nil,
+			[]string{"danger remote_script curl -fsSL https://x.sh | sh"}},
+		{"wget | sudo bash in template", nil, "wget -qO- https://x.sh | sudo bash", nil,
+			[]string{"danger remote_script wget -qO- https://x.sh | sudo bash"}},
+		{"curl | python3", nil, "curl https://x.py | python3 -", nil,
+			[]string{"danger remote_script curl https://x.py | python3"}},
+		{"curl to a file", nil, "curl -o x.sh https://x.sh && sh x.sh", nil, nil},
+		{"powershell iwr | iex", []string{"powershell", "-Command", "iwr https://x.ps1 | iex"}, "", nil,
+			[]string{"danger remote_script iwr https://x.ps1 | iex"}},
+		{"DownloadString", nil, "pwsh -c (New-Object Net.WebClient).DownloadString('https://x') | Invoke-Expression", nil,
+			[]string{"danger remote_script DownloadString('https://x') | Invoke-Expression"}},
+
+		// duplicates are reported once
+		{"duplicates", []string{"sh", "-c", "rm -rf /; rm -rf /"}, "", nil, []string{"danger recursive_delete /"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := Analyze(Input{Command: tt.command, Argv: tt.argv, Outputs: tt.outputs, Home: home})
+			if got := summary(r); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("findings = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReport(t *testing.T) {
+	r := Analyze(Input{Argv: []string{"echo", "hi"}, Home: "/home/u"})
+	if len(r.Findings) != 0 || r.Severity != "" || r.RequiresConfirmation {
+		t.Errorf("harmless command report = %+v", r)
+	}
+
+	r = Analyze(Input{Argv: []string{"sudo", "ls"}, Home: "/home/u"})
+	if r.Severity != SeverityWarning || !r.RequiresConfirmation {
+		t.Errorf("warning report = %+v", r)
+	}
+
+	r = Analyze(Input{Argv: []string{"sudo", "rm", "-rf", "/"}, Home: "/home/u"})
+	if r.Severity != SeverityDanger || !r.RequiresConfirmation {
+		t.Errorf("danger report = %+v", r)
+	}
+	if r.Findings[0].Severity != SeverityDanger {
+		t.Errorf("danger findings are not listed first: %+v", r.Findings)
+	}
+	if !strings.Contains(r.Findings[0].Message, "根目录") {
+		t.Errorf("critical delete message = %q", r.Findings[0].Message)
+	}
+}
@@ -0,0 +1,230 @@
+package template
+
+import (
+	"fmt"
+	"strings"
+
+	"repo/shared-go-lib/models"
+	"repo/shared-go-lib/render"
+)
+
+// Resolve returns a copy of t in which every command lists all of its
+// variables: those of the command it `extends`, then the template-level
+// variables it `include`s, then its own. An own variable replaces an
+// inherited or included one of the same name in place. Inherited and
+// included variables the command never uses are dropped, so a command can
+// extend another and use only part of its variables. The copy has no
+// template-level variables, extends or include left, so resolving it again
+// changes nothing. t itself is not modified.
+func Resolve(t *models.TemplateFile) (*models.TemplateFile, error) {
+	shared := map[string]models.VariableDefinition{}
+	for _, v := range t.Variables {
+		if v.Name == "" {
+			return nil, fmt.Errorf("template variable missing name")
+		}
+		if _, dup := shared[v.Name]; dup {
+			return nil, fmt.Errorf("duplicate template variable '%s'", v.Name)
+		}
+		shared[v.Name] = v
+	}
+	byID := map[string][]int{}
+	for i, c := range t.Cmds {
+		if c.ID != "" {
+			byID[c.ID] = append(byID[c.ID], i)
+		}
+	}
+
+	// resolved 为命令的完整变量列表, 子命令继承完整列表而不是去掉未使用变量后的列表
+	resolved := make([][]models.VariableDefinition, len(t.Cmds))
+	state := make([]int, len(t.Cmds)) // 1 解析中, 2 已完成
+	var resolve func(i int, path []string) error
+	resolve = func(i int, path []string) error {
+		c := t.Cmds[i]
+		switch state[i] {
+		case 2:
+			return nil
+		case 1:
+			for k, id := range path {
+				if id == c.ID {
+					path = path[k:]
+					break
+				}
+			}
+			return fmt.Errorf("commands extend each other in a cycle: %s", strings.Join(append(path, c.ID), " -> "))
+		}
+		state[i] = 1
+
+		var vars []models.VariableDefinition
+		if c.Extends != "" {
+			parents := byID[c.Extends]
+			switch {
+			case len(parents) == 0:
+				return fmt.Errorf("command '%s' extends unknown command '%s'", c.Name, c.Extends)
+			case len(parents) > 1:
+				return fmt.Errorf("command '%s' extends '%s', which is the id of more than one command", c.Name, c.Extends)
+			}
+			if err := resolve(parents[0], append(path, c.ID)); err != nil {
+				return err
+			}
+			vars = append(vars, resolved[parents[0]]...)
+		}
+		inherited := len(vars)
+		for _, name := range c.Include {
+			v, ok := shared[name]
+			if !ok {
+				return fmt.Errorf("command '%s' includes unknown template variable '%s'", c.Name, name)
+			}
+			switch k := variableIndex(vars, name); {
+			case k >= 0 && k < inherited:
+				return fmt.Errorf("command '%s' includes '%s', which it already inherits from '%s'", c.Name, name, c.Extends)
+			case k >= 0:
+				return fmt.Errorf("command '%s' includes '%s' more than once", c.Name, name)
+			}
+			vars = append(vars, v)
+		}
+		own := map[string]struct{}{}
+		for _, v := range c.Variables {
+			if _, dup := own[v.Name]; dup {
+				return fmt.Errorf("duplicate variable name '%s'", v.Name)
+			}
+			own[v.Name] = struct{}{}
+			if k := variableIndex(vars, v.Name); k >= 0 {
+				vars[k] = v
+			} else {
+				vars = append(vars, v)
+			}
+		}
+		resolved[i] = vars
+		state[i] = 2
+		return nil
+	}
+
+	out := *t
+	out.Variables = nil
+	out.Cmds = make([]models.Command, len(t.Cmds))
+	for i, c := range t.Cmds {
+		if err := resolve(i, nil); err != nil {
+			return nil, err
+		}
+		c.Variables = usedVariables(c, resolved[i])
+		c.Extends, c.Include = "", nil
+		out.Cmds[i] = c
+	}
+	return &out, nil
+}
+
+func variableIndex(vars []models.VariableDefinition, name string) int {
+	for i, v := range vars {
+		if v.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// usedVariables drops the inherited and included variables that c does not
+// use: its own variables are kept, as is every variable that the command
+// text, workdir, env or stdin refers to, that is passed as an environment
+// variable, or that a kept variable's expression, options_from or condition
+// refers to. If any of these fails to parse, all variables are kept and
+// validation reports the error.
+func usedVariables(c models.Command, vars []models.VariableDefinition) []models.VariableDefinition {
+	used := map[string]bool{}
+	for _, v := range c.Variables {
+		used[v.Name] = true
+	}
+	texts := []string{c.Workdir}
+	for _, value := range c.Env {
+		texts = append(texts, value)
+	}
+	refs, ok := commandRefs(c.Command, texts)
+	if !ok {
+		return vars
+	}
+	for _, name := range refs {
+		used[name] = true
+	}
+	if c.Stdin != "" {
+		used[c.Stdin] = true
+	}
+	for _, v := range vars {
+		if v.Env != "" {
+			used[v.Name] = true
+		}
+	}
+	// 被保留的变量引用的变量也要保留, 直到不再增加
+	for changed := true; changed; {
+		changed = false
+		for _, v := range vars {
+			if !used[v.Name] {
+				continue
+			}
+			refs, ok := variableRefs(v)
+			if !ok {
+				return vars
+			}
+			for _, name := range refs {
+				if !used[name] {
+					used[name], changed = true, true
+				}
+			}
+		}
+	}
+
+	kept := make([]models.VariableDefinition, 0, len(vars))
+	for _, v := range vars {
+		if used[v.Name] {
+			kept = append(kept, v)
+		}
+	}
+	return kept
+}
+
+// commandRefs returns the variables used by a command template and by
+// plain texts such as the workdir and env values.
+func commandRefs(command string, texts []string) ([]string, bool) {
+	tmpl, err := render.Parse(command)
+	if err != nil {
+		return nil, false
+	}
+	refs := tmpl.Variables()
+	for _, text := range texts {
+		names, err := render.Placeholders(text)
+		if err != nil {
+			return nil, false
+		}
+		refs = append(refs, names...)
+	}
+	return refs, true
+}
+
+// variableRefs returns the variables used by v's options_from command,
+// expression, show_if and required_if.
+func variableRefs(v models.VariableDefinition) ([]string, bool) {
+	var refs []string
+	if src := v.OptionSource(); src != nil {
+		tmpl, err := render.Parse(src.Command)
+		if err != nil {
+			return nil, false
+		}
+		refs = append(refs, tmpl.Variables()...)
+	}
+	if expr := render.Expression(v); expr != "" {
+		names, err := render.Placeholders(expr)
+		if err != nil {
+			return nil, false
+		}
+		refs = append(refs, names...)
+	}
+	for _, expr := range []string{v.ShowIf, v.RequiredIf} {
+		if expr == "" {
+			continue
+		}
+		cond, err := render.ParseCondition(expr)
+		if err != nil {
+			return nil, false
+		}
+		refs = append(refs, cond.Variables()...)
+	}
+	return refs, true
+}
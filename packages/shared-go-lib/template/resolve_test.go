@@ -0,0 +1,220 @@
+package template
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"repo/shared-go-lib/models"
+)
+
+func textVar(name string) models.VariableDefinition {
+	return models.VariableDefinition{Name: name, Type: models.VarTypeText, Label: name}
+}
+
+func varNames(vars []models.VariableDefinition) []string {
+	names := []string{}
+	for _, v := range vars {
+		names = append(names, v.Name)
+	}
+	return names
+}
+
+func testTemplate(vars []models.VariableDefinition, cmds ...models.Command) *models.TemplateFile {
+	for i := range cmds {
+		if cmds[i].Name == "" {
+			cmds[i].Name = cmds[i].ID
+		}
+		cmds[i].Description = cmds[i].Name
+	}
+	return &models.TemplateFile{
+		Name:                "test",
+		Description:         "test",
+		Version:             "1.0",
+		Author:              "test",
+		CliqTemplateVersion: "1.0",
+		Variables:           vars,
+		Cmds:                cmds,
+	}
+}
+
+func TestResolve(t *testing.T) {
+	shared := []models.VariableDefinition{textVar("out"), textVar("log")}
+	overridden := textVar("codec")
+	overridden.Description = "override"
+	tmpl := testTemplate(shared,
+		models.Command{ID: "base", Command: "ffmpeg -i {{in}} -c {{codec}} {{q}}",
+			Variables: []models.VariableDefinition{textVar("in"), textVar("codec"), textVar("q")}},
+		// child is resolved before its parent is reached in the loop
+		models.Command{ID: "child", Extends: "mid", Command: "x {{codec}} {{q}} {{out}} {{extra}}",
+			Include:   []string{"out"},
+			Variables: []models.VariableDefinition{textVar("extra"), overridden}},
+		models.Command{ID: "mid", Extends: "base", Command: "y {{in}} {{codec}} {{q}} {{log}}",
+			Include: []string{"log"}},
+	)
+	got, err := Resolve(tmpl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string][]string{
+		"base":  {"in", "codec", "q"},
+		"child": {"codec", "q", "out", "extra"},
+		"mid":   {"in", "codec", "q", "log"},
+	}
+	for _, c := range got.Cmds {
+		if names := varNames(c.Variables); !reflect.DeepEqual(names, want[c.ID]) {
+			t.Errorf("command '%s' variables = %q, want %q", c.ID, names, want[c.ID])
+		}
+		if c.Extends != "" || c.Include != nil {
+			t.Errorf("command '%s' still has extends or include", c.ID)
+		}
+	}
+	if codec := got.Cmds[1].Variables[0]; codec.Description != "override" {
+		t.Errorf("own variable did not replace the inherited one: %+v", codec)
+	}
+	if got.Variables != nil {
+		t.Errorf("resolved template still has template variables")
+	}
+
+	// t itself is not modified
+	if len(tmpl.Variables) != 2 || tmpl.Cmds[1].Extends != "mid" || len(tmpl.Cmds[1].Variables) != 2 || len(tmpl.Cmds[2].Variables) != 0 {
+		t.Errorf("Resolve modified its input: %+v", tmpl)
+	}
+
+	// resolving again changes nothing
+	again, err := Resolve(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(again, got) {
+		t.Errorf("Resolve is not idempotent:\n%+v\n%+v", again, got)
+	}
+}
+
+// Inherited variables used only through env, stdin, workdir, expressions,
+// options_from or conditions are kept.
+func TestResolveKeepsIndirectlyUsedVariables(t *testing.T) {
+	token := textVar("token")
+	token.Env = "API_TOKEN"
+	mode := models.VariableDefinition{Name: "mode", Type: models.VarTypeSelect, Label: "mode"}
+	rate := textVar("rate")
+	rate.ShowIf = "mode == cbr"
+	tmpl := testTemplate(nil,
+		models.Command{ID: "base", Command: "run {{dir}} {{body}} {{rate}} {{mode}} {{unused}}",
+			Variables: []models.VariableDefinition{textVar("dir"), textVar("body"), token, mode, rate, textVar("unused")}},
+		models.Command{ID: "child", Extends: "base", Command: "call {{rate}}", Workdir: "{{dir}}", Stdin: "body"},
+	)
+	got, err := Resolve(tmpl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"dir", "body", "token", "mode", "rate"}
+	if names := varNames(got.Cmds[1].Variables); !reflect.DeepEqual(names, want) {
+		t.Errorf("child variables = %q, want %q", names, want)
+	}
+}
+
+func TestResolveErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		tmpl *models.TemplateFile
+		want string
+	}{
+		{"cycle", testTemplate(nil,
+			models.Command{ID: "a", Extends: "b", Command: "a"},
+			models.Command{ID: "b", Extends: "a", Command: "b"},
+		), "commands extend each other in a cycle: a -> b -> a"},
+		{"longer cycle", testTemplate(nil,
+			models.Command{ID: "x", Extends: "a", Command: "x"},
+			models.Command{ID: "a", Extends: "b", Command: "a"},
+			models.Command{ID: "b", Extends: "c", Command: "b"},
+			models.Command{ID: "c", Extends: "a", Command: "c"},
+		), "commands extend each other in a cycle: a -> b -> c -> a"},
+		{"self", testTemplate(nil,
+			models.Command{ID: "a", Extends: "a", Command: "a"},
+		), "commands extend each other in a cycle: a -> a"},
+		{"unknown extends", testTemplate(nil,
+			models.Command{ID: "a", Extends: "nope", Command: "a"},
+		), "command 'a' extends unknown command 'nope'"},
+		{"ambiguous extends", testTemplate(nil,
+			models.Command{ID: "a", Command: "a"},
+			models.Command{ID: "a", Name: "a2", Command: "a"},
+			models.Command{ID: "b", Extends: "a", Command: "b"},
+		), "command 'b' extends 'a', which is the id of more than one command"},
+		{"unknown include", testTemplate(nil,
+			models.Command{ID: "a", Include: []string{"out"}, Command: "a"},
+		), "command 'a' includes unknown template variable 'out'"},
+		{"include conflicts with extends", testTemplate([]models.VariableDefinition{textVar("out")},
+			models.Command{ID: "a", Include: []string{"out"}, Command: "a {{out}}"},
+			models.Command{ID: "b", Extends: "a", Include: []string{"out"}, Command: "b {{out}}"},
+		), "command 'b' includes 'out', which it already inherits from 'a'"},
+		{"duplicate include", testTemplate([]models.VariableDefinition{textVar("out")},
+			models.Command{ID: "a", Include: []string{"out", "out"}, Command: "a {{out}}"},
+		), "command 'a' includes 'out' more than once"},
+		{"duplicate template variable", testTemplate([]models.VariableDefinition{textVar("out"), textVar("out")},
+			models.Command{ID: "a", Command: "a"},
+		), "duplicate template variable 'out'"},
+		{"template variable without name", testTemplate([]models.VariableDefinition{{Type: models.VarTypeText}},
+			models.Command{ID: "a", Command: "a"},
+		), "template variable missing name"},
+		{"duplicate own variable", testTemplate(nil,
+			models.Command{ID: "a", Command: "a {{x}}", Variables: []models.VariableDefinition{textVar("x"), textVar("x")}},
+		), "duplicate variable name 'x'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Resolve(tt.tmpl)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Resolve error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateTemplateVariableUse(t *testing.T) {
+	tests := []struct {
+		name string
+		tmpl *models.TemplateFile
+		want string
+	}{
+		{"inherited variables may go unused", testTemplate([]models.VariableDefinition{textVar("out"), textVar("log")},
+			models.Command{ID: "a", Include: []string{"out", "log"}, Command: "a {{in}} {{out}} {{log}}",
+				Variables: []models.VariableDefinition{textVar("in")}},
+			models.Command{ID: "b", Extends: "a", Include: nil, Command: "b {{in}}"},
+		), ""},
+		{"own variable must be used", testTemplate(nil,
+			models.Command{ID: "a", Command: "a {{in}}", Variables: []models.VariableDefinition{textVar("in"), textVar("extra")}},
+		), "variable 'extra' not referenced in command"},
+		{"unknown variable", testTemplate(nil,
+			models.Command{ID: "a", Command: "a {{in}} {{nope}}", Variables: []models.VariableDefinition{textVar("in")}},
+		), "command 'a' references unknown variable 'nope'"},
+		{"unused template variable", testTemplate([]models.VariableDefinition{textVar("out"), textVar("log")},
+			models.Command{ID: "a", Include: []string{"out"}, Command: "a {{out}}"},
+		), "template variable 'log' is not included by any command"},
+		{"invalid variable name", testTemplate(nil,
+			models.Command{ID: "a", Command: "a", Variables: []models.VariableDefinition{textVar("my var")}},
+		), "variable 'my var' has invalid name"},
+		{"baseline names stay valid", testTemplate(nil,
+			models.Command{ID: "a", Command: "a {{max-size}} {{#if 2pass}}--pass 2{{/if}}",
+				Variables: []models.VariableDefinition{textVar("max-size"), textVar("2pass")}},
+		), ""},
+		{"extends cycle", testTemplate(nil,
+			models.Command{ID: "a", Extends: "b", Command: "a"},
+			models.Command{ID: "b", Extends: "a", Command: "b"},
+		), "commands extend each other in a cycle"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateTemplate(tt.tmpl)
+			if tt.want == "" {
+				if err != nil {
+					t.Errorf("ValidateTemplate error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("ValidateTemplate error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
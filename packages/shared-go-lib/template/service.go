@@ -7,6 +7,7 @@ import (
     "gopkg.in/yaml.v3"
 
     "repo/shared-go-lib/models"
+    "repo/shared-go-lib/render"
 )
 
 // TemplateService provides template-related business logic
@@ -24,7 +25,10 @@ func (ts *TemplateService) ParseCommandToTemplate(commandStr string) (*models.Te
 	}
 
 	// 从命令字符串中提取变量
-	variables := extractVariablesFromCommand(commandStr)
+	variables, err := extractVariablesFromCommand(commandStr)
+	if err != nil {
+		return nil, fmt.Errorf("解析命令失败: %w", err)
+	}
 
 	// 生成模板
 	templateFile := &models.TemplateFile{
@@ -145,44 +149,25 @@ func (ts *TemplateService) ParseYAMLToTemplate(yamlStr string) (*models.Template
 	return &template, nil
 }
 
-// extractVariablesFromCommand 从命令字符串中提取变量名
-func extractVariablesFromCommand(commandStr string) []string {
-	var variables []string
-	parts := strings.Fields(commandStr)
-
-	for _, part := range parts {
-		// 查找 {{variable_name}} 格式的变量
-		for start := 0; start < len(part); {
-			pos := strings.Index(part[start:], "{{")
-			if pos == -1 {
-				break
-			}
-			start += pos
-
-			endPos := strings.Index(part[start:], "}}")
-			if endPos == -1 {
-				break
-			}
-			endPos += start
-
-			variable := strings.TrimSpace(part[start+2 : endPos])
-			// 检查是否已存在
-			exists := false
-			for _, v := range variables {
-				if v == variable {
-					exists = true
-					break
-				}
-			}
-			if !exists {
-				variables = append(variables, variable)
-			}
-
-			start = endPos + 2
-		}
+// ResolveTemplate 展开模板中命令的 extends 和 include, 返回每个命令都带有完整变量列表的副本
+func (ts *TemplateService) ResolveTemplate(template *models.TemplateFile) (*models.TemplateFile, error) {
+	if template == nil {
+		return nil, fmt.Errorf("模板不能为空")
+	}
+	resolved, err := Resolve(template)
+	if err != nil {
+		return nil, fmt.Errorf("展开模板失败: %w", err)
 	}
+	return resolved, nil
+}
 
-	return variables
+// extractVariablesFromCommand 从命令字符串中提取变量名, 包括 {{#if}} / {{#each}} 块引用的变量
+func extractVariablesFromCommand(commandStr string) ([]string, error) {
+	tmpl, err := render.Parse(commandStr)
+	if err != nil {
+		return nil, err
+	}
+	return tmpl.Variables(), nil
 }
 
 // determineVariableType 根据变量名确定变量类型
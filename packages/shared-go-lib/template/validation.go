@@ -2,9 +2,13 @@ package template
 
 import (
     "fmt"
+    "regexp"
     "strings"
+    "time"
 
+    "repo/shared-go-lib/inputs"
     "repo/shared-go-lib/models"
+    "repo/shared-go-lib/render"
 )
 
 var allowedTypes = map[string]struct{}{
@@ -14,6 +18,14 @@ var allowedTypes = map[string]struct{}{
     "number": {},
     "boolean": {},
     "select": {},
+    "multi_select": {},
+    "file_list": {},
+    "directory": {},
+    "secret": {},
+    "textarea": {},
+    "date": {},
+    "datetime": {},
+    "key_value": {},
 }
 
 func ValidateTemplate(t *models.TemplateFile) error {
@@ -23,10 +35,29 @@ func ValidateTemplate(t *models.TemplateFile) error {
     if len(t.Cmds) == 0 {
         return fmt.Errorf("cmds must contain at least one command")
     }
+    if err := validateSharedVariables(t); err != nil {
+        return err
+    }
+    // extends / include 展开后, 每个命令按自身的完整变量列表校验
+    t, err := Resolve(t)
+    if err != nil {
+        return err
+    }
+    if err := validateRequirements("template", t.Requires); err != nil {
+        return err
+    }
     for _, c := range t.Cmds {
         if c.Name == "" || c.Description == "" || c.Command == "" {
             return fmt.Errorf("command '%s' missing required fields", c.Name)
         }
+        if c.Timeout != "" {
+            if d, err := time.ParseDuration(c.Timeout); err != nil || d <= 0 {
+                return fmt.Errorf("command '%s' has invalid timeout '%s'", c.Name, c.Timeout)
+            }
+        }
+        if err := validateRequirements(fmt.Sprintf("command '%s'", c.Name), c.Requires); err != nil {
+            return err
+        }
         if len(c.Variables) == 0 {
             return fmt.Errorf("command '%s' must define variables", c.Name)
         }
@@ -35,6 +66,9 @@ func ValidateTemplate(t *models.TemplateFile) error {
             if v.Name == "" || v.Type == "" || v.Label == "" {
                 return fmt.Errorf("variable missing required fields in command '%s'", c.Name)
             }
+            if !render.ValidVariableName(v.Name) {
+                return fmt.Errorf("variable '%s' has invalid name: %s", v.Name, render.VariableNameRule)
+            }
             if _, ok := allowedTypes[v.Type]; !ok {
                 return fmt.Errorf("variable '%s' has unsupported type '%s'", v.Name, v.Type)
             }
@@ -42,14 +76,624 @@ func ValidateTemplate(t *models.TemplateFile) error {
                 return fmt.Errorf("duplicate variable name '%s'", v.Name)
             }
             names[v.Name] = struct{}{}
+            if v.Env != "" && !render.ValidEnvName(v.Env) {
+                return fmt.Errorf("variable '%s' has invalid env name '%s'", v.Name, v.Env)
+            }
+            if v.Env != "" && v.Type == models.VarTypeKeyValue {
+                return fmt.Errorf("variable '%s' of type key_value cannot set env", v.Name)
+            }
+            if err := validateOptions(v); err != nil {
+                return err
+            }
+        }
+        if c.Output != nil {
+            if err := validateOutput(c); err != nil {
+                return err
+            }
+        }
+        if c.Progress != nil {
+            if err := validateProgress(c); err != nil {
+                return err
+            }
+        }
+        referenced, err := validateWorkdirEnv(c, names)
+        if err != nil {
+            return err
+        }
+        if err := validateExpressions(c, names, referenced); err != nil {
+            return err
+        }
+        if err := validateConditions(c, names, referenced); err != nil {
+            return err
+        }
+        if err := validatePresets(c); err != nil {
+            return err
+        }
+        // placeholder consistency: the command may only use defined variables,
+        // and each variable should appear in the command (or workdir/env/stdin).
+        // Resolve 已去掉未使用的继承和引用变量, 所以这里只会报告命令自己定义的变量
+        tmpl, err := render.Parse(c.Command)
+        if err != nil {
+            return fmt.Errorf("command '%s' has invalid command template: %v", c.Name, err)
+        }
+        for _, name := range tmpl.Variables() {
+            if _, ok := names[name]; !ok {
+                return fmt.Errorf("command '%s' references unknown variable '%s'", c.Name, name)
+            }
+            referenced[name] = struct{}{}
+        }
+        for _, v := range c.Variables {
+            if v.Env != "" {
+                continue // injected as an environment variable
+            }
+            if _, ok := referenced[v.Name]; !ok {
+                return fmt.Errorf("variable '%s' not referenced in command", v.Name)
+            }
+        }
+    }
+    return validatePipelines(t)
+}
+
+// validateSharedVariables checks that every template-level variable is
+// included by at least one command. The variables themselves are checked
+// as part of the commands that include them.
+func validateSharedVariables(t *models.TemplateFile) error {
+    included := map[string]struct{}{}
+    for _, c := range t.Cmds {
+        for _, name := range c.Include {
+            included[name] = struct{}{}
+        }
+    }
+    for _, v := range t.Variables {
+        if _, ok := included[v.Name]; !ok {
+            return fmt.Errorf("template variable '%s' is not included by any command", v.Name)
+        }
+    }
+    return nil
+}
+
+// validateWorkdirEnv checks the workdir, env and stdin fields of a command
+// and returns the variables they reference.
+func validateWorkdirEnv(c models.Command, names map[string]struct{}) (map[string]struct{}, error) {
+    referenced := map[string]struct{}{}
+    check := func(field, text string) error {
+        refs, err := render.Placeholders(text)
+        if err != nil {
+            return fmt.Errorf("command '%s' has invalid %s: %v", c.Name, field, err)
+        }
+        for _, ref := range refs {
+            if _, ok := names[ref]; !ok {
+                return fmt.Errorf("command '%s' %s references unknown variable '%s'", c.Name, field, ref)
+            }
+            referenced[ref] = struct{}{}
+        }
+        return nil
+    }
+    if err := check("workdir", c.Workdir); err != nil {
+        return nil, err
+    }
+    for name, value := range c.Env {
+        if !render.ValidEnvName(name) {
+            return nil, fmt.Errorf("command '%s' has invalid env name '%s'", c.Name, name)
+        }
+        if err := check("env "+name, value); err != nil {
+            return nil, err
+        }
+    }
+    if c.Stdin != "" {
+        var stdin *models.VariableDefinition
+        for i := range c.Variables {
+            if c.Variables[i].Name == c.Stdin {
+                stdin = &c.Variables[i]
+            }
+        }
+        if stdin == nil {
+            return nil, fmt.Errorf("command '%s' stdin references unknown variable '%s'", c.Name, c.Stdin)
+        }
+        switch stdin.Type {
+        case models.VarTypeText, models.VarTypeTextarea, models.VarTypeSecret, models.VarTypeFileInput:
+        default:
+            return nil, fmt.Errorf("command '%s' stdin variable '%s' must be of type string, textarea, secret or file_input", c.Name, c.Stdin)
+        }
+        referenced[c.Stdin] = struct{}{}
+    }
+    return referenced, nil
+}
+
+// validateOptions decodes a variable's options into the struct of its type,
+// which rejects unknown keys and values of the wrong type, and checks that
+// they are consistent: `min` not above `max`, a valid `pattern`, and a fixed
+// default that satisfies them and the choices of a select.
+func validateOptions(v models.VariableDefinition) error {
+    decoded, err := models.DecodeOptions(v)
+    if err != nil {
+        return fmt.Errorf("variable '%s' has invalid options: %v", v.Name, err)
+    }
+    if pattern := v.Pattern(); pattern != "" {
+        if _, err := inputs.CompilePattern(pattern); err != nil {
+            return fmt.Errorf("variable '%s' has invalid pattern: %v", v.Name, err)
+        }
+    }
+    fixedDefault := render.Expression(v) == ""
+    var def string
+    switch opts := decoded.(type) {
+    case *models.NumberOptions:
+        if opts.Min != nil && opts.Max != nil && *opts.Min > *opts.Max {
+            return fmt.Errorf("variable '%s' has min greater than max", v.Name)
+        }
+        if d := opts.Default; d != nil && ((opts.Min != nil && *d < *opts.Min) || (opts.Max != nil && *d > *opts.Max)) {
+            return fmt.Errorf("variable '%s' default is outside min/max", v.Name)
+        }
+        return nil
+    case *models.SelectOptions:
+        if err := validateSource(v, opts.Options, opts.OptionsFrom); err != nil {
+            return err
+        }
+        for i, c := range opts.Options {
+            for _, prev := range opts.Options[:i] {
+                if prev.Value == c.Value {
+                    return fmt.Errorf("variable '%s' has duplicate option '%s'", v.Name, c.Value)
+                }
+            }
+        }
+        def = opts.Default
+        if fixedDefault && def != "" && len(opts.Options) > 0 && !containsString(opts.Values(), def) {
+            return fmt.Errorf("variable '%s' default '%s' is not one of its options", v.Name, def)
+        }
+    case *models.MultiSelectOptions:
+        if err := validateSource(v, opts.Options, opts.OptionsFrom); err != nil {
+            return err
+        }
+        if len(opts.Options) == 0 && opts.OptionsFrom == nil {
+            return fmt.Errorf("variable '%s' of type multi_select needs options or options_from", v.Name)
+        }
+        choices := (models.SelectOptions{Options: opts.Options}).Values()
+        for i, value := range choices {
+            if containsString(choices[:i], value) {
+                return fmt.Errorf("variable '%s' has duplicate option '%s'", v.Name, value)
+            }
+        }
+        for _, d := range opts.Default {
+            if !containsString(choices, d) {
+                return fmt.Errorf("variable '%s' default '%s' is not one of its options", v.Name, d)
+            }
+        }
+        return nil
+    case *models.DateOptions:
+        if err := render.ValidDateFormat(opts.Format); err != nil {
+            return fmt.Errorf("variable '%s' has invalid date format: %v", v.Name, err)
+        }
+        if opts.Default != "" {
+            if _, ok := render.ParseDate(opts.Default); !ok {
+                return fmt.Errorf("variable '%s' default '%s' is not a date such as 2024-05-01 or 2024-05-01T10:30", v.Name, opts.Default)
+            }
+        }
+        return nil
+    case *models.TextareaOptions:
+        if opts.Rows < 0 {
+            return fmt.Errorf("variable '%s' option 'rows' must not be negative", v.Name)
+        }
+        def = opts.Default
+    case *models.DirectoryOptions:
+        def = opts.Default
+    case *models.TextOptions:
+        def = opts.Default
+    case *models.FileOptions:
+        if v.Type == models.VarTypeFileInput {
+            return nil // 输入文件的默认值在执行时检查
+        }
+        def = opts.Default
+    }
+    if pattern := v.Pattern(); pattern != "" && fixedDefault && def != "" {
+        if re, _ := inputs.CompilePattern(pattern); !re.MatchString(def) {
+            return fmt.Errorf("variable '%s' default '%s' does not match its pattern", v.Name, def)
+        }
+    }
+    return nil
+}
+
+// validateSource checks the `options_from` of a select or multi_select. The
+// variables its command refers to are checked by validateExpressions.
+func validateSource(v models.VariableDefinition, options []models.Choice, src *models.OptionSource) error {
+    if src == nil {
+        return nil
+    }
+    if len(options) > 0 {
+        return fmt.Errorf("variable '%s' cannot set both options and options_from", v.Name)
+    }
+    if strings.TrimSpace(src.Command) == "" {
+        return fmt.Errorf("variable '%s' options_from needs a command", v.Name)
+    }
+    if _, err := render.Parse(src.Command); err != nil {
+        return fmt.Errorf("variable '%s' options_from has invalid command: %v", v.Name, err)
+    }
+    switch src.OutputFormat() {
+    case models.SourceLines:
+        if src.JSONPath != "" || src.Value != "" || src.Label != "" {
+            return fmt.Errorf("variable '%s' options_from json_path, value and label need format json", v.Name)
+        }
+    case models.SourceJSON:
+    default:
+        return fmt.Errorf("variable '%s' options_from has unsupported format '%s', expected lines or json", v.Name, src.Format)
+    }
+    if src.Cache != "" {
+        if d, err := time.ParseDuration(src.Cache); err != nil || d < 0 {
+            return fmt.Errorf("variable '%s' options_from has invalid cache '%s'", v.Name, src.Cache)
+        }
+    }
+    if src.Timeout != "" {
+        if d, err := time.ParseDuration(src.Timeout); err != nil || d <= 0 {
+            return fmt.Errorf("variable '%s' options_from has invalid timeout '%s'", v.Name, src.Timeout)
+        }
+    }
+    return nil
+}
+
+func containsString(list []string, s string) bool {
+    for _, item := range list {
+        if item == s {
+            return true
+        }
+    }
+    return false
+}
+
+// validateExpressions checks the `computed` fields and interpolated
+// defaults of a command's variables: they may only use defined variables
+// other than their own and known filters, and must not depend on each other
+// in a cycle. The same applies to the commands of `options_from`. Variables
+// used by an expression are added to referenced.
+func validateExpressions(c models.Command, names map[string]struct{}, referenced map[string]struct{}) error {
+    for _, v := range c.Variables {
+        src := v.OptionSource()
+        if src == nil {
+            continue
+        }
+        tmpl, err := render.Parse(src.Command)
+        if err != nil {
+            continue // validateOptions 已报告
+        }
+        for _, ref := range tmpl.Variables() {
+            if ref == v.Name {
+                return fmt.Errorf("variable '%s' options_from references itself", v.Name)
+            }
+            if _, ok := names[ref]; !ok {
+                return fmt.Errorf("variable '%s' options_from references unknown variable '%s'", v.Name, ref)
+            }
+            referenced[ref] = struct{}{}
+        }
+    }
+    for _, v := range c.Variables {
+        expr := render.Expression(v)
+        if expr == "" {
+            continue
+        }
+        refs, err := render.Placeholders(expr)
+        if err != nil {
+            return fmt.Errorf("variable '%s' has invalid expression: %v", v.Name, err)
         }
-        // placeholder consistency: each var should appear in command string
-        for name := range names {
-            ph := "{{" + name + "}}"
-            if !strings.Contains(c.Command, ph) {
-                return fmt.Errorf("variable '%s' not referenced in command", name)
+        for _, ref := range refs {
+            if ref == v.Name {
+                return fmt.Errorf("variable '%s' expression references itself", v.Name)
+            }
+            if _, ok := names[ref]; !ok {
+                return fmt.Errorf("variable '%s' expression references unknown variable '%s'", v.Name, ref)
+            }
+            referenced[ref] = struct{}{}
+        }
+    }
+    if _, err := render.ExpressionOrder(c.Variables); err != nil {
+        return fmt.Errorf("command '%s': %v", c.Name, err)
+    }
+    return nil
+}
+
+// validateConditions checks the show_if and required_if expressions of a
+// command's variables: they must parse and only refer to other variables of
+// the command, and show_if conditions must not depend on each other in a
+// cycle. Variables used by a condition are added to referenced.
+func validateConditions(c models.Command, names map[string]struct{}, referenced map[string]struct{}) error {
+    for _, v := range c.Variables {
+        if v.Required && v.RequiredIf != "" {
+            return fmt.Errorf("variable '%s' sets both required and required_if", v.Name)
+        }
+        for _, field := range []struct{ name, expr string }{{"show_if", v.ShowIf}, {"required_if", v.RequiredIf}} {
+            if field.expr == "" {
+                continue
+            }
+            cond, err := render.ParseCondition(field.expr)
+            if err != nil {
+                return fmt.Errorf("variable '%s' has invalid %s: %v", v.Name, field.name, err)
+            }
+            for _, ref := range cond.Variables() {
+                if ref == v.Name {
+                    return fmt.Errorf("variable '%s' %s references itself", v.Name, field.name)
+                }
+                if _, ok := names[ref]; !ok {
+                    return fmt.Errorf("variable '%s' %s references unknown variable '%s'", v.Name, field.name, ref)
+                }
+                referenced[ref] = struct{}{}
+            }
+        }
+    }
+    if _, err := render.ConditionOrder(c.Variables); err != nil {
+        return fmt.Errorf("command '%s': %v", c.Name, err)
+    }
+    return nil
+}
+
+// validatePresets checks the presets of a command: names must be unique,
+// values may only set the command's variables (never secrets) and must fit
+// numbers, booleans and static choices. At most one preset is the default.
+func validatePresets(c models.Command) error {
+    byName := map[string]models.VariableDefinition{}
+    for _, v := range c.Variables {
+        byName[v.Name] = v
+    }
+    seen := map[string]struct{}{}
+    defaultName := ""
+    for _, p := range c.Presets {
+        if strings.TrimSpace(p.Name) == "" {
+            return fmt.Errorf("command '%s' has a preset without name", c.Name)
+        }
+        if _, dup := seen[p.Name]; dup {
+            return fmt.Errorf("command '%s' has duplicate preset '%s'", c.Name, p.Name)
+        }
+        seen[p.Name] = struct{}{}
+        if p.Default {
+            if defaultName != "" {
+                return fmt.Errorf("command '%s' has more than one default preset: '%s' and '%s'", c.Name, defaultName, p.Name)
+            }
+            defaultName = p.Name
+        }
+        if len(p.Values) == 0 {
+            return fmt.Errorf("preset '%s' of command '%s' must set values", p.Name, c.Name)
+        }
+        for name, value := range p.Values {
+            v, ok := byName[name]
+            if !ok {
+                return fmt.Errorf("preset '%s' sets unknown variable '%s'", p.Name, name)
+            }
+            if err := validatePresetValue(v, value); err != nil {
+                return fmt.Errorf("preset '%s' variable '%s': %v", p.Name, name, err)
+            }
+        }
+    }
+    return nil
+}
+
+// validatePresetValue checks what can be known about a preset value
+// without the user's machine: files and directories are not checked.
+func validatePresetValue(v models.VariableDefinition, value interface{}) error {
+    if value == nil {
+        return nil
+    }
+    switch v.Type {
+    case models.VarTypeSecret:
+        return fmt.Errorf("presets cannot set secret variables")
+    case models.VarTypeBoolean:
+        if _, ok := value.(bool); !ok {
+            return fmt.Errorf("must be true or false")
+        }
+    case models.VarTypeNumber:
+        if _, ok := inputs.ToNumber(value); !ok {
+            return fmt.Errorf("must be a number")
+        }
+    case models.VarTypeSelect:
+        choices := v.SelectOptions().Values()
+        if len(choices) > 0 && !containsString(choices, fmt.Sprint(value)) {
+            return fmt.Errorf("'%v' is not one of %s", value, strings.Join(choices, ", "))
+        }
+    case models.VarTypeMultiSelect, models.VarTypeFileList:
+        items, ok := value.([]interface{})
+        if !ok {
+            return fmt.Errorf("must be a list")
+        }
+        if v.Type == models.VarTypeMultiSelect {
+            choices := (models.SelectOptions{Options: v.MultiSelectOptions().Options}).Values()
+            for _, item := range items {
+                if len(choices) > 0 && !containsString(choices, fmt.Sprint(item)) {
+                    return fmt.Errorf("'%v' is not one of %s", item, strings.Join(choices, ", "))
+                }
+            }
+        }
+    }
+    return nil
+}
+
+// validateOutput checks the output spec of a command.
+func validateOutput(c models.Command) error {
+    o := c.Output
+    switch o.Format {
+    case models.OutputJSON:
+    case models.OutputCSV:
+        if len([]rune(o.Delimiter)) > 1 {
+            return fmt.Errorf("command '%s' output delimiter must be a single character", c.Name)
+        }
+    case models.OutputRegex:
+        re, err := regexp.Compile(o.Pattern)
+        if err != nil {
+            return fmt.Errorf("command '%s' output has invalid pattern: %v", c.Name, err)
+        }
+        named := false
+        for _, name := range re.SubexpNames() {
+            named = named || name != ""
+        }
+        if !named {
+            return fmt.Errorf("command '%s' output pattern must have named groups", c.Name)
+        }
+    default:
+        return fmt.Errorf("command '%s' has unsupported output format '%s'", c.Name, o.Format)
+    }
+    for name, path := range o.Extract {
+        if name == "" || strings.TrimSpace(path) == "" {
+            return fmt.Errorf("command '%s' output extract '%s' must have a name and a path", c.Name, name)
+        }
+    }
+    return nil
+}
+
+// validateProgress checks the progress spec of a command.
+func validateProgress(c models.Command) error {
+    re, err := regexp.Compile(c.Progress.Pattern)
+    if c.Progress.Pattern == "" || err != nil {
+        return fmt.Errorf("command '%s' has invalid progress pattern '%s'", c.Name, c.Progress.Pattern)
+    }
+    hasGroup := func(name string) bool { return re.SubexpIndex(name) > 0 }
+    if !hasGroup("percent") && !hasGroup("current") && re.NumSubexp() == 0 {
+        return fmt.Errorf("command '%s' progress pattern must capture a percent or current value", c.Name)
+    }
+    if hasGroup("current") && !hasGroup("total") && c.Progress.TotalPattern == "" {
+        return fmt.Errorf("command '%s' progress needs a total group or total_pattern", c.Name)
+    }
+    if c.Progress.TotalPattern != "" {
+        if _, err := regexp.Compile(c.Progress.TotalPattern); err != nil {
+            return fmt.Errorf("command '%s' has invalid progress total_pattern: %v", c.Name, err)
+        }
+    }
+    return nil
+}
+
+var (
+    versionPattern = regexp.MustCompile(`^v?\d+(\.\d+)*`)
+    installOSes    = map[string]struct{}{"darwin": {}, "linux": {}, "windows": {}}
+)
+
+// validateRequirements checks a requires list of the template or a command.
+func validateRequirements(scope string, reqs []models.Requirement) error {
+    seen := map[string]struct{}{}
+    for _, r := range reqs {
+        if strings.TrimSpace(r.Name) == "" || strings.ContainsAny(r.Name, " \t") {
+            return fmt.Errorf("%s has requirement with invalid name '%s'", scope, r.Name)
+        }
+        if _, dup := seen[r.Name]; dup {
+            return fmt.Errorf("%s has duplicate requirement '%s'", scope, r.Name)
+        }
+        seen[r.Name] = struct{}{}
+        if r.MinVersion != "" && !versionPattern.MatchString(r.MinVersion) {
+            return fmt.Errorf("requirement '%s' has invalid min_version '%s'", r.Name, r.MinVersion)
+        }
+        if r.VersionCommand != "" {
+            // 版本命令在导入模板时自动运行, 只允许运行依赖本身
+            argv, err := render.Argv(r.VersionCommand, nil)
+            if err != nil || len(argv) == 0 || argv[0] != r.Name {
+                return fmt.Errorf("requirement '%s' version_command must run '%s' itself", r.Name, r.Name)
+            }
+        }
+        if r.VersionPattern != "" {
+            if _, err := regexp.Compile(r.VersionPattern); err != nil {
+                return fmt.Errorf("requirement '%s' has invalid version_pattern: %v", r.Name, err)
+            }
+        }
+        for os := range r.Install {
+            if _, ok := installOSes[os]; !ok {
+                return fmt.Errorf("requirement '%s' has install hint for unsupported os '%s'", r.Name, os)
+            }
+        }
+        for _, in := range r.Installers {
+            if err := validateInstaller(r.Name, in); err != nil {
+                return err
             }
         }
     }
     return nil
+}
+
+var sha256Pattern = regexp.MustCompile(`^[0-9a-fA-F]{64}$`)
+
+func validateInstaller(name string, in models.Installer) error {
+    if in.OS != "" {
+        if _, ok := installOSes[in.OS]; !ok {
+            return fmt.Errorf("requirement '%s' has installer for unsupported os '%s'", name, in.OS)
+        }
+    }
+    switch in.Method {
+    case models.InstallApt, models.InstallBrew, models.InstallWinget, models.InstallPip, models.InstallNpm, models.InstallGo:
+        if strings.TrimSpace(in.Package) == "" {
+            return fmt.Errorf("requirement '%s' %s installer must define package", name, in.Method)
+        }
+        if strings.HasPrefix(in.Package, "-") {
+            return fmt.Errorf("requirement '%s' %s installer has invalid package '%s'", name, in.Method, in.Package)
+        }
+    case models.InstallDownload:
+        if !strings.HasPrefix(in.URL, "https://") {
+            return fmt.Errorf("requirement '%s' download installer must define an https url", name)
+        }
+        if !sha256Pattern.MatchString(in.SHA256) {
+            return fmt.Errorf("requirement '%s' download installer must define a sha256 checksum", name)
+        }
+        // 下载的文件只能安装到 ~/.local/bin, dest 只是文件名
+        file := in.Dest
+        if file == "" {
+            file = name
+        }
+        if file == "." || file == ".." || strings.ContainsAny(file, `/\:`) {
+            return fmt.Errorf("requirement '%s' download installer dest '%s' must be a file name, not a path", name, file)
+        }
+    default:
+        return fmt.Errorf("requirement '%s' has unsupported installer method '%s'", name, in.Method)
+    }
+    return nil
+}
+
+// validatePipelines checks that every step runs a known command, assigns
+// only that command's variables and references only earlier steps.
+func validatePipelines(t *models.TemplateFile) error {
+    cmds := map[string]models.Command{}
+    for _, c := range t.Cmds {
+        if c.ID != "" {
+            cmds[c.ID] = c
+        }
+    }
+    pipelineIDs := map[string]struct{}{}
+    for _, p := range t.Pipelines {
+        if p.ID == "" || p.Name == "" {
+            return fmt.Errorf("pipeline '%s' missing required fields", p.Name)
+        }
+        if _, dup := pipelineIDs[p.ID]; dup {
+            return fmt.Errorf("duplicate pipeline id '%s'", p.ID)
+        }
+        pipelineIDs[p.ID] = struct{}{}
+        if len(p.Steps) == 0 {
+            return fmt.Errorf("pipeline '%s' must define steps", p.Name)
+        }
+        seen := map[string]struct{}{}
+        for _, s := range p.Steps {
+            c, ok := cmds[s.Command]
+            if !ok {
+                return fmt.Errorf("pipeline '%s' step '%s' references unknown command '%s'", p.Name, s.StepID(), s.Command)
+            }
+            if _, dup := seen[s.StepID()]; dup {
+                return fmt.Errorf("pipeline '%s' has duplicate step id '%s'", p.Name, s.StepID())
+            }
+            for name, expr := range s.With {
+                if !hasVariable(c, name) {
+                    return fmt.Errorf("pipeline '%s' step '%s' sets unknown variable '%s'", p.Name, s.StepID(), name)
+                }
+                parts, err := render.ParseStepExpr(expr)
+                if err != nil {
+                    return fmt.Errorf("pipeline '%s' step '%s' has %v", p.Name, s.StepID(), err)
+                }
+                for _, part := range parts {
+                    if part.Ref == nil {
+                        continue
+                    }
+                    if _, ok := seen[part.Ref.Step]; !ok {
+                        return fmt.Errorf("pipeline '%s' step '%s' references step '%s' that does not run before it", p.Name, s.StepID(), part.Ref.Step)
+                    }
+                }
+            }
+            seen[s.StepID()] = struct{}{}
+        }
+    }
+    return nil
+}
+
+func hasVariable(c models.Command, name string) bool {
+    for _, v := range c.Variables {
+        if v.Name == name {
+            return true
+        }
+    }
+    return false
 }
\ No newline at end of file
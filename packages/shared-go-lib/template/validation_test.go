@@ -0,0 +1,96 @@
+package template
+
+import (
+	"strings"
+	"testing"
+
+	"repo/shared-go-lib/models"
+)
+
+func TestValidateRequirements(t *testing.T) {
+	tests := []struct {
+		name string
+		req  models.Requirement
+		want string
+	}{
+		{"plain", models.Requirement{Name: "ffmpeg", MinVersion: "4.4"}, ""},
+		{"version command", models.Requirement{Name: "ffmpeg", MinVersion: "4.4", VersionCommand: "ffmpeg -version"}, ""},
+		{"version command runs another binary", models.Requirement{Name: "ffmpeg", VersionCommand: "curl https://x"},
+			"requirement 'ffmpeg' version_command must run 'ffmpeg' itself"},
+		{"version command runs a shell", models.Requirement{Name: "ffmpeg", VersionCommand: "sh -c 'ffmpeg -version'"},
+			"version_command must run 'ffmpeg' itself"},
+		{"unparsable version command", models.Requirement{Name: "ffmpeg", VersionCommand: "ffmpeg 'x"},
+			"version_command must run 'ffmpeg' itself"},
+		{"name with space", models.Requirement{Name: "my tool"}, "invalid name 'my tool'"},
+		{"bad min_version", models.Requirement{Name: "ffmpeg", MinVersion: "latest"}, "invalid min_version 'latest'"},
+		{"bad version_pattern", models.Requirement{Name: "ffmpeg", VersionPattern: "("}, "invalid version_pattern"},
+		{"bad install os", models.Requirement{Name: "ffmpeg", Install: map[string]string{"beos": "x"}}, "unsupported os 'beos'"},
+		{"https download", models.Requirement{Name: "jq", Installers: []models.Installer{
+			{Method: models.InstallDownload, URL: "https://x/jq", SHA256: strings.Repeat("a", 64)}}}, ""},
+		{"http download", models.Requirement{Name: "jq", Installers: []models.Installer{
+			{Method: models.InstallDownload, URL: "http://x/jq", SHA256: strings.Repeat("a", 64)}}},
+			"requirement 'jq' download installer must define an https url"},
+		{"download without sha256", models.Requirement{Name: "jq", Installers: []models.Installer{
+			{Method: models.InstallDownload, URL: "https://x/jq"}}}, "must define a sha256 checksum"},
+		{"package starting with -", models.Requirement{Name: "jq", Installers: []models.Installer{
+			{Method: models.InstallApt, Package: "--reinstall"}}}, "invalid package '--reinstall'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateRequirements("template", []models.Requirement{tt.req})
+			if tt.want == "" {
+				if err != nil {
+					t.Errorf("validateRequirements error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("validateRequirements error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidatePipelines(t *testing.T) {
+	cmds := []models.Command{
+		{ID: "probe", Name: "probe", Command: "probe {{in}}", Variables: []models.VariableDefinition{{Name: "in", Type: models.VarTypeText}}},
+		{ID: "enc", Name: "enc", Command: "enc {{in}}", Variables: []models.VariableDefinition{{Name: "in", Type: models.VarTypeText}}},
+	}
+	pipeline := func(steps ...models.PipelineStep) *models.TemplateFile {
+		return &models.TemplateFile{Cmds: cmds, Pipelines: []models.Pipeline{{ID: "p", Name: "p", Steps: steps}}}
+	}
+	with := func(command, expr string) models.PipelineStep {
+		return models.PipelineStep{Command: command, With: map[string]string{"in": expr}}
+	}
+	tests := []struct {
+		name string
+		t    *models.TemplateFile
+		want string
+	}{
+		{"references earlier steps", pipeline(with("probe", "{{file}}"),
+			with("enc", "{{steps.probe.stdout}} {{steps.probe.output}} {{ steps.probe.values.duration }}")), ""},
+		{"unknown command", pipeline(with("mux", "x")), "step 'mux' references unknown command 'mux'"},
+		{"unknown variable", pipeline(models.PipelineStep{Command: "enc", With: map[string]string{"out": "x"}}),
+			"step 'enc' sets unknown variable 'out'"},
+		{"duplicate step", pipeline(with("enc", "a"), with("enc", "b")), "duplicate step id 'enc'"},
+		{"later step", pipeline(with("probe", "{{steps.enc.output}}"), with("enc", "x")),
+			"step 'probe' references step 'enc' that does not run before it"},
+		{"itself", pipeline(with("enc", "{{steps.enc.output}}")), "references step 'enc' that does not run before it"},
+		{"invalid field", pipeline(with("probe", "x"), with("enc", "{{steps.probe.file}}")),
+			"pipeline 'p' step 'enc' has invalid reference '{{steps.probe.file}}'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validatePipelines(tt.t)
+			if tt.want == "" {
+				if err != nil {
+					t.Errorf("validatePipelines error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("validatePipelines error = %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}